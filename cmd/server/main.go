@@ -1,15 +1,47 @@
 package main
 
 import (
+	"context"
 	"log"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/itocode21/MerchServiceAvito/internal/auth"
 	"github.com/itocode21/MerchServiceAvito/internal/config"
+	"github.com/itocode21/MerchServiceAvito/internal/events"
 	"github.com/itocode21/MerchServiceAvito/internal/handlers"
 	"github.com/itocode21/MerchServiceAvito/internal/middleware"
+	"github.com/itocode21/MerchServiceAvito/internal/models"
 	"github.com/itocode21/MerchServiceAvito/internal/repositories"
 	"github.com/itocode21/MerchServiceAvito/internal/services"
+	"github.com/itocode21/MerchServiceAvito/internal/worker"
+)
+
+// Сколько поисковых запросов по справочнику сотрудник может сделать в минуту
+const userSearchRateLimit = 30
+
+// Как часто фоновые задачи возвращают ставки по просроченным пари,
+// выполняют запланированные переводы, закрывают аукционы, рассылают
+// уведомления по спискам желаний, выдают значки, пересобирают рейтинги из базы,
+// публикуют события outbox, доставляют вебхуки и чистят опубликованные
+// события и выполненные доставки
+const (
+	betExpiryInterval          = time.Minute
+	scheduledTransferInterval  = time.Minute
+	auctionCloseInterval       = 15 * time.Second
+	wishlistAlertInterval      = time.Minute
+	achievementInterval        = time.Minute
+	leaderboardRebuildInterval = time.Hour
+	outboxRelayInterval        = time.Second
+	outboxPurgeInterval        = time.Hour
+	webhookDeliveryInterval    = time.Second
+	webhookPurgeInterval       = time.Hour
+)
+
+// Поток Redis, в который публикуются события outbox, и его примерная длина
+const (
+	eventStream       = "merch:events"
+	eventStreamMaxLen = 100000
 )
 
 func main() {
@@ -22,24 +54,220 @@ func main() {
 	userRepo := repositories.NewUserRepository(cfg)
 	itemRepo := repositories.NewItemRepository(cfg.DB)
 	transRepo := repositories.NewTransactionRepository(cfg.DB)
+	apiKeyRepo := repositories.NewAPIKeyRepository(cfg.DB)
+	teamRepo := repositories.NewTeamRepository(cfg.DB)
+	paymentRequestRepo := repositories.NewPaymentRequestRepository(cfg.DB)
+	holdRepo := repositories.NewHoldRepository(cfg.DB)
+	betRepo := repositories.NewBetRepository(cfg.DB)
+	scheduleRepo := repositories.NewScheduleRepository(cfg.DB)
+	notificationRepo := repositories.NewNotificationRepository(cfg.DB)
+	tradeRepo := repositories.NewTradeRepository(cfg.DB)
+	auctionRepo := repositories.NewAuctionRepository(cfg.DB)
+	wishlistRepo := repositories.NewWishlistRepository(cfg.DB)
+	promotionRepo := repositories.NewPromotionRepository(cfg.DB)
+	achievementRepo := repositories.NewAchievementRepository(cfg.DB)
+	leaderboardRepo := repositories.NewLeaderboardRepository(cfg.DB)
+	outboxRepo := repositories.NewOutboxRepository(cfg.DB)
+	webhookRepo := repositories.NewWebhookRepository(cfg.DB)
 
 	authService := services.NewAuthService(userRepo)
-	userService := services.NewUserService(userRepo)
-	itemService := services.NewItemService(itemRepo, userRepo)
-	transService := services.NewTransactionService(userRepo, transRepo)
+	userService := services.NewUserService(userRepo, transRepo, outboxRepo)
+	promotionService := services.NewPromotionService(promotionRepo, itemRepo, userRepo)
+	itemService := services.NewItemService(itemRepo, userRepo, notificationRepo, promotionService, outboxRepo)
+	transService := services.NewTransactionService(userRepo, transRepo, outboxRepo, notificationRepo)
+	apiKeyService := services.NewAPIKeyService(apiKeyRepo, userRepo)
+	teamService := services.NewTeamService(teamRepo, userRepo, transRepo, outboxRepo)
+	paymentRequestService := services.NewPaymentRequestService(paymentRequestRepo, userRepo, transService)
+	escrowService := services.NewEscrowService(holdRepo, userRepo, transRepo)
+	betService := services.NewBetService(betRepo, escrowService, userRepo, transRepo, outboxRepo)
+	scheduleService := services.NewScheduleService(scheduleRepo, notificationRepo, userRepo, transService)
+	notificationService := services.NewNotificationService(notificationRepo, userRepo)
+	tradeService := services.NewTradeService(tradeRepo, itemRepo, userRepo, transRepo, notificationRepo, outboxRepo)
+	auctionService := services.NewAuctionService(auctionRepo, escrowService, itemRepo, userRepo, notificationRepo, outboxRepo)
+	wishlistService := services.NewWishlistService(wishlistRepo, itemRepo, userRepo, notificationRepo)
+	achievementService := services.NewAchievementService(achievementRepo, userRepo, transRepo, notificationRepo, outboxRepo)
+	leaderboardService := services.NewLeaderboardService(leaderboardRepo, userRepo)
+	leaderboardService.Subscribe(cfg.Events)
+	webhookService := services.NewWebhookService(webhookRepo)
+	streamService := services.NewStreamService(userRepo)
+	streamService.Subscribe(cfg.Events)
+	sink := events.Fanout{webhookService, events.NewRedisStreamSink(cfg.Redis, eventStream, eventStreamMaxLen)}
+	outboxRelay := services.NewOutboxRelay(outboxRepo, sink, userRepo)
 
 	auth.SetJWTSecret(cfg.JWTSecret)
+	auth.SetRevocationStore(cfg.Redis)
+
+	go worker.Every(context.Background(), betExpiryInterval, "bet-expiry", func(ctx context.Context) error {
+		n, err := betService.ExpireBets()
+		if n > 0 {
+			log.Printf("Возвращены ставки по %d просроченным пари", n)
+		}
+		return err
+	})
+
+	go worker.Every(context.Background(), scheduledTransferInterval, "scheduled-transfers", func(ctx context.Context) error {
+		n, err := scheduleService.RunDueSchedules()
+		if n > 0 {
+			log.Printf("Выполнено запланированных переводов: %d", n)
+		}
+		return err
+	})
+
+	go worker.Every(context.Background(), auctionCloseInterval, "auction-close", func(ctx context.Context) error {
+		n, err := auctionService.CloseAuctions()
+		if n > 0 {
+			log.Printf("Закрыто аукционов: %d", n)
+		}
+		return err
+	})
 
-	h := handlers.NewHandlers(cfg, authService, userService, itemService, transService)
+	go worker.Every(context.Background(), wishlistAlertInterval, "wishlist-alerts", func(ctx context.Context) error {
+		n, err := wishlistService.SendAlerts()
+		if n > 0 {
+			log.Printf("Обработано изменений в списках желаний: %d", n)
+		}
+		return err
+	})
+
+	go worker.Every(context.Background(), achievementInterval, "achievements", func(ctx context.Context) error {
+		n, err := achievementService.Reconcile()
+		if n > 0 {
+			log.Printf("Выдано значков: %d", n)
+		}
+		return err
+	})
+
+	go func() {
+		if err := leaderboardService.Rebuild(); err != nil {
+			log.Printf("Не удалось пересобрать рейтинги при запуске: %v", err)
+		}
+	}()
+	go worker.Every(context.Background(), leaderboardRebuildInterval, "leaderboard-rebuild", func(ctx context.Context) error {
+		return leaderboardService.Rebuild()
+	})
+
+	go worker.Every(context.Background(), outboxRelayInterval, "outbox-relay", func(ctx context.Context) error {
+		_, err := outboxRelay.RelayOnce(ctx)
+		return err
+	})
+
+	go worker.Every(context.Background(), outboxPurgeInterval, "outbox-purge", func(ctx context.Context) error {
+		n, err := outboxRelay.Purge()
+		if n > 0 {
+			log.Printf("Удалено опубликованных событий outbox: %d", n)
+		}
+		return err
+	})
+
+	go worker.Every(context.Background(), webhookDeliveryInterval, "webhook-delivery", func(ctx context.Context) error {
+		_, err := webhookService.Deliver(ctx)
+		return err
+	})
+
+	go worker.Every(context.Background(), webhookPurgeInterval, "webhook-purge", func(ctx context.Context) error {
+		n, err := webhookService.Purge()
+		if n > 0 {
+			log.Printf("Удалено выполненных доставок вебхуков: %d", n)
+		}
+		return err
+	})
+
+	h := handlers.NewHandlers(cfg, authService, userService, itemService, transService, apiKeyService, teamService, paymentRequestService, betService, scheduleService, notificationService, tradeService, auctionService, wishlistService, promotionService, achievementService, leaderboardService, webhookService, streamService)
 
 	r := gin.Default()
+	// Адрес клиента используется для уведомлений о входе с нового устройства:
+	// X-Forwarded-For учитывается только от известных прокси
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("Ошибка настройки доверенных прокси: %v", err)
+	}
 	r.GET("/api/reset", h.ResetDB) // Добавленный эндпоинт для сброса
 	r.POST("/api/register", h.Register)
 	r.POST("/api/auth", h.Authenticate)
+	r.POST("/api/password/reset", h.ResetPassword)
+	r.GET("/api/info", middleware.APIKeyOrJWTAuthMiddleware(apiKeyService, models.ScopeReadInfo), h.GetInfo)
+	r.POST("/api/sendCoin", middleware.APIKeyOrJWTAuthMiddleware(apiKeyService, models.ScopeTransferSendAsBot), h.SendCoin)
+	r.POST("/api/sendCoin/bulk", middleware.APIKeyOrJWTAuthMiddleware(apiKeyService, models.ScopeTransferSendAsBot), h.SendCoinBulk)
+	r.POST("/api/admin/grant", middleware.APIKeyOrJWTAuthMiddleware(apiKeyService, models.ScopeGrantCoins), middleware.AdminMiddleware(userRepo), h.GrantCoins)
 	protected := r.Group("/api").Use(middleware.JWTAuthMiddleware())
-	protected.GET("/info", h.GetInfo)
-	protected.POST("/sendCoin", h.SendCoin)
 	protected.GET("/buy/:item", h.BuyItem)
+	protected.POST("/gift/:item", h.GiftItem)
+	protected.GET("/price/:item", h.GetPrice)
+	protected.GET("/items", h.ListItems)
+	protected.GET("/items/:item", h.GetItem)
+	protected.GET("/items/:item/prices", h.GetPriceTimeline)
+	protected.GET("/categories", h.GetCategories)
+	protected.POST("/items/:item/transfer", h.TransferItem)
+	protected.POST("/trades", h.CreateTrade)
+	protected.GET("/trades", h.GetTrades)
+	protected.POST("/trades/:id/accept", h.AcceptTrade)
+	protected.POST("/trades/:id/decline", h.DeclineTrade)
+	protected.POST("/trades/:id/cancel", h.CancelTrade)
+	protected.POST("/password", h.ChangePassword)
+	protected.GET("/me", h.GetMyProfile)
+	protected.PATCH("/me", h.UpdateMyProfile)
+	protected.GET("/users", middleware.RateLimitMiddleware(cfg.Redis, "user_search", userSearchRateLimit, time.Minute), h.SearchUsers)
+	protected.GET("/users/:username", h.GetUserProfile)
+	protected.GET("/users/:username/badges", h.GetUserBadges)
+	protected.GET("/badges", h.GetBadges)
+	protected.GET("/teams", h.GetMyTeams)
+	protected.GET("/teams/:id", h.GetTeam)
+	protected.POST("/teams/:id/reward", h.RewardTeamMember)
+	protected.GET("/teams/:id/report", h.GetTeamReport)
+	protected.POST("/coinRequests", h.CreateCoinRequest)
+	protected.GET("/coinRequests", h.GetCoinRequests)
+	protected.POST("/coinRequests/:id/approve", h.ApproveCoinRequest)
+	protected.POST("/coinRequests/:id/decline", h.DeclineCoinRequest)
+	protected.POST("/bets", h.CreateBet)
+	protected.GET("/bets", h.GetBets)
+	protected.POST("/bets/:id/accept", h.AcceptBet)
+	protected.POST("/bets/:id/decline", h.DeclineBet)
+	protected.POST("/bets/:id/settle", h.SettleBet)
+	protected.POST("/schedules", h.CreateSchedule)
+	protected.GET("/schedules", h.GetSchedules)
+	protected.GET("/schedules/:id/runs", h.GetScheduleRuns)
+	protected.POST("/schedules/:id/pause", h.PauseSchedule)
+	protected.POST("/schedules/:id/resume", h.ResumeSchedule)
+	protected.DELETE("/schedules/:id", h.CancelSchedule)
+	protected.GET("/notifications", h.GetNotifications)
+	protected.GET("/notifications/unread-count", h.GetUnreadCount)
+	protected.POST("/notifications/:id/read", h.MarkNotificationRead)
+	protected.POST("/notifications/read-all", h.MarkAllNotificationsRead)
+	protected.GET("/notifications/preferences", h.GetNotificationPreferences)
+	protected.PUT("/notifications/preferences", h.SetNotificationPreferences)
+	protected.GET("/leaderboards/:board", h.GetLeaderboard)
+	protected.GET("/stream", h.Stream)
+	protected.GET("/wishlist", h.GetWishlist)
+	protected.PUT("/wishlist/:item", h.AddToWishlist)
+	protected.DELETE("/wishlist/:item", h.RemoveFromWishlist)
+	protected.GET("/auctions", h.GetAuctions)
+	protected.GET("/auctions/:id", h.GetAuction)
+	protected.POST("/auctions/:id/bids", h.PlaceBid)
+	protected.GET("/auctions/:id/bids", h.GetAuctionBids)
+	admin := r.Group("/api/admin").Use(middleware.JWTAuthMiddleware(), middleware.AdminMiddleware(userRepo))
+	admin.POST("/users/:username/password-reset", h.IssuePasswordReset)
+	admin.POST("/users/:username/deactivate", h.DeactivateUser)
+	admin.POST("/users/:username/reactivate", h.ReactivateUser)
+	admin.POST("/teams", h.CreateTeam)
+	admin.PUT("/teams/:id/members/:username", h.SetTeamMember)
+	admin.DELETE("/teams/:id/members/:username", h.RemoveTeamMember)
+	admin.POST("/teams/:id/fund", h.FundTeam)
+	admin.PATCH("/items/:item", h.UpdateItem)
+	admin.POST("/items/:item/prices", h.SchedulePrice)
+	admin.DELETE("/items/:item/prices/:id", h.CancelScheduledPrice)
+	admin.PUT("/categories/:slug", h.SaveCategory)
+	admin.POST("/promotions", h.CreatePromotion)
+	admin.GET("/promotions", h.GetPromotions)
+	admin.DELETE("/promotions/:id", h.DeactivatePromotion)
+	admin.POST("/auctions", h.CreateAuction)
+	admin.POST("/apikeys", h.CreateAPIKey)
+	admin.GET("/apikeys", h.ListAPIKeys)
+	admin.DELETE("/apikeys/:id", h.RevokeAPIKey)
+	admin.POST("/webhooks", h.CreateWebhook)
+	admin.GET("/webhooks", h.ListWebhooks)
+	admin.DELETE("/webhooks/:id", h.DeactivateWebhook)
+	admin.GET("/webhooks/:id/deliveries", h.GetWebhookDeliveries)
+	admin.GET("/webhooks/dead-letters", h.GetWebhookDeadLetters)
+	admin.POST("/webhooks/deliveries/:id/retry", h.RetryWebhookDelivery)
 
 	if err := r.Run(":8080"); err != nil {
 		log.Fatalf("Ошибка запуска сервера: %v", err)
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os/exec"
+	"sync"
 	"testing"
 	"time"
 
@@ -15,6 +16,7 @@ import (
 	"github.com/go-redis/redis/v8"
 	"github.com/itocode21/MerchServiceAvito/internal/auth"
 	"github.com/itocode21/MerchServiceAvito/internal/config"
+	"github.com/itocode21/MerchServiceAvito/internal/events"
 	"github.com/itocode21/MerchServiceAvito/internal/handlers"
 	"github.com/itocode21/MerchServiceAvito/internal/middleware"
 	"github.com/itocode21/MerchServiceAvito/internal/repositories"
@@ -61,19 +63,52 @@ func setupTest(t *testing.T) (*gin.Engine, *sql.DB, *redis.Client, func()) {
 		DB:        db,
 		Redis:     redisClient,
 		JWTSecret: []byte("your_very_secure_secret_key_32_bytes_long"),
+		Events:    events.NewBus(),
 	}
 
 	// Инициализируем репозитории и сервисы
 	userRepo := repositories.NewUserRepository(cfg)
 	itemRepo := repositories.NewItemRepository(cfg.DB)
 	transRepo := repositories.NewTransactionRepository(cfg.DB)
-	userService := services.NewUserService(userRepo)
+	apiKeyRepo := repositories.NewAPIKeyRepository(cfg.DB)
+	teamRepo := repositories.NewTeamRepository(cfg.DB)
+	paymentRequestRepo := repositories.NewPaymentRequestRepository(cfg.DB)
+	holdRepo := repositories.NewHoldRepository(cfg.DB)
+	betRepo := repositories.NewBetRepository(cfg.DB)
+	scheduleRepo := repositories.NewScheduleRepository(cfg.DB)
+	notificationRepo := repositories.NewNotificationRepository(cfg.DB)
+	tradeRepo := repositories.NewTradeRepository(cfg.DB)
+	auctionRepo := repositories.NewAuctionRepository(cfg.DB)
+	wishlistRepo := repositories.NewWishlistRepository(cfg.DB)
+	promotionRepo := repositories.NewPromotionRepository(cfg.DB)
+	achievementRepo := repositories.NewAchievementRepository(cfg.DB)
+	leaderboardRepo := repositories.NewLeaderboardRepository(cfg.DB)
+	outboxRepo := repositories.NewOutboxRepository(cfg.DB)
+	webhookRepo := repositories.NewWebhookRepository(cfg.DB)
+	userService := services.NewUserService(userRepo, transRepo, outboxRepo)
 	authService := services.NewAuthService(userRepo)
-	itemService := services.NewItemService(itemRepo, userRepo)
-	transService := services.NewTransactionService(userRepo, transRepo)
+	promotionService := services.NewPromotionService(promotionRepo, itemRepo, userRepo)
+	itemService := services.NewItemService(itemRepo, userRepo, notificationRepo, promotionService, outboxRepo)
+	transService := services.NewTransactionService(userRepo, transRepo, outboxRepo, notificationRepo)
+	apiKeyService := services.NewAPIKeyService(apiKeyRepo, userRepo)
+	teamService := services.NewTeamService(teamRepo, userRepo, transRepo, outboxRepo)
+	paymentRequestService := services.NewPaymentRequestService(paymentRequestRepo, userRepo, transService)
+	escrowService := services.NewEscrowService(holdRepo, userRepo, transRepo)
+	betService := services.NewBetService(betRepo, escrowService, userRepo, transRepo, outboxRepo)
+	scheduleService := services.NewScheduleService(scheduleRepo, notificationRepo, userRepo, transService)
+	notificationService := services.NewNotificationService(notificationRepo, userRepo)
+	tradeService := services.NewTradeService(tradeRepo, itemRepo, userRepo, transRepo, notificationRepo, outboxRepo)
+	auctionService := services.NewAuctionService(auctionRepo, escrowService, itemRepo, userRepo, notificationRepo, outboxRepo)
+	wishlistService := services.NewWishlistService(wishlistRepo, itemRepo, userRepo, notificationRepo)
+	achievementService := services.NewAchievementService(achievementRepo, userRepo, transRepo, notificationRepo, outboxRepo)
+	leaderboardService := services.NewLeaderboardService(leaderboardRepo, userRepo)
+	leaderboardService.Subscribe(cfg.Events)
+	webhookService := services.NewWebhookService(webhookRepo)
+	streamService := services.NewStreamService(userRepo)
+	streamService.Subscribe(cfg.Events)
 	auth.SetJWTSecret(cfg.JWTSecret)
 
-	h := handlers.NewHandlers(cfg, authService, userService, itemService, transService)
+	h := handlers.NewHandlers(cfg, authService, userService, itemService, transService, apiKeyService, teamService, paymentRequestService, betService, scheduleService, notificationService, tradeService, auctionService, wishlistService, promotionService, achievementService, leaderboardService, webhookService, streamService)
 
 	// Настраиваем маршруты
 	r := gin.Default()
@@ -284,3 +319,75 @@ func TestE2ESendCoin(t *testing.T) {
 		t.Errorf("Ожидалась одна полученная транзакция на 100 монет от пользователя с ID 1, получено %v", receiverInfoResp.CoinHistory.Received)
 	}
 }
+
+// TestE2EConcurrentSendCoin гоняет встречные переводы между двумя пользователями
+// и проверяет, что ни один запрос не упал из-за взаимоблокировки и монеты не потерялись.
+func TestE2EConcurrentSendCoin(t *testing.T) {
+	r, db, _, cleanup := setupTest(t)
+	defer cleanup()
+
+	tokens := make(map[string]string)
+	for _, username := range []string{"alice", "bob"} {
+		reqBody, _ := json.Marshal(map[string]string{"username": username, "password": "12345"})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/register", bytes.NewBuffer(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Регистрация %s провалилась: %d, %s", username, w.Code, w.Body.String())
+		}
+
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("POST", "/api/auth", bytes.NewBuffer(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Аутентификация %s провалилась: %d, %s", username, w.Code, w.Body.String())
+		}
+		var authResp struct{ Token string }
+		json.Unmarshal(w.Body.Bytes(), &authResp)
+		tokens[username] = authResp.Token
+	}
+
+	const requestsPerUser = 50
+	var wg sync.WaitGroup
+	failures := make(chan string, 2*requestsPerUser)
+	for _, pair := range [][2]string{{"alice", "bob"}, {"bob", "alice"}} {
+		from, to := pair[0], pair[1]
+		for i := 0; i < requestsPerUser; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				sendBody, _ := json.Marshal(map[string]interface{}{"toUser": to, "amount": 1})
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest("POST", "/api/sendCoin", bytes.NewBuffer(sendBody))
+				req.Header.Set("Authorization", "Bearer "+tokens[from])
+				req.Header.Set("Content-Type", "application/json")
+				r.ServeHTTP(w, req)
+				if w.Code != http.StatusOK {
+					failures <- w.Body.String()
+				}
+			}()
+		}
+	}
+	wg.Wait()
+	close(failures)
+
+	for body := range failures {
+		t.Errorf("Перевод завершился ошибкой: %s", body)
+	}
+
+	var total, transfers int
+	if err := db.QueryRow("SELECT SUM(coins) FROM users WHERE username IN ('alice', 'bob')").Scan(&total); err != nil {
+		t.Fatalf("Ошибка подсчёта баланса: %v", err)
+	}
+	if total != 2000 {
+		t.Errorf("Ожидалось 2000 монет на двоих, получено %d", total)
+	}
+	if err := db.QueryRow("SELECT COUNT(*) FROM transactions").Scan(&transfers); err != nil {
+		t.Fatalf("Ошибка подсчёта транзакций: %v", err)
+	}
+	if transfers != 2*requestsPerUser {
+		t.Errorf("Ожидалось %d транзакций, получено %d", 2*requestsPerUser, transfers)
+	}
+}
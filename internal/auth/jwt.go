@@ -9,6 +9,8 @@ import (
 
 var jwtSecret []byte
 
+const tokenTTL = time.Hour * 24
+
 func SetJWTSecret(secret []byte) {
 	jwtSecret = secret
 }
@@ -17,9 +19,12 @@ func GenerateJWT(username string) (string, error) {
 	if len(jwtSecret) == 0 {
 		return "", fmt.Errorf("JWT secret not set")
 	}
+	now := time.Now()
 	claims := jwt.MapClaims{
 		"username": username,
-		"exp":      time.Now().Add(time.Hour * 24).Unix(),
+		"iat":      now.Unix(),
+		"iat_us":   now.UnixMicro(),
+		"exp":      now.Add(tokenTTL).Unix(),
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	signedToken, err := token.SignedString(jwtSecret)
@@ -50,5 +55,21 @@ func ValidateJWT(tokenStr string) (string, error) {
 		return "", fmt.Errorf("неверные данные в токене")
 	}
 
+	// Время выпуска в микросекундах: токен, выданный в ту же секунду после смены пароля,
+	// должен остаться действительным, а выданный до неё — нет
+	var issuedAt int64
+	if iat, ok := claims["iat_us"].(float64); ok {
+		issuedAt = int64(iat)
+	} else if iat, ok := claims["iat"].(float64); ok {
+		issuedAt = int64(iat) * int64(time.Second/time.Microsecond)
+	}
+	revoked, err := isRevoked(username, issuedAt)
+	if err != nil {
+		return "", fmt.Errorf("ошибка проверки отзыва токена: %v", err)
+	}
+	if revoked {
+		return "", fmt.Errorf("токен отозван")
+	}
+
 	return username, nil
 }
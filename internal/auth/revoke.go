@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var revocationStore *redis.Client
+
+func SetRevocationStore(client *redis.Client) {
+	revocationStore = client
+}
+
+// RevokeTokens делает недействительными все токены пользователя, выпущенные до текущего момента.
+// Метка хранится в Redis в микросекундах и не дольше срока жизни токена.
+func RevokeTokens(username string) error {
+	if revocationStore == nil {
+		return nil
+	}
+	err := revocationStore.Set(context.Background(), "tokens_revoked_at:"+username, time.Now().UnixMicro(), tokenTTL).Err()
+	if err != nil {
+		return fmt.Errorf("ошибка отзыва токенов: %v", err)
+	}
+	return nil
+}
+
+// isRevoked проверяет, выпущен ли токен (issuedAt в микросекундах) до отзыва
+func isRevoked(username string, issuedAt int64) (bool, error) {
+	if revocationStore == nil {
+		return false, nil
+	}
+	val, err := revocationStore.Get(context.Background(), "tokens_revoked_at:"+username).Result()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	revokedAt, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		return false, err
+	}
+	return issuedAt < revokedAt, nil
+}
@@ -6,9 +6,13 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/itocode21/MerchServiceAvito/internal/database"
+	"github.com/itocode21/MerchServiceAvito/internal/events"
 	"github.com/joho/godotenv"
 )
 
@@ -16,6 +20,20 @@ type Config struct {
 	DB        *sql.DB
 	JWTSecret []byte
 	Redis     *redis.Client
+
+	// Лимиты переводов между пользователями; 0 — без ограничения
+	TransferMaxAmount  int
+	TransferDailyLimit int
+
+	// Через сколько неподтверждённый запрос монет истекает; 0 — значение по умолчанию
+	PaymentRequestTTL time.Duration
+
+	// Шина событий о завершённых операциях; nil — события никуда не доставляются
+	Events *events.Bus
+
+	// Адреса или подсети прокси, которым можно доверить X-Forwarded-For; пусто — никому,
+	// и адрес клиента берётся из соединения
+	TrustedProxies []string
 }
 
 func Load() (*Config, error) {
@@ -52,13 +70,55 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("JWT_SECRET не указан")
 	}
 
+	transferMaxAmount, err := intFromEnv("TRANSFER_MAX_AMOUNT")
+	if err != nil {
+		return nil, err
+	}
+	transferDailyLimit, err := intFromEnv("TRANSFER_DAILY_LIMIT")
+	if err != nil {
+		return nil, err
+	}
+
+	var paymentRequestTTL time.Duration
+	if value := os.Getenv("PAYMENT_REQUEST_TTL"); value != "" {
+		paymentRequestTTL, err = time.ParseDuration(value)
+		if err != nil || paymentRequestTTL <= 0 {
+			return nil, fmt.Errorf("PAYMENT_REQUEST_TTL должно быть положительной длительностью: %q", value)
+		}
+	}
+
+	var trustedProxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			trustedProxies = append(trustedProxies, proxy)
+		}
+	}
+
 	return &Config{
-		DB:        db,
-		JWTSecret: jwtSecret,
-		Redis:     redisClient,
+		DB:                 db,
+		JWTSecret:          jwtSecret,
+		Redis:              redisClient,
+		TransferMaxAmount:  transferMaxAmount,
+		TransferDailyLimit: transferDailyLimit,
+		PaymentRequestTTL:  paymentRequestTTL,
+		Events:             events.NewBus(),
+		TrustedProxies:     trustedProxies,
 	}, nil
 }
 
+// intFromEnv читает неотрицательное целое из переменной окружения; пустое значение — 0
+func intFromEnv(name string) (int, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s должно быть неотрицательным целым числом: %q", name, value)
+	}
+	return n, nil
+}
+
 func (c *Config) Close() {
 	if err := c.DB.Close(); err != nil {
 		log.Printf("Ошибка закрытия БД: %v", err)
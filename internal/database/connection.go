@@ -2,6 +2,7 @@ package database
 
 import (
 	"database/sql"
+	_ "embed"
 	"fmt"
 	"log"
 	"os"
@@ -106,8 +107,13 @@ func applyMigrations(db *sql.DB) error {
 	return nil
 }
 
+// resetSQL очищает все таблицы с данными пользователей; новые таблицы добавляются в reset.sql
+//
+//go:embed reset.sql
+var resetSQL string
+
 func ResetDB(db *sql.DB) error {
-	_, err := db.Exec("TRUNCATE TABLE users, transactions, inventory RESTART IDENTITY")
+	_, err := db.Exec(resetSQL)
 	if err != nil {
 		log.Printf("Ошибка очистки базы данных: %v", err)
 		return fmt.Errorf("ошибка очистки базы данных: %v", err)
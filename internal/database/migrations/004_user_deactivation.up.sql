@@ -0,0 +1,2 @@
+ALTER TABLE users ADD COLUMN is_active BOOLEAN NOT NULL DEFAULT TRUE;
+ALTER TABLE users ADD COLUMN deactivated_at TIMESTAMP;
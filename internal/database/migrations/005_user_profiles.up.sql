@@ -0,0 +1,5 @@
+ALTER TABLE users ADD COLUMN display_name VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN department VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN office VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN title VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN avatar_url VARCHAR(1024) NOT NULL DEFAULT '';
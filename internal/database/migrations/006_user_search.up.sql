@@ -0,0 +1,5 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Поиск по справочнику ведётся только среди активных сотрудников
+CREATE INDEX idx_users_username_trgm ON users USING gin (username gin_trgm_ops) WHERE is_active;
+CREATE INDEX idx_users_display_name_trgm ON users USING gin (display_name gin_trgm_ops) WHERE is_active;
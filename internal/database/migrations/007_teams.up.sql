@@ -0,0 +1,20 @@
+CREATE TABLE teams (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL UNIQUE,
+    coins INT NOT NULL DEFAULT 0 CHECK (coins >= 0),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE team_members (
+    team_id INT NOT NULL REFERENCES teams(id),
+    user_id INT NOT NULL REFERENCES users(id),
+    role VARCHAR(16) NOT NULL DEFAULT 'member' CHECK (role IN ('member', 'manager')),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (team_id, user_id)
+);
+
+CREATE INDEX idx_team_members_user_id ON team_members (user_id);
+
+-- Пополнения бюджета команды и награды из него попадают в общую историю транзакций
+ALTER TABLE transactions ADD COLUMN team_id INT REFERENCES teams(id);
+CREATE INDEX idx_transactions_team_id ON transactions (team_id, created_at);
@@ -0,0 +1,8 @@
+ALTER TABLE users ADD CONSTRAINT users_coins_non_negative CHECK (coins >= 0);
+ALTER TABLE transactions ADD CONSTRAINT transactions_amount_positive CHECK (amount > 0);
+
+-- Старые переводы самому себе могли остаться в истории, поэтому ограничение проверяется только для новых строк
+ALTER TABLE transactions ADD CONSTRAINT transactions_not_self CHECK (from_user_id <> to_user_id) NOT VALID;
+
+-- Суточный лимит считается по переводам отправителя с начала суток
+CREATE INDEX idx_transactions_from_user_created_at ON transactions (from_user_id, created_at);
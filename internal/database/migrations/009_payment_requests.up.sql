@@ -0,0 +1,16 @@
+CREATE TABLE payment_requests (
+    id SERIAL PRIMARY KEY,
+    requester_id INT NOT NULL REFERENCES users(id),
+    payer_id INT NOT NULL REFERENCES users(id),
+    amount INT NOT NULL CHECK (amount > 0),
+    memo VARCHAR(255) NOT NULL DEFAULT '',
+    status VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'declined', 'expired')),
+    transaction_id INT REFERENCES transactions(id),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL,
+    resolved_at TIMESTAMP,
+    CHECK (requester_id <> payer_id)
+);
+
+CREATE INDEX idx_payment_requests_payer ON payment_requests (payer_id, status);
+CREATE INDEX idx_payment_requests_requester ON payment_requests (requester_id, created_at);
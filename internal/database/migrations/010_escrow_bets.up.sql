@@ -0,0 +1,36 @@
+-- Зарезервированные монеты остаются в coins, но не могут быть потрачены
+ALTER TABLE users ADD COLUMN held_coins INT NOT NULL DEFAULT 0;
+ALTER TABLE users ADD CONSTRAINT users_held_coins_valid CHECK (held_coins >= 0 AND held_coins <= coins);
+
+CREATE TABLE bets (
+    id SERIAL PRIMARY KEY,
+    creator_id INT NOT NULL REFERENCES users(id),
+    opponent_id INT NOT NULL REFERENCES users(id),
+    arbiter_id INT NOT NULL REFERENCES users(id),
+    winner_id INT REFERENCES users(id),
+    stake INT NOT NULL CHECK (stake > 0),
+    description VARCHAR(255) NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'active', 'settled', 'declined', 'refunded')),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL,
+    resolved_at TIMESTAMP,
+    CHECK (creator_id <> opponent_id AND arbiter_id <> creator_id AND arbiter_id <> opponent_id)
+);
+
+CREATE INDEX idx_bets_open_expires_at ON bets (expires_at) WHERE status IN ('open', 'active');
+CREATE INDEX idx_bets_creator ON bets (creator_id);
+CREATE INDEX idx_bets_opponent ON bets (opponent_id);
+CREATE INDEX idx_bets_arbiter ON bets (arbiter_id);
+
+CREATE TABLE coin_holds (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id),
+    bet_id INT REFERENCES bets(id),
+    amount INT NOT NULL CHECK (amount > 0),
+    status VARCHAR(16) NOT NULL DEFAULT 'held' CHECK (status IN ('held', 'released')),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    released_at TIMESTAMP
+);
+
+CREATE INDEX idx_coin_holds_bet ON coin_holds (bet_id);
+CREATE INDEX idx_coin_holds_user ON coin_holds (user_id) WHERE status = 'held';
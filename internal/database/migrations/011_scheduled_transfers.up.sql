@@ -0,0 +1,39 @@
+CREATE TABLE scheduled_transfers (
+    id SERIAL PRIMARY KEY,
+    owner_id INT NOT NULL REFERENCES users(id),
+    to_user_id INT NOT NULL REFERENCES users(id),
+    amount INT NOT NULL CHECK (amount > 0),
+    recurrence VARCHAR(16) NOT NULL CHECK (recurrence IN ('once', 'weekly', 'monthly')),
+    status VARCHAR(16) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'paused', 'cancelled', 'completed', 'failed')),
+    -- первый срок: от него считаются следующие, чтобы ежемесячный перевод не съезжал
+    starts_at TIMESTAMP NOT NULL,
+    next_run_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK (owner_id <> to_user_id)
+);
+
+CREATE INDEX idx_scheduled_transfers_due ON scheduled_transfers (next_run_at) WHERE status = 'active';
+CREATE INDEX idx_scheduled_transfers_owner ON scheduled_transfers (owner_id);
+
+-- Каждый запуск, включая неудачные, остаётся в истории расписания
+CREATE TABLE scheduled_transfer_runs (
+    id SERIAL PRIMARY KEY,
+    schedule_id INT NOT NULL REFERENCES scheduled_transfers(id),
+    planned_at TIMESTAMP NOT NULL,
+    status VARCHAR(16) NOT NULL CHECK (status IN ('succeeded', 'failed')),
+    error TEXT NOT NULL DEFAULT '',
+    transaction_id INT REFERENCES transactions(id),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_scheduled_transfer_runs_schedule ON scheduled_transfer_runs (schedule_id, created_at);
+
+CREATE TABLE notifications (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id),
+    kind VARCHAR(32) NOT NULL,
+    message TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_notifications_user ON notifications (user_id, created_at);
@@ -0,0 +1,13 @@
+-- Каждая покупка мерча; у подарка получатель отличается от покупателя
+CREATE TABLE purchases (
+    id SERIAL PRIMARY KEY,
+    buyer_id INT NOT NULL REFERENCES users(id),
+    recipient_id INT NOT NULL REFERENCES users(id),
+    item_id INT NOT NULL REFERENCES items(id),
+    price INT NOT NULL CHECK (price >= 0),
+    message VARCHAR(500) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_purchases_buyer ON purchases (buyer_id, created_at);
+CREATE INDEX idx_purchases_recipient ON purchases (recipient_id, created_at);
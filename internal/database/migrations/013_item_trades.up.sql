@@ -0,0 +1,39 @@
+ALTER TABLE inventory ADD CONSTRAINT inventory_quantity_non_negative CHECK (quantity >= 0);
+
+CREATE TABLE trades (
+    id SERIAL PRIMARY KEY,
+    proposer_id INT NOT NULL REFERENCES users(id),
+    counterparty_id INT NOT NULL REFERENCES users(id),
+    proposer_coins INT NOT NULL DEFAULT 0 CHECK (proposer_coins >= 0),
+    counterparty_coins INT NOT NULL DEFAULT 0 CHECK (counterparty_coins >= 0),
+    status VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted', 'declined', 'cancelled')),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    resolved_at TIMESTAMP,
+    CHECK (proposer_id <> counterparty_id)
+);
+
+CREATE INDEX idx_trades_proposer ON trades (proposer_id, created_at);
+CREATE INDEX idx_trades_counterparty ON trades (counterparty_id, created_at);
+
+-- Предметы, которые отдаёт каждая из сторон обмена
+CREATE TABLE trade_items (
+    trade_id INT NOT NULL REFERENCES trades(id),
+    side VARCHAR(16) NOT NULL CHECK (side IN ('proposer', 'counterparty')),
+    item_id INT NOT NULL REFERENCES items(id),
+    quantity INT NOT NULL CHECK (quantity > 0),
+    PRIMARY KEY (trade_id, side, item_id)
+);
+
+-- История перемещений предметов между сотрудниками (передачи и обмены)
+CREATE TABLE item_transfers (
+    id SERIAL PRIMARY KEY,
+    from_user_id INT NOT NULL REFERENCES users(id),
+    to_user_id INT NOT NULL REFERENCES users(id),
+    item_id INT NOT NULL REFERENCES items(id),
+    quantity INT NOT NULL CHECK (quantity > 0),
+    trade_id INT REFERENCES trades(id),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_item_transfers_from ON item_transfers (from_user_id, created_at);
+CREATE INDEX idx_item_transfers_to ON item_transfers (to_user_id, created_at);
@@ -0,0 +1,30 @@
+-- Аукционы на редкие предметы каталога: ставки резервируют монеты, победитель
+-- оплачивает лот при закрытии
+CREATE TABLE auctions (
+    id SERIAL PRIMARY KEY,
+    item_id INT NOT NULL REFERENCES items(id),
+    created_by INT NOT NULL REFERENCES users(id),
+    starting_price INT NOT NULL CHECK (starting_price > 0),
+    min_increment INT NOT NULL DEFAULT 1 CHECK (min_increment > 0),
+    leader_id INT REFERENCES users(id),
+    current_bid INT NOT NULL DEFAULT 0 CHECK (current_bid >= 0),
+    status VARCHAR(16) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'sold', 'unsold')),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    ends_at TIMESTAMP NOT NULL,
+    closed_at TIMESTAMP
+);
+
+CREATE INDEX idx_auctions_open_ends_at ON auctions (ends_at) WHERE status = 'open';
+
+CREATE TABLE auction_bids (
+    id SERIAL PRIMARY KEY,
+    auction_id INT NOT NULL REFERENCES auctions(id),
+    user_id INT NOT NULL REFERENCES users(id),
+    amount INT NOT NULL CHECK (amount > 0),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_auction_bids_auction ON auction_bids (auction_id, id);
+
+ALTER TABLE coin_holds ADD COLUMN auction_id INT REFERENCES auctions(id);
+CREATE INDEX idx_coin_holds_auction ON coin_holds (auction_id);
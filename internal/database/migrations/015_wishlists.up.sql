@@ -0,0 +1,16 @@
+-- NULL — предмет без ограничения запаса
+ALTER TABLE items ADD COLUMN stock INT CHECK (stock >= 0);
+
+-- Список желаний; notified_price, was_in_stock и was_affordable хранят состояние,
+-- о котором пользователь уже знает, чтобы уведомлять только об изменениях
+CREATE TABLE wishlist_items (
+    user_id INT NOT NULL REFERENCES users(id),
+    item_id INT NOT NULL REFERENCES items(id),
+    notified_price INT NOT NULL,
+    was_in_stock BOOLEAN NOT NULL,
+    was_affordable BOOLEAN NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, item_id)
+);
+
+CREATE INDEX idx_wishlist_items_item ON wishlist_items (item_id);
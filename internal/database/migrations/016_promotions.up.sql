@@ -0,0 +1,43 @@
+ALTER TABLE items ADD COLUMN category VARCHAR(64);
+
+-- Скидки: без кода применяются автоматически, с кодом — только по промокоду.
+-- item_id и category ограничивают область действия; если оба NULL, скидка на весь каталог.
+CREATE TABLE promotions (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(64) UNIQUE,
+    kind VARCHAR(16) NOT NULL CHECK (kind IN ('percent', 'fixed')),
+    value INT NOT NULL CHECK (value > 0),
+    item_id INT REFERENCES items(id),
+    category VARCHAR(64),
+    starts_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    ends_at TIMESTAMP,
+    max_uses INT CHECK (max_uses > 0),
+    per_user_limit INT CHECK (per_user_limit > 0),
+    uses INT NOT NULL DEFAULT 0,
+    stackable BOOLEAN NOT NULL DEFAULT FALSE,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK (kind <> 'percent' OR value <= 100),
+    CHECK (item_id IS NULL OR category IS NULL),
+    CHECK (ends_at IS NULL OR ends_at > starts_at),
+    CHECK (max_uses IS NULL OR uses <= max_uses)
+);
+
+CREATE INDEX idx_promotions_automatic ON promotions (starts_at) WHERE active AND code IS NULL;
+
+ALTER TABLE purchases ADD COLUMN list_price INT;
+UPDATE purchases SET list_price = price;
+ALTER TABLE purchases ALTER COLUMN list_price SET NOT NULL;
+ALTER TABLE purchases ADD COLUMN discount INT NOT NULL DEFAULT 0 CHECK (discount >= 0);
+ALTER TABLE purchases ADD COLUMN promo_code VARCHAR(64);
+
+CREATE TABLE promotion_redemptions (
+    id SERIAL PRIMARY KEY,
+    promotion_id INT NOT NULL REFERENCES promotions(id),
+    user_id INT NOT NULL REFERENCES users(id),
+    purchase_id INT NOT NULL REFERENCES purchases(id),
+    discount INT NOT NULL CHECK (discount >= 0),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_promotion_redemptions_promotion_user ON promotion_redemptions (promotion_id, user_id);
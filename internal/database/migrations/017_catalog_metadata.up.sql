@@ -0,0 +1,32 @@
+CREATE TABLE categories (
+    slug VARCHAR(64) PRIMARY KEY,
+    name VARCHAR(128) NOT NULL,
+    sort_order INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO categories (slug, name, sort_order) VALUES
+    ('apparel', 'Одежда', 10),
+    ('office', 'Офис', 20),
+    ('accessories', 'Аксессуары', 30);
+
+-- Категории, которые уже указаны у предметов и скидок, становятся записями справочника
+INSERT INTO categories (slug, name)
+SELECT DISTINCT category, category FROM items WHERE category IS NOT NULL
+UNION
+SELECT DISTINCT category, category FROM promotions WHERE category IS NOT NULL
+ON CONFLICT (slug) DO NOTHING;
+
+ALTER TABLE items ADD CONSTRAINT items_category_fkey
+    FOREIGN KEY (category) REFERENCES categories (slug) ON UPDATE CASCADE;
+ALTER TABLE promotions ADD CONSTRAINT promotions_category_fkey
+    FOREIGN KEY (category) REFERENCES categories (slug) ON UPDATE CASCADE;
+
+ALTER TABLE items ADD COLUMN description TEXT NOT NULL DEFAULT '';
+ALTER TABLE items ADD COLUMN tags TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE items ADD COLUMN image_urls TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE items ADD COLUMN sort_order INT NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_items_category ON items (category, sort_order);
+CREATE INDEX idx_items_tags ON items USING gin (tags);
+CREATE INDEX idx_items_name_trgm ON items USING gin (name gin_trgm_ops);
@@ -0,0 +1,13 @@
+CREATE TABLE item_prices (
+    id SERIAL PRIMARY KEY,
+    item_id INT NOT NULL REFERENCES items(id),
+    price INT NOT NULL CHECK (price > 0),
+    effective_from TIMESTAMP NOT NULL,
+    created_by INT REFERENCES users(id),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (item_id, effective_from)
+);
+
+-- Текущие цены становятся первой записью истории
+INSERT INTO item_prices (item_id, price, effective_from)
+SELECT id, price, NOW() FROM items;
@@ -0,0 +1,10 @@
+CREATE TABLE user_badges (
+    user_id INT NOT NULL REFERENCES users(id),
+    badge VARCHAR(64) NOT NULL,
+    reward INT NOT NULL DEFAULT 0,
+    awarded_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, badge)
+);
+
+-- Значок «Коллекционер кружек» выдаётся за все предметы с тегом cup
+UPDATE items SET tags = array_append(tags, 'cup') WHERE name = 'cup' AND NOT 'cup' = ANY(tags);
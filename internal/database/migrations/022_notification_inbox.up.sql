@@ -0,0 +1,19 @@
+ALTER TABLE notifications ADD COLUMN read_at TIMESTAMP;
+
+CREATE INDEX idx_notifications_unread ON notifications (user_id) WHERE read_at IS NULL;
+
+-- Отключённые пользователем виды уведомлений; вид без записи включён
+CREATE TABLE notification_preferences (
+    user_id INT NOT NULL REFERENCES users(id),
+    kind VARCHAR(64) NOT NULL,
+    enabled BOOLEAN NOT NULL,
+    PRIMARY KEY (user_id, kind)
+);
+
+-- Устройства (IP и User-Agent), с которых пользователь входил; device — их хеш
+CREATE TABLE user_devices (
+    user_id INT NOT NULL REFERENCES users(id),
+    device CHAR(64) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, device)
+);
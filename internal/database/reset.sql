@@ -1 +1 @@
-TRUNCATE TABLE users, transactions, inventory RESTART IDENTITY;
\ No newline at end of file
+TRUNCATE TABLE users, transactions, inventory, password_reset_tokens, api_keys, teams, team_members, payment_requests, bets, coin_holds, scheduled_transfers, scheduled_transfer_runs, notifications, purchases, trades, trade_items, item_transfers, auctions, auction_bids, wishlist_items, promotions, promotion_redemptions, item_prices, user_badges, outbox_events, webhook_subscriptions, webhook_deliveries, notification_preferences, user_devices RESTART IDENTITY;
\ No newline at end of file
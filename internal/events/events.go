@@ -0,0 +1,91 @@
+// Package events — внутренняя шина событий: сервисы сообщают о завершённых
+// операциях, а подписчики реагируют на них.
+package events
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	CoinsSent     = "coins_sent"
+	CoinsGranted  = "coins_granted"
+	ItemPurchased = "item_purchased"
+	// BalanceChanged — баланс или резерв User изменила операция без собственного
+	// события: пари, ставка на аукционе, обмен, награда и т.п.
+	BalanceChanged = "balance_changed"
+	// InventoryChanged — предметы в инвентаре User переложила операция без
+	// собственного события: передача предмета или обмен
+	InventoryChanged = "inventory_changed"
+)
+
+// Event — завершённая операция. User совершил действие; Target — второй участник:
+// получатель перевода, начисления или предмета (при покупке себе совпадает с User;
+// у начисления User пуст).
+type Event struct {
+	Kind     string
+	UserID   int
+	User     string
+	TargetID int
+	Target   string
+	ItemID   int
+	Amount   int
+	At       time.Time
+}
+
+type Handler func(e Event) error
+
+// Bus синхронно доставляет события подписчикам в порядке подписки.
+// Методы nil-шины ничего не делают, поэтому сервисы можно создавать без неё.
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[string][]namedHandler
+}
+
+type namedHandler struct {
+	name string
+	fn   Handler
+}
+
+func NewBus() *Bus {
+	return &Bus{handlers: make(map[string][]namedHandler)}
+}
+
+// Subscribe подписывает fn на события вида kind; name попадает в лог при ошибке
+func (b *Bus) Subscribe(kind, name string, fn Handler) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[kind] = append(b.handlers[kind], namedHandler{name: name, fn: fn})
+}
+
+// Publish вызывается после фиксации транзакции. Ошибки и паники подписчиков
+// только логируются: операция, о которой сообщает событие, уже выполнена.
+func (b *Bus) Publish(e Event) {
+	if b == nil {
+		return
+	}
+	if e.At.IsZero() {
+		e.At = time.Now()
+	}
+	b.mu.RLock()
+	handlers := b.handlers[e.Kind]
+	b.mu.RUnlock()
+	for _, h := range handlers {
+		deliver(h, e)
+	}
+}
+
+func deliver(h namedHandler, e Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Подписчик %s упал на событии %s: %v", h.name, e.Kind, r)
+		}
+	}()
+	if err := h.fn(e); err != nil {
+		log.Printf("Подписчик %s не обработал событие %s: %v", h.name, e.Kind, err)
+	}
+}
@@ -0,0 +1,63 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+)
+
+// Sink — внешний получатель событий outbox (Redis Streams, Kafka, NATS).
+// Доставка «хотя бы один раз»: одно событие может прийти повторно,
+// получатели различают повторы по ID.
+type Sink interface {
+	Publish(ctx context.Context, e models.OutboxEvent) error
+}
+
+// RedisStreamSink добавляет события в Redis Stream; поток обрезается примерно
+// до maxLen последних записей
+type RedisStreamSink struct {
+	client *redis.Client
+	stream string
+	maxLen int64
+}
+
+func NewRedisStreamSink(client *redis.Client, stream string, maxLen int64) *RedisStreamSink {
+	return &RedisStreamSink{client: client, stream: stream, maxLen: maxLen}
+}
+
+func (s *RedisStreamSink) Publish(ctx context.Context, e models.OutboxEvent) error {
+	err := s.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: s.stream,
+		MaxLen: s.maxLen,
+		Approx: true,
+		Values: map[string]interface{}{
+			"id":         strconv.FormatInt(e.ID, 10),
+			"type":       e.Type,
+			"user_id":    strconv.Itoa(e.UserID),
+			"payload":    string(e.Payload),
+			"created_at": e.CreatedAt.Format(time.RFC3339Nano),
+		},
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("ошибка записи в поток %s: %w", s.stream, err)
+	}
+	return nil
+}
+
+// Fanout публикует событие во все sinks по очереди. Если какой-то из них
+// откажет, событие будет опубликовано повторно во все, поэтому получатели
+// должны отбрасывать повторы.
+type Fanout []Sink
+
+func (f Fanout) Publish(ctx context.Context, e models.OutboxEvent) error {
+	for _, s := range f {
+		if err := s.Publish(ctx, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
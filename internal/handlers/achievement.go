@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"log"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handlers) GetBadges(c *gin.Context) {
+	c.JSON(200, h.achievementService.GetAvailableBadges())
+}
+
+func (h *Handlers) GetUserBadges(c *gin.Context) {
+	username := c.Param("username")
+	badges, err := h.achievementService.GetBadges(username)
+	if err != nil {
+		log.Printf("GetUserBadges failed for %s: %v", username, err)
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, badges)
+}
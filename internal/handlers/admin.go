@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"log"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handlers) DeactivateUser(c *gin.Context) {
+	username := c.Param("username")
+	var req struct {
+		TransferTo string `json:"transferTo"`
+	}
+	// Тело необязательно: без transferTo остаток остаётся на счёте
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(400, gin.H{"error": "Неверный запрос"})
+			return
+		}
+	}
+	if err := h.userService.DeactivateUser(username, req.TransferTo); err != nil {
+		log.Printf("DeactivateUser failed for user %s: %v", username, err)
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("DeactivateUser succeeded for user %s by %s, balance moved to %q", username, c.MustGet("username").(string), req.TransferTo)
+	c.JSON(200, gin.H{"message": "Пользователь деактивирован"})
+}
+
+func (h *Handlers) ReactivateUser(c *gin.Context) {
+	username := c.Param("username")
+	if err := h.userService.ReactivateUser(username); err != nil {
+		log.Printf("ReactivateUser failed for user %s: %v", username, err)
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("ReactivateUser succeeded for user %s by %s", username, c.MustGet("username").(string))
+	c.JSON(200, gin.H{"message": "Пользователь активирован"})
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"log"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handlers) CreateAPIKey(c *gin.Context) {
+	var req struct {
+		Username string   `json:"username"`
+		Name     string   `json:"name"`
+		Scopes   []string `json:"scopes"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Неверный запрос"})
+		return
+	}
+	rawKey, key, err := h.apiKeyService.CreateAPIKey(req.Username, req.Name, req.Scopes)
+	if err != nil {
+		log.Printf("CreateAPIKey failed for user %s: %v", req.Username, err)
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("CreateAPIKey succeeded for user %s, key %d", req.Username, key.ID)
+	c.JSON(200, gin.H{"key": rawKey, "apiKey": key})
+}
+
+func (h *Handlers) ListAPIKeys(c *gin.Context) {
+	keys, err := h.apiKeyService.ListAPIKeys()
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, keys)
+}
+
+func (h *Handlers) RevokeAPIKey(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Неверный идентификатор ключа"})
+		return
+	}
+	if err := h.apiKeyService.RevokeAPIKey(id); err != nil {
+		log.Printf("RevokeAPIKey failed for key %d: %v", id, err)
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("RevokeAPIKey succeeded for key %d", id)
+	c.JSON(200, gin.H{"message": "Ключ отозван"})
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handlers) CreateAuction(c *gin.Context) {
+	var req struct {
+		Item          string    `json:"item"`
+		StartingPrice int       `json:"startingPrice"`
+		MinIncrement  int       `json:"minIncrement"`
+		EndsAt        time.Time `json:"endsAt"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Неверный запрос"})
+		return
+	}
+	username := c.MustGet("username").(string)
+	auction, err := h.auctionService.CreateAuction(username, req.Item, req.StartingPrice, req.MinIncrement, req.EndsAt)
+	if err != nil {
+		log.Printf("CreateAuction failed for %s, item %s: %v", username, req.Item, err)
+		respondError(c, err)
+		return
+	}
+	log.Printf("CreateAuction succeeded for %s, item %s, auction %d", username, req.Item, auction.ID)
+	c.JSON(200, auction)
+}
+
+func (h *Handlers) GetAuctions(c *gin.Context) {
+	auctions, err := h.auctionService.GetAuctions()
+	if err != nil {
+		log.Printf("GetAuctions failed: %v", err)
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, auctions)
+}
+
+func (h *Handlers) GetAuction(c *gin.Context) {
+	id, ok := auctionIDParam(c)
+	if !ok {
+		return
+	}
+	auction, err := h.auctionService.GetAuction(id)
+	if err != nil {
+		log.Printf("GetAuction failed for auction %d: %v", id, err)
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, auction)
+}
+
+func (h *Handlers) PlaceBid(c *gin.Context) {
+	id, ok := auctionIDParam(c)
+	if !ok {
+		return
+	}
+	var req struct {
+		Amount int `json:"amount"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Неверный запрос"})
+		return
+	}
+	username := c.MustGet("username").(string)
+	bid, err := h.auctionService.PlaceBid(username, id, req.Amount)
+	if err != nil {
+		log.Printf("PlaceBid failed for %s, auction %d, amount %d: %v", username, id, req.Amount, err)
+		respondError(c, err)
+		return
+	}
+	log.Printf("PlaceBid succeeded for %s, auction %d, amount %d", username, id, req.Amount)
+	c.JSON(200, bid)
+}
+
+func (h *Handlers) GetAuctionBids(c *gin.Context) {
+	id, ok := auctionIDParam(c)
+	if !ok {
+		return
+	}
+	bids, err := h.auctionService.GetBids(id)
+	if err != nil {
+		log.Printf("GetAuctionBids failed for auction %d: %v", id, err)
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, bids)
+}
+
+func auctionIDParam(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Неверный идентификатор аукциона"})
+		return 0, false
+	}
+	return id, true
+}
@@ -41,6 +41,61 @@ func (h *Handlers) Authenticate(c *gin.Context) {
 		c.JSON(401, gin.H{"error": "Неверный логин или пароль"})
 		return
 	}
+	if err := h.notificationService.RecordLogin(req.Username, c.ClientIP(), c.Request.UserAgent()); err != nil {
+		log.Printf("RecordLogin failed for %s: %v", req.Username, err)
+	}
 	log.Printf("Authenticate %s took %v", req.Username, time.Since(start))
 	c.JSON(200, gin.H{"token": token})
 }
+
+func (h *Handlers) ChangePassword(c *gin.Context) {
+	var req struct {
+		OldPassword string `json:"oldPassword"`
+		NewPassword string `json:"newPassword"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Неверный запрос"})
+		return
+	}
+	username := c.MustGet("username").(string)
+	token, err := h.authService.ChangePassword(username, req.OldPassword, req.NewPassword)
+	if err != nil {
+		log.Printf("ChangePassword failed for user %s: %v", username, err)
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.notificationService.NotifyPasswordChanged(username); err != nil {
+		log.Printf("NotifyPasswordChanged failed for user %s: %v", username, err)
+	}
+	log.Printf("ChangePassword succeeded for user %s", username)
+	c.JSON(200, gin.H{"token": token})
+}
+
+func (h *Handlers) IssuePasswordReset(c *gin.Context) {
+	username := c.Param("username")
+	token, expiresAt, err := h.authService.IssuePasswordReset(username)
+	if err != nil {
+		log.Printf("IssuePasswordReset failed for user %s: %v", username, err)
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("IssuePasswordReset succeeded for user %s by %s", username, c.MustGet("username").(string))
+	c.JSON(200, gin.H{"resetToken": token, "expiresAt": expiresAt})
+}
+
+func (h *Handlers) ResetPassword(c *gin.Context) {
+	var req struct {
+		Token       string `json:"token"`
+		NewPassword string `json:"newPassword"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Неверный запрос"})
+		return
+	}
+	if err := h.authService.ResetPassword(req.Token, req.NewPassword); err != nil {
+		log.Printf("ResetPassword failed: %v", err)
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"message": "Пароль успешно изменён"})
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handlers) CreateBet(c *gin.Context) {
+	var req struct {
+		Opponent    string    `json:"opponent"`
+		Arbiter     string    `json:"arbiter"`
+		Stake       int       `json:"stake"`
+		Description string    `json:"description"`
+		ExpiresAt   time.Time `json:"expiresAt"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Неверный запрос"})
+		return
+	}
+	username := c.MustGet("username").(string)
+	bet, err := h.betService.CreateBet(username, req.Opponent, req.Arbiter, req.Stake, req.Description, req.ExpiresAt)
+	if err != nil {
+		log.Printf("CreateBet failed for %s against %s, stake %d: %v", username, req.Opponent, req.Stake, err)
+		respondError(c, err)
+		return
+	}
+	log.Printf("CreateBet succeeded for %s against %s, stake %d, bet %d", username, req.Opponent, req.Stake, bet.ID)
+	c.JSON(200, bet)
+}
+
+func (h *Handlers) GetBets(c *gin.Context) {
+	username := c.MustGet("username").(string)
+	bets, err := h.betService.GetBets(username)
+	if err != nil {
+		log.Printf("GetBets failed for %s: %v", username, err)
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, bets)
+}
+
+func (h *Handlers) AcceptBet(c *gin.Context) {
+	id, ok := betIDParam(c)
+	if !ok {
+		return
+	}
+	username := c.MustGet("username").(string)
+	bet, err := h.betService.AcceptBet(username, id)
+	if err != nil {
+		log.Printf("AcceptBet failed for %s, bet %d: %v", username, id, err)
+		respondError(c, err)
+		return
+	}
+	log.Printf("AcceptBet succeeded for %s, bet %d", username, id)
+	c.JSON(200, bet)
+}
+
+func (h *Handlers) DeclineBet(c *gin.Context) {
+	id, ok := betIDParam(c)
+	if !ok {
+		return
+	}
+	username := c.MustGet("username").(string)
+	bet, err := h.betService.DeclineBet(username, id)
+	if err != nil {
+		log.Printf("DeclineBet failed for %s, bet %d: %v", username, id, err)
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, bet)
+}
+
+func (h *Handlers) SettleBet(c *gin.Context) {
+	id, ok := betIDParam(c)
+	if !ok {
+		return
+	}
+	var req struct {
+		Winner string `json:"winner"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Неверный запрос"})
+		return
+	}
+	username := c.MustGet("username").(string)
+	bet, err := h.betService.SettleBet(username, id, req.Winner)
+	if err != nil {
+		log.Printf("SettleBet failed for %s, bet %d, winner %s: %v", username, id, req.Winner, err)
+		respondError(c, err)
+		return
+	}
+	log.Printf("SettleBet succeeded for %s, bet %d, winner %s", username, id, req.Winner)
+	c.JSON(200, bet)
+}
+
+func betIDParam(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Неверный идентификатор пари"})
+		return 0, false
+	}
+	return id, true
+}
@@ -8,20 +8,48 @@ import (
 )
 
 type Handlers struct {
-	config       *config.Config
-	authService  *services.AuthService
-	userService  *services.UserService
-	itemService  *services.ItemService
-	transService *services.TransactionService
+	config                *config.Config
+	authService           *services.AuthService
+	userService           *services.UserService
+	itemService           *services.ItemService
+	transService          *services.TransactionService
+	apiKeyService         *services.APIKeyService
+	teamService           *services.TeamService
+	paymentRequestService *services.PaymentRequestService
+	betService            *services.BetService
+	scheduleService       *services.ScheduleService
+	notificationService   *services.NotificationService
+	tradeService          *services.TradeService
+	auctionService        *services.AuctionService
+	wishlistService       *services.WishlistService
+	promotionService      *services.PromotionService
+	achievementService    *services.AchievementService
+	leaderboardService    *services.LeaderboardService
+	webhookService        *services.WebhookService
+	streamService         *services.StreamService
 }
 
-func NewHandlers(config *config.Config, authService *services.AuthService, userService *services.UserService, itemService *services.ItemService, transService *services.TransactionService) *Handlers {
+func NewHandlers(config *config.Config, authService *services.AuthService, userService *services.UserService, itemService *services.ItemService, transService *services.TransactionService, apiKeyService *services.APIKeyService, teamService *services.TeamService, paymentRequestService *services.PaymentRequestService, betService *services.BetService, scheduleService *services.ScheduleService, notificationService *services.NotificationService, tradeService *services.TradeService, auctionService *services.AuctionService, wishlistService *services.WishlistService, promotionService *services.PromotionService, achievementService *services.AchievementService, leaderboardService *services.LeaderboardService, webhookService *services.WebhookService, streamService *services.StreamService) *Handlers {
 	return &Handlers{
-		config:       config,
-		authService:  authService,
-		userService:  userService,
-		itemService:  itemService,
-		transService: transService,
+		config:                config,
+		authService:           authService,
+		userService:           userService,
+		itemService:           itemService,
+		transService:          transService,
+		apiKeyService:         apiKeyService,
+		teamService:           teamService,
+		paymentRequestService: paymentRequestService,
+		betService:            betService,
+		scheduleService:       scheduleService,
+		notificationService:   notificationService,
+		tradeService:          tradeService,
+		auctionService:        auctionService,
+		wishlistService:       wishlistService,
+		promotionService:      promotionService,
+		achievementService:    achievementService,
+		leaderboardService:    leaderboardService,
+		webhookService:        webhookService,
+		streamService:         streamService,
 	}
 }
 
@@ -32,3 +60,13 @@ func (h *Handlers) ResetDB(c *gin.Context) {
 	}
 	c.JSON(200, gin.H{"message": "База данных очищена"})
 }
+
+// respondError отдаёт клиенту текст ошибок бизнес-логики (400), а внутренние
+// ошибки скрывает за общим сообщением (500); подробности остаются в логе.
+func respondError(c *gin.Context, err error) {
+	if services.IsUserError(err) {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(500, gin.H{"error": "Внутренняя ошибка сервера"})
+}
@@ -2,8 +2,11 @@ package handlers
 
 import (
 	"log"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/itocode21/MerchServiceAvito/internal/models"
 )
 
 func (h *Handlers) BuyItem(c *gin.Context) {
@@ -15,7 +18,7 @@ func (h *Handlers) BuyItem(c *gin.Context) {
 	}
 
 	username := c.MustGet("username").(string)
-	err := h.itemService.BuyItem(username, itemName)
+	err := h.itemService.BuyItem(username, itemName, c.Query("promo"))
 	if err != nil {
 		log.Printf("BuyItem failed for user %s, item %s: %v", username, itemName, err)
 		c.JSON(400, gin.H{"error": err.Error()})
@@ -25,3 +28,116 @@ func (h *Handlers) BuyItem(c *gin.Context) {
 	log.Printf("BuyItem succeeded for user %s, item %s", username, itemName)
 	c.JSON(200, gin.H{"message": "Предмет успешно куплен"})
 }
+
+func (h *Handlers) GiftItem(c *gin.Context) {
+	var req struct {
+		ToUser    string `json:"toUser"`
+		Message   string `json:"message"`
+		PromoCode string `json:"promoCode"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Неверный запрос"})
+		return
+	}
+	itemName := c.Param("item")
+	username := c.MustGet("username").(string)
+	purchase, err := h.itemService.GiftItem(username, req.ToUser, itemName, req.Message, req.PromoCode)
+	if err != nil {
+		log.Printf("GiftItem failed for user %s to %s, item %s: %v", username, req.ToUser, itemName, err)
+		respondError(c, err)
+		return
+	}
+	log.Printf("GiftItem succeeded for user %s to %s, item %s", username, req.ToUser, itemName)
+	c.JSON(200, purchase)
+}
+
+func (h *Handlers) UpdateItem(c *gin.Context) {
+	var req models.ItemUpdate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Неверный запрос"})
+		return
+	}
+	itemName := c.Param("item")
+	username := c.MustGet("username").(string)
+	item, err := h.itemService.UpdateItem(username, itemName, req)
+	if err != nil {
+		log.Printf("UpdateItem failed for %s, item %s: %v", username, itemName, err)
+		respondError(c, err)
+		return
+	}
+	log.Printf("UpdateItem succeeded for %s, item %s", username, itemName)
+	c.JSON(200, item)
+}
+
+func (h *Handlers) ListItems(c *gin.Context) {
+	filter := models.CatalogFilter{
+		Query:    c.Query("q"),
+		Category: c.Query("category"),
+		InStock:  c.Query("inStock") == "true",
+	}
+	if tags := c.Query("tags"); tags != "" {
+		filter.Tags = strings.Split(tags, ",")
+	}
+	for param, dst := range map[string]*int{
+		"minPrice": &filter.MinPrice,
+		"maxPrice": &filter.MaxPrice,
+		"limit":    &filter.Limit,
+		"offset":   &filter.Offset,
+	} {
+		if raw := c.Query(param); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil {
+				c.JSON(400, gin.H{"error": "Неверное значение параметра " + param})
+				return
+			}
+			*dst = n
+		}
+	}
+	items, err := h.itemService.ListItems(filter)
+	if err != nil {
+		log.Printf("ListItems failed: %v", err)
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, items)
+}
+
+func (h *Handlers) GetItem(c *gin.Context) {
+	itemName := c.Param("item")
+	item, err := h.itemService.GetItem(itemName)
+	if err != nil {
+		log.Printf("GetItem failed for item %s: %v", itemName, err)
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, item)
+}
+
+func (h *Handlers) GetCategories(c *gin.Context) {
+	categories, err := h.itemService.GetCategories()
+	if err != nil {
+		log.Printf("GetCategories failed: %v", err)
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, categories)
+}
+
+func (h *Handlers) SaveCategory(c *gin.Context) {
+	var req struct {
+		Name      string `json:"name"`
+		SortOrder int    `json:"sortOrder"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Неверный запрос"})
+		return
+	}
+	category := &models.Category{Slug: c.Param("slug"), Name: req.Name, SortOrder: req.SortOrder}
+	if err := h.itemService.SaveCategory(category); err != nil {
+		log.Printf("SaveCategory failed for %s: %v", category.Slug, err)
+		respondError(c, err)
+		return
+	}
+	log.Printf("SaveCategory succeeded for %s", category.Slug)
+	c.JSON(200, category)
+}
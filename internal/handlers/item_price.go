@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handlers) GetPriceTimeline(c *gin.Context) {
+	itemName := c.Param("item")
+	timeline, err := h.itemService.GetPriceTimeline(itemName)
+	if err != nil {
+		log.Printf("GetPriceTimeline failed for item %s: %v", itemName, err)
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, timeline)
+}
+
+func (h *Handlers) SchedulePrice(c *gin.Context) {
+	var req struct {
+		Price         int       `json:"price"`
+		EffectiveFrom time.Time `json:"effectiveFrom"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Неверный запрос"})
+		return
+	}
+	itemName := c.Param("item")
+	username := c.MustGet("username").(string)
+	price, err := h.itemService.SchedulePrice(username, itemName, req.Price, req.EffectiveFrom)
+	if err != nil {
+		log.Printf("SchedulePrice failed for %s, item %s: %v", username, itemName, err)
+		respondError(c, err)
+		return
+	}
+	log.Printf("SchedulePrice succeeded for %s, item %s, price %d", username, itemName, price.ID)
+	c.JSON(200, price)
+}
+
+func (h *Handlers) CancelScheduledPrice(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Неверный идентификатор цены"})
+		return
+	}
+	itemName := c.Param("item")
+	if err := h.itemService.CancelScheduledPrice(itemName, id); err != nil {
+		log.Printf("CancelScheduledPrice failed for item %s, price %d: %v", itemName, id, err)
+		respondError(c, err)
+		return
+	}
+	log.Printf("CancelScheduledPrice succeeded for item %s, price %d", itemName, id)
+	c.JSON(200, gin.H{"message": "Смена цены отменена"})
+}
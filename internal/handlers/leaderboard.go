@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+)
+
+func (h *Handlers) GetLeaderboard(c *gin.Context) {
+	period := c.DefaultQuery("period", models.PeriodMonth)
+	at := time.Now()
+	if value := c.Query("at"); value != "" {
+		var err error
+		if at, err = time.Parse("2006-01-02", value); err != nil {
+			c.JSON(400, gin.H{"error": "Параметр at должен быть датой в формате ГГГГ-ММ-ДД"})
+			return
+		}
+	}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	board := c.Param("board")
+	username := c.MustGet("username").(string)
+	leaderboard, err := h.leaderboardService.GetLeaderboard(board, period, at, username, limit)
+	if err != nil {
+		log.Printf("GetLeaderboard failed for %s, board %s: %v", username, board, err)
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, leaderboard)
+}
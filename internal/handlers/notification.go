@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"log"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handlers) GetNotifications(c *gin.Context) {
+	username := c.MustGet("username").(string)
+	limit, offset, ok := pageParams(c)
+	if !ok {
+		return
+	}
+	notifications, err := h.notificationService.GetNotifications(username, c.Query("unread") == "true", limit, offset)
+	if err != nil {
+		log.Printf("GetNotifications failed for %s: %v", username, err)
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, notifications)
+}
+
+func (h *Handlers) GetUnreadCount(c *gin.Context) {
+	username := c.MustGet("username").(string)
+	n, err := h.notificationService.CountUnread(username)
+	if err != nil {
+		log.Printf("GetUnreadCount failed for %s: %v", username, err)
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, gin.H{"unread": n})
+}
+
+func (h *Handlers) MarkNotificationRead(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Неверный идентификатор уведомления"})
+		return
+	}
+	username := c.MustGet("username").(string)
+	if err := h.notificationService.MarkRead(username, id); err != nil {
+		log.Printf("MarkNotificationRead failed for %s, notification %d: %v", username, id, err)
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, gin.H{"message": "Уведомление прочитано"})
+}
+
+func (h *Handlers) MarkAllNotificationsRead(c *gin.Context) {
+	username := c.MustGet("username").(string)
+	n, err := h.notificationService.MarkAllRead(username)
+	if err != nil {
+		log.Printf("MarkAllNotificationsRead failed for %s: %v", username, err)
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, gin.H{"marked": n})
+}
+
+func (h *Handlers) GetNotificationPreferences(c *gin.Context) {
+	username := c.MustGet("username").(string)
+	prefs, err := h.notificationService.GetPreferences(username)
+	if err != nil {
+		log.Printf("GetNotificationPreferences failed for %s: %v", username, err)
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, prefs)
+}
+
+func (h *Handlers) SetNotificationPreferences(c *gin.Context) {
+	var req map[string]bool
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Неверный запрос"})
+		return
+	}
+	username := c.MustGet("username").(string)
+	prefs, err := h.notificationService.SetPreferences(username, req)
+	if err != nil {
+		log.Printf("SetNotificationPreferences failed for %s: %v", username, err)
+		respondError(c, err)
+		return
+	}
+	log.Printf("SetNotificationPreferences succeeded for %s", username)
+	c.JSON(200, prefs)
+}
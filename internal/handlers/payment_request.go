@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"log"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handlers) CreateCoinRequest(c *gin.Context) {
+	var req struct {
+		FromUser string `json:"fromUser"`
+		Amount   int    `json:"amount"`
+		Memo     string `json:"memo"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Неверный запрос"})
+		return
+	}
+	username := c.MustGet("username").(string)
+	pr, err := h.paymentRequestService.CreateRequest(username, req.FromUser, req.Amount, req.Memo)
+	if err != nil {
+		log.Printf("CreateCoinRequest failed for %s from %s, amount %d: %v", username, req.FromUser, req.Amount, err)
+		respondError(c, err)
+		return
+	}
+	log.Printf("CreateCoinRequest succeeded for %s from %s, amount %d", username, req.FromUser, req.Amount)
+	c.JSON(200, pr)
+}
+
+func (h *Handlers) GetCoinRequests(c *gin.Context) {
+	username := c.MustGet("username").(string)
+	incoming, outgoing, err := h.paymentRequestService.GetRequests(username)
+	if err != nil {
+		log.Printf("GetCoinRequests failed for %s: %v", username, err)
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, gin.H{"incoming": incoming, "outgoing": outgoing})
+}
+
+func (h *Handlers) ApproveCoinRequest(c *gin.Context) {
+	id, ok := coinRequestIDParam(c)
+	if !ok {
+		return
+	}
+	username := c.MustGet("username").(string)
+	pr, err := h.paymentRequestService.ApproveRequest(username, id)
+	if err != nil {
+		log.Printf("ApproveCoinRequest failed for %s, request %d: %v", username, id, err)
+		respondError(c, err)
+		return
+	}
+	log.Printf("ApproveCoinRequest succeeded for %s, request %d", username, id)
+	c.JSON(200, pr)
+}
+
+func (h *Handlers) DeclineCoinRequest(c *gin.Context) {
+	id, ok := coinRequestIDParam(c)
+	if !ok {
+		return
+	}
+	username := c.MustGet("username").(string)
+	pr, err := h.paymentRequestService.DeclineRequest(username, id)
+	if err != nil {
+		log.Printf("DeclineCoinRequest failed for %s, request %d: %v", username, id, err)
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, pr)
+}
+
+func coinRequestIDParam(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Неверный идентификатор запроса"})
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+)
+
+func (h *Handlers) GetPrice(c *gin.Context) {
+	itemName := c.Param("item")
+	username := c.MustGet("username").(string)
+	quote, err := h.promotionService.Quote(username, itemName, c.Query("promo"))
+	if err != nil {
+		log.Printf("GetPrice failed for %s, item %s: %v", username, itemName, err)
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, quote)
+}
+
+func (h *Handlers) CreatePromotion(c *gin.Context) {
+	var req struct {
+		Code         string     `json:"code"`
+		Kind         string     `json:"kind"`
+		Value        int        `json:"value"`
+		Item         string     `json:"item"`
+		Category     string     `json:"category"`
+		StartsAt     time.Time  `json:"startsAt"`
+		EndsAt       *time.Time `json:"endsAt"`
+		MaxUses      int        `json:"maxUses"`
+		PerUserLimit int        `json:"perUserLimit"`
+		Stackable    bool       `json:"stackable"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Неверный запрос"})
+		return
+	}
+	promotion := &models.Promotion{
+		Code:         req.Code,
+		Kind:         req.Kind,
+		Value:        req.Value,
+		Item:         req.Item,
+		Category:     req.Category,
+		StartsAt:     req.StartsAt,
+		EndsAt:       req.EndsAt,
+		MaxUses:      req.MaxUses,
+		PerUserLimit: req.PerUserLimit,
+		Stackable:    req.Stackable,
+	}
+	if err := h.promotionService.CreatePromotion(promotion); err != nil {
+		log.Printf("CreatePromotion failed for code %q: %v", req.Code, err)
+		respondError(c, err)
+		return
+	}
+	log.Printf("CreatePromotion succeeded, promotion %d", promotion.ID)
+	c.JSON(200, promotion)
+}
+
+func (h *Handlers) GetPromotions(c *gin.Context) {
+	promotions, err := h.promotionService.GetPromotions()
+	if err != nil {
+		log.Printf("GetPromotions failed: %v", err)
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, promotions)
+}
+
+func (h *Handlers) DeactivatePromotion(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Неверный идентификатор скидки"})
+		return
+	}
+	if err := h.promotionService.DeactivatePromotion(id); err != nil {
+		log.Printf("DeactivatePromotion failed for promotion %d: %v", id, err)
+		respondError(c, err)
+		return
+	}
+	log.Printf("DeactivatePromotion succeeded, promotion %d", id)
+	c.JSON(200, gin.H{"message": "Скидка отключена"})
+}
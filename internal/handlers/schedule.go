@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+)
+
+func (h *Handlers) CreateSchedule(c *gin.Context) {
+	var req struct {
+		ToUser     string    `json:"toUser"`
+		Amount     int       `json:"amount"`
+		Recurrence string    `json:"recurrence"`
+		StartAt    time.Time `json:"startAt"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Неверный запрос"})
+		return
+	}
+	username := c.MustGet("username").(string)
+	st, err := h.scheduleService.CreateSchedule(username, req.ToUser, req.Amount, req.Recurrence, req.StartAt)
+	if err != nil {
+		log.Printf("CreateSchedule failed for %s to %s, amount %d: %v", username, req.ToUser, req.Amount, err)
+		respondError(c, err)
+		return
+	}
+	log.Printf("CreateSchedule succeeded for %s to %s, amount %d, %s", username, req.ToUser, req.Amount, st.Recurrence)
+	c.JSON(200, st)
+}
+
+func (h *Handlers) GetSchedules(c *gin.Context) {
+	username := c.MustGet("username").(string)
+	schedules, err := h.scheduleService.GetSchedules(username)
+	if err != nil {
+		log.Printf("GetSchedules failed for %s: %v", username, err)
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, schedules)
+}
+
+func (h *Handlers) GetScheduleRuns(c *gin.Context) {
+	id, ok := scheduleIDParam(c)
+	if !ok {
+		return
+	}
+	username := c.MustGet("username").(string)
+	runs, err := h.scheduleService.GetScheduleRuns(username, id)
+	if err != nil {
+		log.Printf("GetScheduleRuns failed for %s, schedule %d: %v", username, id, err)
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, runs)
+}
+
+func (h *Handlers) PauseSchedule(c *gin.Context) {
+	h.changeSchedule(c, "PauseSchedule", h.scheduleService.PauseSchedule)
+}
+
+func (h *Handlers) ResumeSchedule(c *gin.Context) {
+	h.changeSchedule(c, "ResumeSchedule", h.scheduleService.ResumeSchedule)
+}
+
+func (h *Handlers) CancelSchedule(c *gin.Context) {
+	h.changeSchedule(c, "CancelSchedule", h.scheduleService.CancelSchedule)
+}
+
+func (h *Handlers) changeSchedule(c *gin.Context, action string, change func(string, int) (*models.ScheduledTransfer, error)) {
+	id, ok := scheduleIDParam(c)
+	if !ok {
+		return
+	}
+	username := c.MustGet("username").(string)
+	st, err := change(username, id)
+	if err != nil {
+		log.Printf("%s failed for %s, schedule %d: %v", action, username, id, err)
+		respondError(c, err)
+		return
+	}
+	log.Printf("%s succeeded for %s, schedule %d", action, username, id)
+	c.JSON(200, st)
+}
+
+func scheduleIDParam(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Неверный идентификатор расписания"})
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"io"
+	"log"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Как часто поток шлёт комментарий-пинг, чтобы прокси не закрывали соединение
+const streamPingInterval = 25 * time.Second
+
+// Stream — поток Server-Sent Events с балансом и входящими переводами
+// пользователя. Первым приходит текущий баланс.
+func (h *Handlers) Stream(c *gin.Context) {
+	username := c.MustGet("username").(string)
+	ctx := c.Request.Context()
+	events, err := h.streamService.Listen(ctx, username)
+	if err != nil {
+		log.Printf("Stream failed for %s: %v", username, err)
+		respondError(c, err)
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	ping := time.NewTicker(streamPingInterval)
+	defer ping.Stop()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case e, ok := <-events:
+			if !ok {
+				return false
+			}
+			c.SSEvent(e.Kind, e.Data)
+			return true
+		case <-ping.C:
+			_, err := io.WriteString(w, ": ping\n\n")
+			return err == nil
+		}
+	})
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handlers) CreateTeam(c *gin.Context) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Неверный запрос"})
+		return
+	}
+	team, err := h.teamService.CreateTeam(req.Name)
+	if err != nil {
+		log.Printf("CreateTeam failed for %q: %v", req.Name, err)
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, team)
+}
+
+func (h *Handlers) SetTeamMember(c *gin.Context) {
+	teamID, ok := teamIDParam(c)
+	if !ok {
+		return
+	}
+	var req struct {
+		Role string `json:"role"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(400, gin.H{"error": "Неверный запрос"})
+			return
+		}
+	}
+	username := c.Param("username")
+	if err := h.teamService.SetMember(teamID, username, req.Role); err != nil {
+		log.Printf("SetTeamMember failed for team %d, user %s: %v", teamID, username, err)
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"message": "Участник добавлен"})
+}
+
+func (h *Handlers) RemoveTeamMember(c *gin.Context) {
+	teamID, ok := teamIDParam(c)
+	if !ok {
+		return
+	}
+	username := c.Param("username")
+	if err := h.teamService.RemoveMember(teamID, username); err != nil {
+		log.Printf("RemoveTeamMember failed for team %d, user %s: %v", teamID, username, err)
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"message": "Участник удалён"})
+}
+
+func (h *Handlers) FundTeam(c *gin.Context) {
+	teamID, ok := teamIDParam(c)
+	if !ok {
+		return
+	}
+	var req struct {
+		Amount int `json:"amount"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Неверный запрос"})
+		return
+	}
+	if err := h.teamService.FundTeam(teamID, req.Amount); err != nil {
+		log.Printf("FundTeam failed for team %d, amount %d: %v", teamID, req.Amount, err)
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("FundTeam succeeded for team %d, amount %d by %s", teamID, req.Amount, c.MustGet("username").(string))
+	c.JSON(200, gin.H{"message": "Бюджет команды пополнен"})
+}
+
+func (h *Handlers) GetMyTeams(c *gin.Context) {
+	teams, err := h.teamService.GetUserTeams(c.MustGet("username").(string))
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, teams)
+}
+
+func (h *Handlers) GetTeam(c *gin.Context) {
+	teamID, ok := teamIDParam(c)
+	if !ok {
+		return
+	}
+	team, err := h.teamService.GetTeam(c.MustGet("username").(string), teamID)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, team)
+}
+
+func (h *Handlers) RewardTeamMember(c *gin.Context) {
+	teamID, ok := teamIDParam(c)
+	if !ok {
+		return
+	}
+	var req struct {
+		ToUser string `json:"toUser"`
+		Amount int    `json:"amount"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Неверный запрос"})
+		return
+	}
+	manager := c.MustGet("username").(string)
+	if err := h.teamService.RewardMember(manager, teamID, req.ToUser, req.Amount); err != nil {
+		log.Printf("RewardTeamMember failed for team %d by %s to %s, amount %d: %v", teamID, manager, req.ToUser, req.Amount, err)
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("RewardTeamMember succeeded for team %d by %s to %s, amount %d", teamID, manager, req.ToUser, req.Amount)
+	c.JSON(200, gin.H{"message": "Монеты успешно отправлены"})
+}
+
+// GetTeamReport принимает период в формате YYYY-MM-DD (to не включается), по умолчанию — текущий месяц.
+func (h *Handlers) GetTeamReport(c *gin.Context) {
+	teamID, ok := teamIDParam(c)
+	if !ok {
+		return
+	}
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+	var err error
+	if v := c.Query("from"); v != "" {
+		if from, err = time.Parse(time.DateOnly, v); err != nil {
+			c.JSON(400, gin.H{"error": "Неверный формат даты from"})
+			return
+		}
+	}
+	if v := c.Query("to"); v != "" {
+		if to, err = time.Parse(time.DateOnly, v); err != nil {
+			c.JSON(400, gin.H{"error": "Неверный формат даты to"})
+			return
+		}
+	}
+	report, err := h.teamService.SpendingReport(c.MustGet("username").(string), teamID, from, to)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, report)
+}
+
+func teamIDParam(c *gin.Context) (int, bool) {
+	teamID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Неверный идентификатор команды"})
+		return 0, false
+	}
+	return teamID, true
+}
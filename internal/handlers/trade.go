@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"log"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+)
+
+func (h *Handlers) TransferItem(c *gin.Context) {
+	var req struct {
+		ToUser   string `json:"toUser"`
+		Quantity int    `json:"quantity"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Неверный запрос"})
+		return
+	}
+	if req.Quantity == 0 {
+		req.Quantity = 1
+	}
+	itemName := c.Param("item")
+	username := c.MustGet("username").(string)
+	transfer, err := h.tradeService.TransferItem(username, req.ToUser, itemName, req.Quantity)
+	if err != nil {
+		log.Printf("TransferItem failed for user %s to %s, item %s x%d: %v", username, req.ToUser, itemName, req.Quantity, err)
+		respondError(c, err)
+		return
+	}
+	log.Printf("TransferItem succeeded for user %s to %s, item %s x%d", username, req.ToUser, itemName, req.Quantity)
+	c.JSON(200, transfer)
+}
+
+func (h *Handlers) CreateTrade(c *gin.Context) {
+	var req struct {
+		ToUser    string             `json:"toUser"`
+		Give      []models.TradeItem `json:"give"`
+		Want      []models.TradeItem `json:"want"`
+		GiveCoins int                `json:"giveCoins"`
+		WantCoins int                `json:"wantCoins"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Неверный запрос"})
+		return
+	}
+	username := c.MustGet("username").(string)
+	trade, err := h.tradeService.CreateTrade(username, req.ToUser, req.Give, req.Want, req.GiveCoins, req.WantCoins)
+	if err != nil {
+		log.Printf("CreateTrade failed for user %s to %s: %v", username, req.ToUser, err)
+		respondError(c, err)
+		return
+	}
+	log.Printf("CreateTrade succeeded for user %s to %s, trade %d", username, req.ToUser, trade.ID)
+	c.JSON(200, trade)
+}
+
+func (h *Handlers) GetTrades(c *gin.Context) {
+	username := c.MustGet("username").(string)
+	trades, err := h.tradeService.GetTrades(username)
+	if err != nil {
+		log.Printf("GetTrades failed for %s: %v", username, err)
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, trades)
+}
+
+func (h *Handlers) AcceptTrade(c *gin.Context) {
+	h.resolveTrade(c, "AcceptTrade", h.tradeService.AcceptTrade)
+}
+
+func (h *Handlers) DeclineTrade(c *gin.Context) {
+	h.resolveTrade(c, "DeclineTrade", h.tradeService.DeclineTrade)
+}
+
+func (h *Handlers) CancelTrade(c *gin.Context) {
+	h.resolveTrade(c, "CancelTrade", h.tradeService.CancelTrade)
+}
+
+func (h *Handlers) resolveTrade(c *gin.Context, action string, resolve func(string, int) (*models.Trade, error)) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Неверный идентификатор обмена"})
+		return
+	}
+	username := c.MustGet("username").(string)
+	trade, err := resolve(username, id)
+	if err != nil {
+		log.Printf("%s failed for %s, trade %d: %v", action, username, id, err)
+		respondError(c, err)
+		return
+	}
+	log.Printf("%s succeeded for %s, trade %d", action, username, id)
+	c.JSON(200, trade)
+}
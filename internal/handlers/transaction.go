@@ -4,6 +4,8 @@ import (
 	"log"
 
 	"github.com/gin-gonic/gin"
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+	"github.com/itocode21/MerchServiceAvito/internal/services"
 )
 
 func (h *Handlers) SendCoin(c *gin.Context) {
@@ -20,9 +22,65 @@ func (h *Handlers) SendCoin(c *gin.Context) {
 	err := h.transService.SendCoins(fromUser, req.ToUser, req.Amount)
 	if err != nil {
 		log.Printf("SendCoin failed for user %s to %s, amount %d: %v", fromUser, req.ToUser, req.Amount, err)
-		c.JSON(400, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 	log.Printf("SendCoin succeeded for user %s to %s, amount %d", fromUser, req.ToUser, req.Amount)
 	c.JSON(200, gin.H{"message": "Монеты успешно отправлены"})
 }
+
+func (h *Handlers) GrantCoins(c *gin.Context) {
+	var req struct {
+		ToUser string `json:"toUser"`
+		Amount int    `json:"amount"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("GrantCoins failed: invalid request: %v", err)
+		c.JSON(400, gin.H{"error": "Неверный запрос"})
+		return
+	}
+	granter := c.MustGet("username").(string)
+	if err := h.transService.GrantCoins(req.ToUser, req.Amount); err != nil {
+		log.Printf("GrantCoins failed by %s to %s, amount %d: %v", granter, req.ToUser, req.Amount, err)
+		respondError(c, err)
+		return
+	}
+	log.Printf("GrantCoins succeeded by %s to %s, amount %d", granter, req.ToUser, req.Amount)
+	c.JSON(200, gin.H{"message": "Монеты успешно начислены"})
+}
+
+// SendCoinBulk принимает либо список {toUser, amount}, либо toUsers + totalAmount для деления поровну.
+func (h *Handlers) SendCoinBulk(c *gin.Context) {
+	var req struct {
+		Recipients  []models.BulkTransfer `json:"recipients"`
+		ToUsers     []string              `json:"toUsers"`
+		TotalAmount int                   `json:"totalAmount"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("SendCoinBulk failed: invalid request: %v", err)
+		c.JSON(400, gin.H{"error": "Неверный запрос"})
+		return
+	}
+	fromUser := c.MustGet("username").(string)
+
+	transfers := req.Recipients
+	if len(req.ToUsers) > 0 {
+		if len(transfers) > 0 {
+			c.JSON(400, gin.H{"error": "Укажите либо recipients, либо toUsers и totalAmount"})
+			return
+		}
+		var err error
+		if transfers, err = services.SplitEvenly(req.ToUsers, req.TotalAmount); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := h.transService.SendCoinsBulk(fromUser, transfers); err != nil {
+		log.Printf("SendCoinBulk failed for user %s to %d recipients: %v", fromUser, len(transfers), err)
+		respondError(c, err)
+		return
+	}
+	log.Printf("SendCoinBulk succeeded for user %s to %d recipients", fromUser, len(transfers))
+	c.JSON(200, gin.H{"message": "Монеты успешно отправлены", "transfers": transfers})
+}
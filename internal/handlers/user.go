@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -29,15 +30,26 @@ func (h *Handlers) GetInfo(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Пользователь не найден"})
 		return
 	}
+	badges, err := h.achievementService.GetBadges(username)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
 	// Формируем ответ
 	response := gin.H{
 		"coins":     info.Coins,
+		"heldCoins": info.HeldCoins,
 		"inventory": info.InventoryJSON,
 		"coinHistory": gin.H{
 			"received": info.ReceivedJSON,
 			"sent":     info.SentJSON,
 		},
+		"gifts": gin.H{
+			"received": info.GiftsReceivedJSON,
+			"sent":     info.GiftsSentJSON,
+		},
+		"badges": badges,
 	}
 
 	responseJSON, err := json.Marshal(response)
@@ -68,3 +80,47 @@ func filterTransactions(transactions []models.Transaction, userID int, received
 	}
 	return result
 }
+
+func (h *Handlers) GetMyProfile(c *gin.Context) {
+	username := c.MustGet("username").(string)
+	profile, err := h.userService.GetProfile(username)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+func (h *Handlers) UpdateMyProfile(c *gin.Context) {
+	var req models.ProfileUpdate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный запрос"})
+		return
+	}
+	username := c.MustGet("username").(string)
+	profile, err := h.userService.UpdateProfile(username, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+func (h *Handlers) GetUserProfile(c *gin.Context) {
+	profile, err := h.userService.GetProfile(c.Param("username"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+func (h *Handlers) SearchUsers(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	profiles, err := h.userService.SearchUsers(c.Query("q"), limit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, profiles)
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"log"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handlers) CreateWebhook(c *gin.Context) {
+	var req struct {
+		URL    string   `json:"url"`
+		Events []string `json:"events"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Неверный запрос"})
+		return
+	}
+	username := c.MustGet("username").(string)
+	secret, subscription, err := h.webhookService.CreateSubscription(username, req.URL, req.Events)
+	if err != nil {
+		log.Printf("CreateWebhook failed for %s, url %s: %v", username, req.URL, err)
+		respondError(c, err)
+		return
+	}
+	log.Printf("CreateWebhook succeeded for %s, webhook %d", username, subscription.ID)
+	c.JSON(200, gin.H{"secret": secret, "webhook": subscription})
+}
+
+func (h *Handlers) ListWebhooks(c *gin.Context) {
+	subscriptions, err := h.webhookService.GetSubscriptions()
+	if err != nil {
+		log.Printf("ListWebhooks failed: %v", err)
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, subscriptions)
+}
+
+func (h *Handlers) DeactivateWebhook(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Неверный идентификатор подписки"})
+		return
+	}
+	if err := h.webhookService.DeactivateSubscription(id); err != nil {
+		log.Printf("DeactivateWebhook failed for webhook %d: %v", id, err)
+		respondError(c, err)
+		return
+	}
+	log.Printf("DeactivateWebhook succeeded for webhook %d", id)
+	c.JSON(200, gin.H{"message": "Подписка отключена"})
+}
+
+func (h *Handlers) GetWebhookDeliveries(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Неверный идентификатор подписки"})
+		return
+	}
+	limit, offset, ok := pageParams(c)
+	if !ok {
+		return
+	}
+	deliveries, err := h.webhookService.GetDeliveries(id, c.Query("status"), limit, offset)
+	if err != nil {
+		log.Printf("GetWebhookDeliveries failed for webhook %d: %v", id, err)
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, deliveries)
+}
+
+func (h *Handlers) GetWebhookDeadLetters(c *gin.Context) {
+	limit, offset, ok := pageParams(c)
+	if !ok {
+		return
+	}
+	deliveries, err := h.webhookService.GetDeadLetters(limit, offset)
+	if err != nil {
+		log.Printf("GetWebhookDeadLetters failed: %v", err)
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, deliveries)
+}
+
+func (h *Handlers) RetryWebhookDelivery(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Неверный идентификатор доставки"})
+		return
+	}
+	if err := h.webhookService.RetryDelivery(id); err != nil {
+		log.Printf("RetryWebhookDelivery failed for delivery %d: %v", id, err)
+		respondError(c, err)
+		return
+	}
+	log.Printf("RetryWebhookDelivery succeeded for delivery %d", id)
+	c.JSON(200, gin.H{"message": "Доставка поставлена в очередь"})
+}
+
+// pageParams читает limit и offset из запроса; при неверном значении отвечает 400
+func pageParams(c *gin.Context) (limit, offset int, ok bool) {
+	for param, dst := range map[string]*int{"limit": &limit, "offset": &offset} {
+		if raw := c.Query(param); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil {
+				c.JSON(400, gin.H{"error": "Неверное значение параметра " + param})
+				return 0, 0, false
+			}
+			*dst = n
+		}
+	}
+	return limit, offset, true
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"log"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handlers) GetWishlist(c *gin.Context) {
+	username := c.MustGet("username").(string)
+	items, err := h.wishlistService.GetWishlist(username)
+	if err != nil {
+		log.Printf("GetWishlist failed for %s: %v", username, err)
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, items)
+}
+
+func (h *Handlers) AddToWishlist(c *gin.Context) {
+	itemName := c.Param("item")
+	username := c.MustGet("username").(string)
+	if err := h.wishlistService.AddItem(username, itemName); err != nil {
+		log.Printf("AddToWishlist failed for %s, item %s: %v", username, itemName, err)
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, gin.H{"message": "Предмет добавлен в список желаний"})
+}
+
+func (h *Handlers) RemoveFromWishlist(c *gin.Context) {
+	itemName := c.Param("item")
+	username := c.MustGet("username").(string)
+	if err := h.wishlistService.RemoveItem(username, itemName); err != nil {
+		log.Printf("RemoveFromWishlist failed for %s, item %s: %v", username, itemName, err)
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, gin.H{"message": "Предмет удалён из списка желаний"})
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/itocode21/MerchServiceAvito/internal/repositories"
+)
+
+// AdminMiddleware пропускает только администраторов. Должен стоять после JWTAuthMiddleware
+// или APIKeyOrJWTAuthMiddleware: запросы по API-ключу к этому моменту уже прошли проверку права.
+func AdminMiddleware(userRepo *repositories.UserRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get("apiKeyScopes"); ok {
+			c.Next()
+			return
+		}
+
+		username := c.MustGet("username").(string)
+
+		isAdmin, err := userRepo.IsAdmin(username)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "ошибка проверки прав"})
+			c.Abort()
+			return
+		}
+		if !isAdmin {
+			c.JSON(http.StatusForbidden, gin.H{"error": "недостаточно прав"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"net/http"
+	"slices"
 	"strings"
 
 	"github.com/itocode21/MerchServiceAvito/internal/auth"
@@ -9,31 +10,74 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// APIKeyValidator проверяет API-ключ и возвращает имя владельца и права ключа.
+type APIKeyValidator interface {
+	ValidateAPIKey(key string) (string, []string, error)
+}
+
 func JWTAuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "токен не предоставлен"})
-			c.Abort()
+		if !authenticateJWT(c) {
 			return
 		}
+		c.Next()
+	}
+}
 
-		tokenStr := strings.Split(authHeader, " ")
-		if len(tokenStr) != 2 || tokenStr[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "неверный формат токена"})
-			c.Abort()
+// APIKeyOrJWTAuthMiddleware дополнительно к JWT принимает заголовок "Authorization: ApiKey <key>".
+// Ключ пропускается, только если у него есть право scope. В обоих случаях
+// в контекст кладётся username; для ключей также выставляется apiKeyScopes.
+func APIKeyOrJWTAuthMiddleware(keys APIKeyValidator, scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		parts := strings.Split(c.GetHeader("Authorization"), " ")
+		if len(parts) != 2 || parts[0] != "ApiKey" {
+			if !authenticateJWT(c) {
+				return
+			}
+			c.Next()
 			return
 		}
 
-		username, err := auth.ValidateJWT(tokenStr[1])
+		username, scopes, err := keys.ValidateAPIKey(parts[1])
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "недействительный токен"})
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "недействительный API-ключ"})
+			c.Abort()
+			return
+		}
+		if !slices.Contains(scopes, scope) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "у API-ключа нет права " + scope})
 			c.Abort()
 			return
 		}
 
 		c.Set("username", username)
+		c.Set("apiKeyScopes", scopes)
 		c.Next()
 	}
 }
+
+func authenticateJWT(c *gin.Context) bool {
+	authHeader := c.GetHeader("Authorization")
+	if authHeader == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "токен не предоставлен"})
+		c.Abort()
+		return false
+	}
+
+	tokenStr := strings.Split(authHeader, " ")
+	if len(tokenStr) != 2 || tokenStr[0] != "Bearer" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "неверный формат токена"})
+		c.Abort()
+		return false
+	}
+
+	username, err := auth.ValidateJWT(tokenStr[1])
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "недействительный токен"})
+		c.Abort()
+		return false
+	}
+
+	c.Set("username", username)
+	return true
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+)
+
+// RateLimitMiddleware ограничивает число запросов пользователя к эндпоинту за окно window
+// (фиксированное окно, счётчик в Redis). Должен стоять после аутентификации.
+// При недоступности Redis запросы пропускаются.
+func RateLimitMiddleware(client *redis.Client, name string, limit int, window time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		username := c.MustGet("username").(string)
+		windowStart := time.Now().Truncate(window).Unix()
+		key := fmt.Sprintf("rate_limit:%s:%s:%d", name, username, windowStart)
+
+		ctx := context.Background()
+		count, err := client.Incr(ctx, key).Result()
+		if err != nil {
+			log.Printf("RateLimit %s: redis error for user %s: %v", name, username, err)
+			c.Next()
+			return
+		}
+		if count == 1 {
+			client.Expire(ctx, key, window)
+		}
+		if count > int64(limit) {
+			c.Header("Retry-After", fmt.Sprintf("%d", windowStart+int64(window.Seconds())-time.Now().Unix()))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "слишком много запросов, попробуйте позже"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+const (
+	ScopeTransferSendAsBot = "transfer:send-as-bot"
+	ScopeGrantCoins        = "grant:coins"
+	ScopeReadInfo          = "read:info"
+)
+
+var APIKeyScopes = []string{ScopeTransferSendAsBot, ScopeGrantCoins, ScopeReadInfo}
+
+type APIKey struct {
+	ID        int        `json:"id"`
+	UserID    int        `json:"user_id"`
+	Username  string     `json:"username"`
+	Name      string     `json:"name"`
+	Prefix    string     `json:"prefix"`
+	KeyHash   string     `json:"-"`
+	Scopes    []string   `json:"scopes"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+const (
+	AuctionStatusOpen   = "open"
+	AuctionStatusSold   = "sold"
+	AuctionStatusUnsold = "unsold"
+)
+
+// Auction — торги за предмет каталога. Лидер держит в резерве сумму своей ставки,
+// при закрытии он оплачивает лот и получает предмет.
+type Auction struct {
+	ID            int        `json:"id"`
+	ItemID        int        `json:"-"`
+	Item          string     `json:"item"`
+	CreatedByID   int        `json:"-"`
+	StartingPrice int        `json:"starting_price"`
+	MinIncrement  int        `json:"min_increment"`
+	LeaderID      int        `json:"-"`
+	Leader        string     `json:"leader,omitempty"`
+	CurrentBid    int        `json:"current_bid"`
+	Status        string     `json:"status"`
+	CreatedAt     time.Time  `json:"created_at"`
+	EndsAt        time.Time  `json:"ends_at"`
+	ClosedAt      *time.Time `json:"closed_at,omitempty"`
+}
+
+// MinNextBid возвращает минимальную допустимую следующую ставку
+func (a *Auction) MinNextBid() int {
+	if a.LeaderID == 0 {
+		return a.StartingPrice
+	}
+	return a.CurrentBid + a.MinIncrement
+}
+
+type AuctionBid struct {
+	ID        int       `json:"id"`
+	AuctionID int       `json:"auction_id"`
+	UserID    int       `json:"-"`
+	Username  string    `json:"username"`
+	Amount    int       `json:"amount"`
+	CreatedAt time.Time `json:"created_at"`
+}
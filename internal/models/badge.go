@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+const (
+	BadgeFirstPurchase = "first_purchase"
+	BadgeGenerous      = "generous"
+	BadgeCupCollector  = "cup_collector"
+)
+
+// Badge — значок за достижение. Reward — сколько монет начисляется при получении.
+type Badge struct {
+	Code        string     `json:"code"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Reward      int        `json:"reward,omitempty"`
+	AwardedAt   *time.Time `json:"awarded_at,omitempty"`
+}
@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+const (
+	BetStatusOpen     = "open"
+	BetStatusActive   = "active"
+	BetStatusSettled  = "settled"
+	BetStatusDeclined = "declined"
+	BetStatusRefunded = "refunded"
+
+	HoldStatusHeld     = "held"
+	HoldStatusReleased = "released"
+)
+
+// Bet — пари между двумя сотрудниками: оба ставят stake монет, арбитр отдаёт банк победителю
+type Bet struct {
+	ID          int        `json:"id"`
+	CreatorID   int        `json:"-"`
+	OpponentID  int        `json:"-"`
+	ArbiterID   int        `json:"-"`
+	WinnerID    int        `json:"-"`
+	Creator     string     `json:"creator"`
+	Opponent    string     `json:"opponent"`
+	Arbiter     string     `json:"arbiter"`
+	Winner      string     `json:"winner,omitempty"`
+	Stake       int        `json:"stake"`
+	Description string     `json:"description"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	ResolvedAt  *time.Time `json:"resolved_at,omitempty"`
+}
+
+// CoinHold — монеты пользователя, зарезервированные в эскроу
+type CoinHold struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"user_id"`
+	BetID      int        `json:"bet_id,omitempty"`
+	AuctionID  int        `json:"auction_id,omitempty"`
+	Amount     int        `json:"amount"`
+	Status     string     `json:"status"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ReleasedAt *time.Time `json:"released_at,omitempty"`
+}
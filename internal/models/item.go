@@ -1,7 +1,67 @@
 package models
 
+import "time"
+
 type Item struct {
-	ID    int    `json:"id"`
-	Name  string `json:"name"`
-	Price int    `json:"price"`
+	ID          int      `json:"id"`
+	Name        string   `json:"name"`
+	Price       int      `json:"price"`
+	Stock       *int     `json:"stock"`
+	Category    string   `json:"category,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	ImageURLs   []string `json:"image_urls,omitempty"`
+	SortOrder   int      `json:"sort_order"`
+}
+
+// ItemUpdate — изменения предмета каталога; нулевые поля оставляют значения прежними.
+// Пустой, но не nil срез Tags или ImageURLs очищает список.
+type ItemUpdate struct {
+	Price          int      `json:"price"`
+	Stock          *int     `json:"stock"`
+	UnlimitedStock bool     `json:"unlimitedStock"`
+	Category       *string  `json:"category"`
+	Description    *string  `json:"description"`
+	Tags           []string `json:"tags"`
+	ImageURLs      []string `json:"imageUrls"`
+	SortOrder      *int     `json:"sortOrder"`
+}
+
+// Category — раздел витрины мерча
+type Category struct {
+	Slug      string `json:"slug"`
+	Name      string `json:"name"`
+	SortOrder int    `json:"sort_order"`
+}
+
+// CatalogFilter — условия выборки каталога; нулевые поля не ограничивают выборку
+type CatalogFilter struct {
+	Query    string
+	Category string
+	Tags     []string
+	MinPrice int
+	MaxPrice int
+	InStock  bool
+	Limit    int
+	Offset   int
+}
+
+// ItemPrice — запись истории цен предмета. Цена действует с EffectiveFrom до начала
+// следующей записи; записи с EffectiveFrom в будущем — запланированные изменения.
+type ItemPrice struct {
+	ID            int        `json:"id"`
+	ItemID        int        `json:"-"`
+	Price         int        `json:"price"`
+	EffectiveFrom time.Time  `json:"effective_from"`
+	EffectiveTo   *time.Time `json:"effective_to,omitempty"`
+	Scheduled     bool       `json:"scheduled"`
+	CreatedBy     string     `json:"created_by,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// PriceTimeline — действующая цена предмета и вся история её изменений
+type PriceTimeline struct {
+	Item   string      `json:"item"`
+	Price  int         `json:"price"`
+	Prices []ItemPrice `json:"prices"`
 }
@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+const (
+	LeaderboardReceived   = "received"
+	LeaderboardSent       = "sent"
+	LeaderboardThanked    = "thanked"
+	LeaderboardCollectors = "collectors"
+)
+
+const (
+	PeriodWeek  = "week"
+	PeriodMonth = "month"
+	PeriodYear  = "year"
+	PeriodAll   = "all"
+)
+
+type LeaderboardEntry struct {
+	Rank     int    `json:"rank"`
+	Username string `json:"username"`
+	Score    int    `json:"score"`
+}
+
+// Leaderboard — верхушка рейтинга за период Bucket (например, 2026-10 для месяца)
+// и место запросившего пользователя, если он есть в рейтинге
+type Leaderboard struct {
+	Board   string             `json:"board"`
+	Period  string             `json:"period"`
+	Bucket  string             `json:"bucket"`
+	Entries []LeaderboardEntry `json:"entries"`
+	Me      *LeaderboardEntry  `json:"me,omitempty"`
+}
+
+// TransferDay — сумма переводов от одного сотрудника другому за день
+type TransferDay struct {
+	Day    time.Time
+	From   string
+	To     string
+	Amount int
+}
+
+// PeriodRange — границы периода рейтинга [From, To); нулевые границы означают всё время
+type PeriodRange struct {
+	From time.Time
+	To   time.Time
+}
+
+// TransferScores — счета сотрудника в рейтингах переводов за один период
+type TransferScores struct {
+	Received int
+	Sent     int
+	Thanked  int
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+const (
+	NotificationScheduledTransferFailed = "scheduled_transfer_failed"
+	NotificationGiftReceived            = "gift_received"
+	NotificationItemReceived            = "item_received"
+	NotificationTradeOffered            = "trade_offered"
+	NotificationTradeAccepted           = "trade_accepted"
+	NotificationAuctionOutbid           = "auction_outbid"
+	NotificationAuctionWon              = "auction_won"
+	NotificationWishlistAffordable      = "wishlist_affordable"
+	NotificationWishlistRestocked       = "wishlist_restocked"
+	NotificationWishlistPriceDrop       = "wishlist_price_drop"
+	NotificationBadgeAwarded            = "badge_awarded"
+	NotificationCoinsReceived           = "coins_received"
+	NotificationCoinsGranted            = "coins_granted"
+	NotificationNewLogin                = "new_login"
+	NotificationPasswordChanged         = "password_changed"
+)
+
+var NotificationKinds = []string{
+	NotificationCoinsReceived, NotificationCoinsGranted, NotificationGiftReceived, NotificationItemReceived,
+	NotificationTradeOffered, NotificationTradeAccepted, NotificationAuctionOutbid, NotificationAuctionWon,
+	NotificationWishlistAffordable, NotificationWishlistRestocked, NotificationWishlistPriceDrop,
+	NotificationBadgeAwarded, NotificationScheduledTransferFailed, NotificationNewLogin, NotificationPasswordChanged,
+}
+
+type Notification struct {
+	ID        int        `json:"id"`
+	UserID    int        `json:"-"`
+	Kind      string     `json:"kind"`
+	Message   string     `json:"message"`
+	CreatedAt time.Time  `json:"created_at"`
+	ReadAt    *time.Time `json:"read_at,omitempty"`
+}
+
+type NotificationPreference struct {
+	Kind    string `json:"kind"`
+	Enabled bool   `json:"enabled"`
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	EventCoinsTransferred = "CoinsTransferred"
+	EventItemPurchased    = "ItemPurchased"
+	EventUserRegistered   = "UserRegistered"
+)
+
+var OutboxEventTypes = []string{EventCoinsTransferred, EventItemPurchased, EventUserRegistered}
+
+// OutboxPayload — событие для внешних систем (HR, Slack-бот, аналитика)
+type OutboxPayload interface {
+	EventType() string
+}
+
+// OutboxEvent — запись outbox. UserID — пользователь, в порядке событий которого
+// они доставляются: отправитель перевода, покупатель, новый сотрудник.
+type OutboxEvent struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	UserID    int             `json:"user_id"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type CoinsTransferred struct {
+	TransactionID int       `json:"transaction_id"`
+	From          string    `json:"from"`
+	To            string    `json:"to"`
+	Amount        int       `json:"amount"`
+	Kind          string    `json:"kind"`
+	At            time.Time `json:"at"`
+}
+
+func (CoinsTransferred) EventType() string { return EventCoinsTransferred }
+
+type ItemPurchased struct {
+	PurchaseID int       `json:"purchase_id"`
+	Buyer      string    `json:"buyer"`
+	Recipient  string    `json:"recipient"`
+	Item       string    `json:"item"`
+	Price      int       `json:"price"`
+	ListPrice  int       `json:"list_price"`
+	PromoCode  string    `json:"promo_code,omitempty"`
+	At         time.Time `json:"at"`
+}
+
+func (ItemPurchased) EventType() string { return EventItemPurchased }
+
+type UserRegistered struct {
+	UserID   int       `json:"user_id"`
+	Username string    `json:"username"`
+	At       time.Time `json:"at"`
+}
+
+func (UserRegistered) EventType() string { return EventUserRegistered }
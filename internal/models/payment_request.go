@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+const (
+	PaymentRequestPending  = "pending"
+	PaymentRequestApproved = "approved"
+	PaymentRequestDeclined = "declined"
+	PaymentRequestExpired  = "expired"
+)
+
+// PaymentRequest — просьба перевести монеты: requester просит у payer сумму amount
+type PaymentRequest struct {
+	ID            int        `json:"id"`
+	RequesterID   int        `json:"-"`
+	PayerID       int        `json:"-"`
+	Requester     string     `json:"requester"`
+	Payer         string     `json:"payer"`
+	Amount        int        `json:"amount"`
+	Memo          string     `json:"memo"`
+	Status        string     `json:"status"`
+	TransactionID int        `json:"transaction_id,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	ExpiresAt     time.Time  `json:"expires_at"`
+	ResolvedAt    *time.Time `json:"resolved_at,omitempty"`
+}
@@ -0,0 +1,19 @@
+package models
+
+type Profile struct {
+	Username    string `json:"username"`
+	DisplayName string `json:"displayName"`
+	Department  string `json:"department"`
+	Office      string `json:"office"`
+	Title       string `json:"title"`
+	AvatarURL   string `json:"avatarUrl"`
+}
+
+// ProfileUpdate содержит только те поля, которые нужно изменить.
+type ProfileUpdate struct {
+	DisplayName *string `json:"displayName"`
+	Department  *string `json:"department"`
+	Office      *string `json:"office"`
+	Title       *string `json:"title"`
+	AvatarURL   *string `json:"avatarUrl"`
+}
@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+const (
+	PromotionKindPercent = "percent"
+	PromotionKindFixed   = "fixed"
+)
+
+// Promotion — скидка на каталог, категорию или отдельный предмет. Без кода
+// применяется автоматически, с кодом — только если покупатель его ввёл.
+type Promotion struct {
+	ID           int        `json:"id"`
+	Code         string     `json:"code,omitempty"`
+	Kind         string     `json:"kind"`
+	Value        int        `json:"value"`
+	ItemID       int        `json:"-"`
+	Item         string     `json:"item,omitempty"`
+	Category     string     `json:"category,omitempty"`
+	StartsAt     time.Time  `json:"starts_at"`
+	EndsAt       *time.Time `json:"ends_at,omitempty"`
+	MaxUses      int        `json:"max_uses,omitempty"`
+	PerUserLimit int        `json:"per_user_limit,omitempty"`
+	Uses         int        `json:"uses"`
+	Stackable    bool       `json:"stackable"`
+	Active       bool       `json:"active"`
+	CreatedAt    time.Time  `json:"created_at"`
+
+	// Заполняются при расчёте цены для конкретного покупателя и предмета
+	UserUses int  `json:"-"`
+	InScope  bool `json:"-"`
+}
+
+// Apply возвращает цену после скидки; цена не опускается ниже нуля
+func (p *Promotion) Apply(price int) int {
+	discount := p.Value
+	if p.Kind == PromotionKindPercent {
+		discount = price * p.Value / 100
+	}
+	if discount > price {
+		return 0
+	}
+	return price - discount
+}
+
+// PriceQuote — цена предмета для покупателя с учётом применённых скидок
+type PriceQuote struct {
+	ListPrice  int                `json:"list_price"`
+	Price      int                `json:"price"`
+	Discount   int                `json:"discount"`
+	Code       string             `json:"promo_code,omitempty"`
+	Promotions []AppliedPromotion `json:"promotions"`
+}
+
+type AppliedPromotion struct {
+	PromotionID int    `json:"promotion_id"`
+	Code        string `json:"code,omitempty"`
+	Discount    int    `json:"discount"`
+}
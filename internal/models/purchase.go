@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// Purchase — покупка мерча; если RecipientID отличается от BuyerID, это подарок
+type Purchase struct {
+	ID          int       `json:"id"`
+	BuyerID     int       `json:"buyer_id"`
+	RecipientID int       `json:"recipient_id"`
+	ItemID      int       `json:"item_id"`
+	Price       int       `json:"price"`
+	ListPrice   int       `json:"list_price"`
+	Discount    int       `json:"discount"`
+	PromoCode   string    `json:"promo_code,omitempty"`
+	Message     string    `json:"message,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+const (
+	RecurrenceOnce    = "once"
+	RecurrenceWeekly  = "weekly"
+	RecurrenceMonthly = "monthly"
+
+	ScheduleStatusActive    = "active"
+	ScheduleStatusPaused    = "paused"
+	ScheduleStatusCancelled = "cancelled"
+	ScheduleStatusCompleted = "completed"
+	ScheduleStatusFailed    = "failed"
+
+	ScheduleRunSucceeded = "succeeded"
+	ScheduleRunFailed    = "failed"
+)
+
+// ScheduledTransfer — разовый или регулярный перевод, выполняемый фоновой задачей
+type ScheduledTransfer struct {
+	ID         int       `json:"id"`
+	OwnerID    int       `json:"-"`
+	ToUserID   int       `json:"-"`
+	Owner      string    `json:"owner"`
+	ToUser     string    `json:"toUser"`
+	Amount     int       `json:"amount"`
+	Recurrence string    `json:"recurrence"`
+	Status     string    `json:"status"`
+	StartsAt   time.Time `json:"starts_at"`
+	NextRunAt  time.Time `json:"next_run_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type ScheduledTransferRun struct {
+	ID            int       `json:"id"`
+	ScheduleID    int       `json:"schedule_id"`
+	PlannedAt     time.Time `json:"planned_at"`
+	Status        string    `json:"status"`
+	Error         string    `json:"error,omitempty"`
+	TransactionID int       `json:"transaction_id,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
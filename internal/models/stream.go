@@ -0,0 +1,31 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Виды событий потока /api/stream
+const (
+	StreamBalance          = "balance"
+	StreamTransferReceived = "transfer_received"
+)
+
+// StreamEvent — событие для потока пользователя; Data — JSON события вида Kind
+type StreamEvent struct {
+	Kind string          `json:"kind"`
+	Data json.RawMessage `json:"data"`
+}
+
+type Balance struct {
+	Coins     int `json:"coins"`
+	HeldCoins int `json:"heldCoins"`
+	Available int `json:"available"`
+}
+
+type TransferReceived struct {
+	From   string    `json:"fromUser"`
+	Amount int       `json:"amount"`
+	Kind   string    `json:"kind"`
+	At     time.Time `json:"createdAt"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+const (
+	TeamRoleMember  = "member"
+	TeamRoleManager = "manager"
+)
+
+type Team struct {
+	ID        int          `json:"id"`
+	Name      string       `json:"name"`
+	Coins     int          `json:"coins"`
+	CreatedAt time.Time    `json:"created_at"`
+	Role      string       `json:"role,omitempty"`
+	Members   []TeamMember `json:"members,omitempty"`
+}
+
+type TeamMember struct {
+	UserID   int    `json:"user_id"`
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+type TeamSpendingLine struct {
+	Username string `json:"username"`
+	Count    int    `json:"count"`
+	Amount   int    `json:"amount"`
+}
+
+type TeamSpendingReport struct {
+	TeamID      int                `json:"team_id"`
+	From        time.Time          `json:"from"`
+	To          time.Time          `json:"to"`
+	Funded      int                `json:"funded"`
+	Spent       int                `json:"spent"`
+	ByRecipient []TeamSpendingLine `json:"by_recipient"`
+	ByManager   []TeamSpendingLine `json:"by_manager"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+const (
+	TradeStatusPending   = "pending"
+	TradeStatusAccepted  = "accepted"
+	TradeStatusDeclined  = "declined"
+	TradeStatusCancelled = "cancelled"
+
+	TradeSideProposer     = "proposer"
+	TradeSideCounterparty = "counterparty"
+)
+
+// Trade — предложение обмена: каждая сторона отдаёт предметы и, возможно, монеты.
+// Автор соглашается на обмен, создавая предложение; обмен выполняется, когда его принимает вторая сторона.
+type Trade struct {
+	ID                int         `json:"id"`
+	ProposerID        int         `json:"-"`
+	CounterpartyID    int         `json:"-"`
+	Proposer          string      `json:"proposer"`
+	Counterparty      string      `json:"counterparty"`
+	ProposerCoins     int         `json:"proposerCoins"`
+	CounterpartyCoins int         `json:"counterpartyCoins"`
+	ProposerItems     []TradeItem `json:"proposerItems"`
+	CounterpartyItems []TradeItem `json:"counterpartyItems"`
+	Status            string      `json:"status"`
+	CreatedAt         time.Time   `json:"created_at"`
+	ResolvedAt        *time.Time  `json:"resolved_at,omitempty"`
+}
+
+type TradeItem struct {
+	ItemID   int    `json:"-"`
+	Type     string `json:"type"`
+	Quantity int    `json:"quantity"`
+}
+
+// ItemTransfer — перемещение предметов от одного сотрудника другому
+type ItemTransfer struct {
+	ID         int       `json:"id"`
+	FromUserID int       `json:"from_user_id"`
+	ToUserID   int       `json:"to_user_id"`
+	ItemID     int       `json:"item_id"`
+	Quantity   int       `json:"quantity"`
+	TradeID    int       `json:"trade_id,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -2,10 +2,31 @@ package models
 
 import "time"
 
+const (
+	TransactionKindTransfer    = "transfer"
+	TransactionKindGrant       = "grant"
+	TransactionKindOffboarding = "offboarding"
+	TransactionKindTeamFunding = "team_funding"
+	TransactionKindTeamReward  = "team_reward"
+	TransactionKindHold        = "hold"
+	TransactionKindHoldRelease = "hold_release"
+	TransactionKindBetPayout   = "bet_payout"
+	TransactionKindTrade       = "trade"
+	TransactionKindAchievement = "achievement"
+)
+
 type Transaction struct {
 	ID         int       `json:"id"`
 	FromUserID int       `json:"from_user_id"`
 	ToUserID   int       `json:"to_user_id"`
 	Amount     int       `json:"amount"`
+	Kind       string    `json:"kind"`
+	TeamID     int       `json:"team_id,omitempty"`
 	CreatedAt  time.Time `json:"created_at"`
 }
+
+// BulkTransfer — одна строка массового перевода
+type BulkTransfer struct {
+	ToUser string `json:"toUser"`
+	Amount int    `json:"amount"`
+}
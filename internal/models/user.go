@@ -7,5 +7,13 @@ type User struct {
 	Username     string    `json:"username"`
 	PasswordHash string    `json:"-"`
 	Coins        int       `json:"coins"`
+	HeldCoins    int       `json:"held_coins"`
+	IsActive     bool      `json:"is_active"`
 	CreatedAt    time.Time `json:"created_at"`
 }
+
+// SpendableCoins — монеты, которые можно потратить: зарезервированные в эскроу
+// остаются в балансе, но недоступны для переводов и покупок
+func (u *User) SpendableCoins() int {
+	return u.Coins - u.HeldCoins
+}
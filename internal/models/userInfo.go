@@ -3,8 +3,11 @@ package models
 import "encoding/json"
 
 type UserInfo struct {
-	Coins         int             `json:"coins"`
-	InventoryJSON json.RawMessage `json:"inventory"`
-	ReceivedJSON  json.RawMessage `json:"received"`
-	SentJSON      json.RawMessage `json:"sent"`
+	Coins             int             `json:"coins"`
+	HeldCoins         int             `json:"heldCoins"`
+	InventoryJSON     json.RawMessage `json:"inventory"`
+	ReceivedJSON      json.RawMessage `json:"received"`
+	SentJSON          json.RawMessage `json:"sent"`
+	GiftsReceivedJSON json.RawMessage `json:"giftsReceived"`
+	GiftsSentJSON     json.RawMessage `json:"giftsSent"`
 }
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// WishlistItem — предмет из списка желаний вместе с прогрессом накопления на него
+type WishlistItem struct {
+	UserID        int       `json:"-"`
+	ItemID        int       `json:"-"`
+	Item          string    `json:"item"`
+	Price         int       `json:"price"`
+	Stock         *int      `json:"stock"`
+	InStock       bool      `json:"in_stock"`
+	Balance       int       `json:"balance"`
+	Missing       int       `json:"missing"`
+	Progress      int       `json:"progress"`
+	CreatedAt     time.Time `json:"created_at"`
+	NotifiedPrice int       `json:"-"`
+	WasInStock    bool      `json:"-"`
+	WasAffordable bool      `json:"-"`
+}
+
+// Fill рассчитывает наличие, недостающую сумму и прогресс в процентах по Stock, Price и Balance
+func (w *WishlistItem) Fill() {
+	w.InStock = w.Stock == nil || *w.Stock > 0
+	w.Missing = w.Price - w.Balance
+	if w.Missing < 0 {
+		w.Missing = 0
+	}
+	w.Progress = 100
+	if w.Price > 0 && w.Balance < w.Price {
+		w.Progress = w.Balance * 100 / w.Price
+	}
+}
+
+// Affordable сообщает, хватает ли доступного баланса на предмет
+func (w *WishlistItem) Affordable() bool {
+	return w.Balance >= w.Price
+}
@@ -0,0 +1,127 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+)
+
+type AchievementRepository struct {
+	db *sql.DB
+}
+
+func NewAchievementRepository(db *sql.DB) *AchievementRepository {
+	return &AchievementRepository{db: db}
+}
+
+// GetBadges возвращает полученные пользователем значки: код, награду и время получения
+func (r *AchievementRepository) GetBadges(userID int) ([]models.Badge, error) {
+	rows, err := r.db.Query("SELECT badge, reward, awarded_at FROM user_badges WHERE user_id = $1 ORDER BY awarded_at, badge", userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения значков: %w", err)
+	}
+	defer rows.Close()
+
+	badges := []models.Badge{}
+	for rows.Next() {
+		var b models.Badge
+		b.AwardedAt = new(time.Time)
+		if err := rows.Scan(&b.Code, &b.Reward, b.AwardedAt); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования значка: %w", err)
+		}
+		badges = append(badges, b)
+	}
+	return badges, rows.Err()
+}
+
+// AwardTx выдаёт значок; false — у пользователя он уже есть
+func (r *AchievementRepository) AwardTx(tx *sql.Tx, userID int, b *models.Badge) (bool, error) {
+	query := `
+        INSERT INTO user_badges (user_id, badge, reward)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (user_id, badge) DO NOTHING
+        RETURNING awarded_at
+    `
+	b.AwardedAt = new(time.Time)
+	err := tx.QueryRow(query, userID, b.Code, b.Reward).Scan(b.AwardedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("ошибка выдачи значка: %w", err)
+	}
+	return true, nil
+}
+
+// GetBuyersWithoutBadge возвращает активных пользователей, которые что-нибудь купили,
+// но ещё не получили значок badge
+func (r *AchievementRepository) GetBuyersWithoutBadge(badge string, limit int) ([]models.User, error) {
+	query := `
+        SELECT u.id, u.username
+        FROM users u
+        WHERE u.is_active
+            AND EXISTS (SELECT 1 FROM purchases p WHERE p.buyer_id = u.id)
+            AND NOT EXISTS (SELECT 1 FROM user_badges b WHERE b.user_id = u.id AND b.badge = $1)
+        ORDER BY u.id
+        LIMIT $2
+    `
+	return r.queryUsers(query, badge, limit)
+}
+
+// GetSendersWithoutBadge возвращает активных пользователей, которые переводили монеты
+// не менее чем recipients разным коллегам, но ещё не получили значок badge
+func (r *AchievementRepository) GetSendersWithoutBadge(badge string, recipients, limit int) ([]models.User, error) {
+	query := `
+        SELECT u.id, u.username
+        FROM transactions t
+        JOIN users u ON u.id = t.from_user_id
+        WHERE t.kind = $1 AND u.is_active
+            AND NOT EXISTS (SELECT 1 FROM user_badges b WHERE b.user_id = u.id AND b.badge = $2)
+        GROUP BY u.id, u.username
+        HAVING COUNT(DISTINCT t.to_user_id) >= $3
+        ORDER BY u.id
+        LIMIT $4
+    `
+	return r.queryUsers(query, models.TransactionKindTransfer, badge, recipients, limit)
+}
+
+// GetCollectorsWithoutBadge возвращает активных пользователей, у которых в инвентаре
+// есть все предметы с тегом tag, но ещё нет значка badge. Если таких предметов в
+// каталоге нет, коллекцию собрать нельзя.
+func (r *AchievementRepository) GetCollectorsWithoutBadge(badge, tag string, limit int) ([]models.User, error) {
+	query := `
+        SELECT u.id, u.username
+        FROM users u
+        WHERE u.is_active
+            AND EXISTS (SELECT 1 FROM items i WHERE $1 = ANY(i.tags))
+            AND NOT EXISTS (
+                SELECT 1 FROM items i
+                WHERE $1 = ANY(i.tags)
+                    AND NOT EXISTS (SELECT 1 FROM inventory inv WHERE inv.item_id = i.id AND inv.user_id = u.id)
+            )
+            AND NOT EXISTS (SELECT 1 FROM user_badges b WHERE b.user_id = u.id AND b.badge = $2)
+        ORDER BY u.id
+        LIMIT $3
+    `
+	return r.queryUsers(query, tag, badge, limit)
+}
+
+func (r *AchievementRepository) queryUsers(query string, args ...interface{}) ([]models.User, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка поиска кандидатов на значок: %w", err)
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		var u models.User
+		if err := rows.Scan(&u.ID, &u.Username); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования пользователя: %w", err)
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
@@ -0,0 +1,86 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+	"github.com/lib/pq"
+)
+
+type APIKeyRepository struct {
+	db *sql.DB
+}
+
+func NewAPIKeyRepository(db *sql.DB) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+func (r *APIKeyRepository) CreateAPIKey(k *models.APIKey) error {
+	query := `
+        INSERT INTO api_keys (user_id, name, key_prefix, key_hash, scopes)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING id, created_at
+    `
+	err := r.db.QueryRow(query, k.UserID, k.Name, k.Prefix, k.KeyHash, pq.Array(k.Scopes)).
+		Scan(&k.ID, &k.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("ошибка создания API-ключа: %v", err)
+	}
+	return nil
+}
+
+func (r *APIKeyRepository) ListAPIKeys() ([]models.APIKey, error) {
+	query := `
+        SELECT k.id, k.user_id, u.username, k.name, k.key_prefix, k.scopes, k.created_at, k.revoked_at
+        FROM api_keys k
+        JOIN users u ON u.id = k.user_id
+        ORDER BY k.id
+    `
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения API-ключей: %v", err)
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		var k models.APIKey
+		if err := rows.Scan(&k.ID, &k.UserID, &k.Username, &k.Name, &k.Prefix, pq.Array(&k.Scopes), &k.CreatedAt, &k.RevokedAt); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования API-ключа: %v", err)
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+func (r *APIKeyRepository) RevokeAPIKey(id int) (bool, error) {
+	res, err := r.db.Exec("UPDATE api_keys SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL", id)
+	if err != nil {
+		return false, fmt.Errorf("ошибка отзыва API-ключа: %v", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("ошибка отзыва API-ключа: %v", err)
+	}
+	return n > 0, nil
+}
+
+func (r *APIKeyRepository) GetActiveAPIKeyByHash(keyHash string) (*models.APIKey, error) {
+	query := `
+        SELECT k.id, k.user_id, u.username, k.name, k.key_prefix, k.scopes, k.created_at
+        FROM api_keys k
+        JOIN users u ON u.id = k.user_id
+        WHERE k.key_hash = $1 AND k.revoked_at IS NULL AND u.is_active
+    `
+	var k models.APIKey
+	err := r.db.QueryRow(query, keyHash).
+		Scan(&k.ID, &k.UserID, &k.Username, &k.Name, &k.Prefix, pq.Array(&k.Scopes), &k.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения API-ключа: %v", err)
+	}
+	return &k, nil
+}
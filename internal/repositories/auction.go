@@ -0,0 +1,188 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+	"github.com/lib/pq"
+)
+
+type AuctionRepository struct {
+	db *sql.DB
+}
+
+func NewAuctionRepository(db *sql.DB) *AuctionRepository {
+	return &AuctionRepository{db: db}
+}
+
+const auctionColumns = `
+        a.id, a.item_id, i.name, a.created_by, a.starting_price, a.min_increment,
+        COALESCE(a.leader_id, 0), COALESCE(lu.username, ''), a.current_bid, a.status,
+        a.created_at, a.ends_at, a.closed_at
+`
+
+const auctionJoins = `
+        FROM auctions a
+        JOIN items i ON i.id = a.item_id
+        LEFT JOIN users lu ON lu.id = a.leader_id
+`
+
+func scanAuction(row interface{ Scan(...interface{}) error }) (*models.Auction, error) {
+	var auction models.Auction
+	var closedAt sql.NullTime
+	err := row.Scan(&auction.ID, &auction.ItemID, &auction.Item, &auction.CreatedByID, &auction.StartingPrice, &auction.MinIncrement,
+		&auction.LeaderID, &auction.Leader, &auction.CurrentBid, &auction.Status,
+		&auction.CreatedAt, &auction.EndsAt, &closedAt)
+	if err != nil {
+		return nil, err
+	}
+	if closedAt.Valid {
+		auction.ClosedAt = &closedAt.Time
+	}
+	return &auction, nil
+}
+
+func (r *AuctionRepository) CreateAuctionTx(tx *sql.Tx, auction *models.Auction) error {
+	query := `
+        INSERT INTO auctions (item_id, created_by, starting_price, min_increment, ends_at)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING id, status, created_at
+    `
+	err := tx.QueryRow(query, auction.ItemID, auction.CreatedByID, auction.StartingPrice, auction.MinIncrement, auction.EndsAt).
+		Scan(&auction.ID, &auction.Status, &auction.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("ошибка создания аукциона: %w", err)
+	}
+	return nil
+}
+
+func (r *AuctionRepository) GetAuction(id int) (*models.Auction, error) {
+	query := "SELECT" + auctionColumns + auctionJoins + "WHERE a.id = $1"
+	auction, err := scanAuction(r.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения аукциона: %w", err)
+	}
+	return auction, nil
+}
+
+func (r *AuctionRepository) LockAuctionTx(tx *sql.Tx, id int) (*models.Auction, error) {
+	query := "SELECT" + auctionColumns + auctionJoins + "WHERE a.id = $1 FOR UPDATE OF a"
+	auction, err := scanAuction(tx.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка блокировки аукциона: %w", err)
+	}
+	return auction, nil
+}
+
+// LockEndedAuctionTx блокирует открытый аукцион с наименьшим id, время которого вышло,
+// кроме skipIDs. Аукционы, которые уже закрывает другой экземпляр сервиса, пропускаются.
+// nil — закрывать нечего.
+func (r *AuctionRepository) LockEndedAuctionTx(tx *sql.Tx, skipIDs []int) (*models.Auction, error) {
+	query := "SELECT" + auctionColumns + auctionJoins + `
+        WHERE a.status = $1 AND a.ends_at <= NOW() AND NOT (a.id = ANY($2))
+        ORDER BY a.id
+        LIMIT 1
+        FOR UPDATE OF a SKIP LOCKED
+    `
+	if skipIDs == nil {
+		skipIDs = []int{}
+	}
+	auction, err := scanAuction(tx.QueryRow(query, models.AuctionStatusOpen, pq.Array(skipIDs)))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка выборки аукционов: %w", err)
+	}
+	return auction, nil
+}
+
+// GetOpenAuctions возвращает открытые аукционы; ближайшие к закрытию — первыми
+func (r *AuctionRepository) GetOpenAuctions() ([]*models.Auction, error) {
+	query := "SELECT" + auctionColumns + auctionJoins + `
+        WHERE a.status = $1
+        ORDER BY a.ends_at, a.id
+    `
+	return r.queryAuctions(r.db.Query(query, models.AuctionStatusOpen))
+}
+
+func (r *AuctionRepository) queryAuctions(rows *sql.Rows, err error) ([]*models.Auction, error) {
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения аукционов: %w", err)
+	}
+	defer rows.Close()
+
+	auctions := []*models.Auction{}
+	for rows.Next() {
+		auction, err := scanAuction(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка сканирования аукциона: %w", err)
+		}
+		auctions = append(auctions, auction)
+	}
+	return auctions, rows.Err()
+}
+
+func (r *AuctionRepository) CreateBidTx(tx *sql.Tx, bid *models.AuctionBid) error {
+	query := `
+        INSERT INTO auction_bids (auction_id, user_id, amount)
+        VALUES ($1, $2, $3)
+        RETURNING id, created_at
+    `
+	if err := tx.QueryRow(query, bid.AuctionID, bid.UserID, bid.Amount).Scan(&bid.ID, &bid.CreatedAt); err != nil {
+		return fmt.Errorf("ошибка записи ставки: %w", err)
+	}
+	return nil
+}
+
+func (r *AuctionRepository) UpdateAuctionTx(tx *sql.Tx, auction *models.Auction) error {
+	query := `
+        UPDATE auctions SET leader_id = $1, current_bid = $2, status = $3,
+            closed_at = CASE WHEN $4 THEN NOW() END
+        WHERE id = $5
+        RETURNING closed_at
+    `
+	closed := auction.Status != models.AuctionStatusOpen
+	var closedAt sql.NullTime
+	err := tx.QueryRow(query, nullableID(auction.LeaderID), auction.CurrentBid, auction.Status, closed, auction.ID).Scan(&closedAt)
+	if err != nil {
+		return fmt.Errorf("ошибка обновления аукциона: %w", err)
+	}
+	if closedAt.Valid {
+		auction.ClosedAt = &closedAt.Time
+	}
+	return nil
+}
+
+// GetBids возвращает историю ставок по аукциону; новые — первыми
+func (r *AuctionRepository) GetBids(auctionID int) ([]models.AuctionBid, error) {
+	query := `
+        SELECT b.id, b.auction_id, b.user_id, u.username, b.amount, b.created_at
+        FROM auction_bids b
+        JOIN users u ON u.id = b.user_id
+        WHERE b.auction_id = $1
+        ORDER BY b.id DESC
+    `
+	rows, err := r.db.Query(query, auctionID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения ставок: %w", err)
+	}
+	defer rows.Close()
+
+	bids := []models.AuctionBid{}
+	for rows.Next() {
+		var bid models.AuctionBid
+		if err := rows.Scan(&bid.ID, &bid.AuctionID, &bid.UserID, &bid.Username, &bid.Amount, &bid.CreatedAt); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования ставки: %w", err)
+		}
+		bids = append(bids, bid)
+	}
+	return bids, rows.Err()
+}
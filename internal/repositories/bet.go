@@ -0,0 +1,137 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+)
+
+type BetRepository struct {
+	db *sql.DB
+}
+
+func NewBetRepository(db *sql.DB) *BetRepository {
+	return &BetRepository{db: db}
+}
+
+const betColumns = `
+        b.id, b.creator_id, b.opponent_id, b.arbiter_id, COALESCE(b.winner_id, 0),
+        cu.username, ou.username, au.username, COALESCE(wu.username, ''),
+        b.stake, b.description, b.status, b.created_at, b.expires_at, b.resolved_at
+`
+
+const betJoins = `
+        FROM bets b
+        JOIN users cu ON cu.id = b.creator_id
+        JOIN users ou ON ou.id = b.opponent_id
+        JOIN users au ON au.id = b.arbiter_id
+        LEFT JOIN users wu ON wu.id = b.winner_id
+`
+
+func scanBet(row interface{ Scan(...interface{}) error }) (*models.Bet, error) {
+	var bet models.Bet
+	var resolvedAt sql.NullTime
+	err := row.Scan(&bet.ID, &bet.CreatorID, &bet.OpponentID, &bet.ArbiterID, &bet.WinnerID,
+		&bet.Creator, &bet.Opponent, &bet.Arbiter, &bet.Winner,
+		&bet.Stake, &bet.Description, &bet.Status, &bet.CreatedAt, &bet.ExpiresAt, &resolvedAt)
+	if err != nil {
+		return nil, err
+	}
+	if resolvedAt.Valid {
+		bet.ResolvedAt = &resolvedAt.Time
+	}
+	return &bet, nil
+}
+
+func (r *BetRepository) CreateBetTx(tx *sql.Tx, bet *models.Bet) error {
+	query := `
+        INSERT INTO bets (creator_id, opponent_id, arbiter_id, stake, description, expires_at)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING id, status, created_at
+    `
+	err := tx.QueryRow(query, bet.CreatorID, bet.OpponentID, bet.ArbiterID, bet.Stake, bet.Description, bet.ExpiresAt).
+		Scan(&bet.ID, &bet.Status, &bet.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("ошибка создания пари: %w", err)
+	}
+	return nil
+}
+
+func (r *BetRepository) LockBetTx(tx *sql.Tx, id int) (*models.Bet, error) {
+	query := "SELECT" + betColumns + betJoins + "WHERE b.id = $1 FOR UPDATE OF b"
+	bet, err := scanBet(tx.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка блокировки пари: %w", err)
+	}
+	return bet, nil
+}
+
+// LockExpiredBetsTx блокирует до limit просроченных незакрытых пари. Строки, которые уже
+// обрабатывает другой экземпляр сервиса, пропускаются.
+func (r *BetRepository) LockExpiredBetsTx(tx *sql.Tx, limit int) ([]*models.Bet, error) {
+	query := "SELECT" + betColumns + betJoins + `
+        WHERE b.status IN ($1, $2) AND b.expires_at <= NOW()
+        ORDER BY b.id
+        LIMIT $3
+        FOR UPDATE OF b SKIP LOCKED
+    `
+	rows, err := tx.Query(query, models.BetStatusOpen, models.BetStatusActive, limit)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка выборки просроченных пари: %w", err)
+	}
+	defer rows.Close()
+
+	var bets []*models.Bet
+	for rows.Next() {
+		bet, err := scanBet(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка сканирования пари: %w", err)
+		}
+		bets = append(bets, bet)
+	}
+	return bets, rows.Err()
+}
+
+func (r *BetRepository) UpdateBetTx(tx *sql.Tx, bet *models.Bet) error {
+	query := `
+        UPDATE bets SET status = $1, winner_id = $2, resolved_at = CASE WHEN $3 THEN NOW() END
+        WHERE id = $4
+        RETURNING resolved_at
+    `
+	resolved := bet.Status != models.BetStatusOpen && bet.Status != models.BetStatusActive
+	var resolvedAt sql.NullTime
+	if err := tx.QueryRow(query, bet.Status, nullableID(bet.WinnerID), resolved, bet.ID).Scan(&resolvedAt); err != nil {
+		return fmt.Errorf("ошибка обновления пари: %w", err)
+	}
+	if resolvedAt.Valid {
+		bet.ResolvedAt = &resolvedAt.Time
+	}
+	return nil
+}
+
+// GetUserBets возвращает пари, где пользователь участник или арбитр; новые — первыми
+func (r *BetRepository) GetUserBets(userID int) ([]models.Bet, error) {
+	query := "SELECT" + betColumns + betJoins + `
+        WHERE $1 IN (b.creator_id, b.opponent_id, b.arbiter_id)
+        ORDER BY b.created_at DESC, b.id DESC
+    `
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения пари: %w", err)
+	}
+	defer rows.Close()
+
+	bets := []models.Bet{}
+	for rows.Next() {
+		bet, err := scanBet(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка сканирования пари: %w", err)
+		}
+		bets = append(bets, *bet)
+	}
+	return bets, rows.Err()
+}
@@ -0,0 +1,143 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+	"github.com/lib/pq"
+)
+
+const catalogItemColumns = `
+        i.id, i.name, ` + itemPriceSQL + `, i.stock, COALESCE(i.category, ''), i.description, i.tags, i.image_urls, i.sort_order
+`
+
+func scanCatalogItem(row interface{ Scan(...interface{}) error }) (*models.Item, error) {
+	var item models.Item
+	var stock sql.NullInt64
+	err := row.Scan(&item.ID, &item.Name, &item.Price, &stock, &item.Category, &item.Description,
+		pq.Array(&item.Tags), pq.Array(&item.ImageURLs), &item.SortOrder)
+	if err != nil {
+		return nil, err
+	}
+	if stock.Valid {
+		n := int(stock.Int64)
+		item.Stock = &n
+	}
+	return &item, nil
+}
+
+// GetCatalogItem возвращает предмет со всеми данными витрины
+func (r *ItemRepository) GetCatalogItem(name string) (*models.Item, error) {
+	item, err := scanCatalogItem(r.db.QueryRow("SELECT"+catalogItemColumns+"FROM items i WHERE i.name = $1", name))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения предмета: %w", err)
+	}
+	return item, nil
+}
+
+// ListItems выбирает предметы каталога по фильтру. Без поискового запроса предметы
+// идут в порядке витрины: по категориям, затем по sort_order. С запросом сначала
+// идут совпадения по началу названия, затем по похожести (pg_trgm).
+func (r *ItemRepository) ListItems(f models.CatalogFilter) ([]models.Item, error) {
+	var where []string
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	orderBy := "COALESCE(c.sort_order, 2147483647), i.category, i.sort_order, i.name"
+	if f.Query != "" {
+		q, prefix, contains := arg(f.Query), arg(escapeLike(f.Query)+"%"), arg("%"+escapeLike(f.Query)+"%")
+		where = append(where, fmt.Sprintf("(i.name ILIKE %s OR i.description ILIKE %s OR i.name %% %s OR LOWER(%s) = ANY(i.tags))",
+			contains, contains, q, q))
+		orderBy = fmt.Sprintf("(i.name ILIKE %s) DESC, similarity(i.name, %s) DESC, i.sort_order, i.name", prefix, q)
+	}
+	if f.Category != "" {
+		where = append(where, "i.category = "+arg(f.Category))
+	}
+	if len(f.Tags) > 0 {
+		where = append(where, "i.tags @> "+arg(pq.Array(f.Tags)))
+	}
+	if f.MinPrice > 0 {
+		where = append(where, "cp.price >= "+arg(f.MinPrice))
+	}
+	if f.MaxPrice > 0 {
+		where = append(where, "cp.price <= "+arg(f.MaxPrice))
+	}
+	if f.InStock {
+		where = append(where, "(i.stock IS NULL OR i.stock > 0)")
+	}
+
+	query := `
+        SELECT i.id, i.name, cp.price, i.stock, COALESCE(i.category, ''), i.description, i.tags, i.image_urls, i.sort_order
+        FROM items i
+        CROSS JOIN LATERAL (SELECT ` + itemPriceSQL + ` AS price) cp
+        LEFT JOIN categories c ON c.slug = i.category
+    `
+	if len(where) > 0 {
+		query += "WHERE " + strings.Join(where, " AND ") + "\n"
+	}
+	query += "ORDER BY " + orderBy + "\nLIMIT " + arg(f.Limit) + " OFFSET " + arg(f.Offset)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения каталога: %w", err)
+	}
+	defer rows.Close()
+
+	items := []models.Item{}
+	for rows.Next() {
+		item, err := scanCatalogItem(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка сканирования предмета: %w", err)
+		}
+		items = append(items, *item)
+	}
+	return items, rows.Err()
+}
+
+func (r *ItemRepository) GetCategories() ([]models.Category, error) {
+	rows, err := r.db.Query("SELECT slug, name, sort_order FROM categories ORDER BY sort_order, slug")
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения категорий: %w", err)
+	}
+	defer rows.Close()
+
+	categories := []models.Category{}
+	for rows.Next() {
+		var c models.Category
+		if err := rows.Scan(&c.Slug, &c.Name, &c.SortOrder); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования категории: %w", err)
+		}
+		categories = append(categories, c)
+	}
+	return categories, rows.Err()
+}
+
+// CategoryExists сообщает, есть ли категория в справочнике
+func (r *ItemRepository) CategoryExists(slug string) (bool, error) {
+	var exists bool
+	if err := r.db.QueryRow("SELECT EXISTS (SELECT 1 FROM categories WHERE slug = $1)", slug).Scan(&exists); err != nil {
+		return false, fmt.Errorf("ошибка проверки категории: %w", err)
+	}
+	return exists, nil
+}
+
+// SaveCategory создаёт категорию или обновляет название и порядок существующей
+func (r *ItemRepository) SaveCategory(c *models.Category) error {
+	query := `
+        INSERT INTO categories (slug, name, sort_order)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (slug) DO UPDATE SET name = EXCLUDED.name, sort_order = EXCLUDED.sort_order
+    `
+	if _, err := r.db.Exec(query, c.Slug, c.Name, c.SortOrder); err != nil {
+		return fmt.Errorf("ошибка сохранения категории: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+)
+
+type HoldRepository struct {
+	db *sql.DB
+}
+
+func NewHoldRepository(db *sql.DB) *HoldRepository {
+	return &HoldRepository{db: db}
+}
+
+func (r *HoldRepository) CreateHoldTx(tx *sql.Tx, hold *models.CoinHold) error {
+	query := `
+        INSERT INTO coin_holds (user_id, bet_id, auction_id, amount)
+        VALUES ($1, $2, $3, $4)
+        RETURNING id, status, created_at
+    `
+	err := tx.QueryRow(query, hold.UserID, nullableID(hold.BetID), nullableID(hold.AuctionID), hold.Amount).
+		Scan(&hold.ID, &hold.Status, &hold.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("ошибка создания резерва: %w", err)
+	}
+	return nil
+}
+
+// LockBetHoldsTx блокирует действующие резервы по пари
+func (r *HoldRepository) LockBetHoldsTx(tx *sql.Tx, betID int) ([]models.CoinHold, error) {
+	query := `
+        SELECT id, user_id, COALESCE(bet_id, 0), amount, status, created_at
+        FROM coin_holds
+        WHERE bet_id = $1 AND status = $2
+        ORDER BY id
+        FOR UPDATE
+    `
+	rows, err := tx.Query(query, betID, models.HoldStatusHeld)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка блокировки резервов: %w", err)
+	}
+	defer rows.Close()
+
+	var holds []models.CoinHold
+	for rows.Next() {
+		var hold models.CoinHold
+		if err := rows.Scan(&hold.ID, &hold.UserID, &hold.BetID, &hold.Amount, &hold.Status, &hold.CreatedAt); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования резерва: %w", err)
+		}
+		holds = append(holds, hold)
+	}
+	return holds, rows.Err()
+}
+
+func (r *HoldRepository) ReleaseHoldTx(tx *sql.Tx, hold *models.CoinHold) error {
+	query := "UPDATE coin_holds SET status = $1, released_at = NOW() WHERE id = $2"
+	if _, err := tx.Exec(query, models.HoldStatusReleased, hold.ID); err != nil {
+		return fmt.Errorf("ошибка снятия резерва: %w", err)
+	}
+	hold.Status = models.HoldStatusReleased
+	return nil
+}
+
+// LockAuctionHoldTx блокирует действующий резерв пользователя по аукциону.
+// Возвращает nil, если резерва нет.
+func (r *HoldRepository) LockAuctionHoldTx(tx *sql.Tx, auctionID, userID int) (*models.CoinHold, error) {
+	query := `
+        SELECT id, user_id, auction_id, amount, status, created_at
+        FROM coin_holds
+        WHERE auction_id = $1 AND user_id = $2 AND status = $3
+        FOR UPDATE
+    `
+	var hold models.CoinHold
+	err := tx.QueryRow(query, auctionID, userID, models.HoldStatusHeld).
+		Scan(&hold.ID, &hold.UserID, &hold.AuctionID, &hold.Amount, &hold.Status, &hold.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка блокировки резерва: %w", err)
+	}
+	return &hold, nil
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/itocode21/MerchServiceAvito/internal/models"
+	"github.com/lib/pq"
 )
 
 type ItemRepository struct {
@@ -18,7 +19,7 @@ func NewItemRepository(db *sql.DB) *ItemRepository {
 
 func (r *ItemRepository) GetItemByName(name string) (*models.Item, error) {
 	var item models.Item
-	query := "SELECT id, name, price FROM items WHERE name = $1"
+	query := "SELECT i.id, i.name, " + itemPriceSQL + " FROM items i WHERE i.name = $1"
 	err := r.db.QueryRow(query, name).Scan(&item.ID, &item.Name, &item.Price)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -29,6 +30,25 @@ func (r *ItemRepository) GetItemByName(name string) (*models.Item, error) {
 	return &item, nil
 }
 
+// GetItemsByNames возвращает предметы по названиям; ненайденные в результат не попадают
+func (r *ItemRepository) GetItemsByNames(names []string) (map[string]*models.Item, error) {
+	rows, err := r.db.Query("SELECT i.id, i.name, "+itemPriceSQL+" FROM items i WHERE i.name = ANY($1)", pq.Array(names))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения предметов: %w", err)
+	}
+	defer rows.Close()
+
+	items := make(map[string]*models.Item, len(names))
+	for rows.Next() {
+		var item models.Item
+		if err := rows.Scan(&item.ID, &item.Name, &item.Price); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования предмета: %w", err)
+		}
+		items[item.Name] = &item
+	}
+	return items, rows.Err()
+}
+
 func (r *ItemRepository) AddToInventory(tx *sql.Tx, userID, itemID int) error {
 	query := `
         INSERT INTO inventory (user_id, item_id, quantity)
@@ -43,6 +63,145 @@ func (r *ItemRepository) AddToInventory(tx *sql.Tx, userID, itemID int) error {
 	return nil
 }
 
+// TakeFromStockTx списывает одну единицу запаса предмета. Возвращает false, если
+// запас ограничен и закончился; у предметов без ограничения запас не меняется.
+func (r *ItemRepository) TakeFromStockTx(tx *sql.Tx, itemID int) (bool, error) {
+	query := "UPDATE items SET stock = stock - 1 WHERE id = $1 AND (stock IS NULL OR stock > 0)"
+	res, err := tx.Exec(query, itemID)
+	if err != nil {
+		return false, fmt.Errorf("ошибка списания запаса: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("ошибка списания запаса: %w", err)
+	}
+	return n > 0, nil
+}
+
+// ReturnToStockTx возвращает в запас единицу, списанную TakeFromStockTx
+func (r *ItemRepository) ReturnToStockTx(tx *sql.Tx, itemID int) error {
+	if _, err := tx.Exec("UPDATE items SET stock = stock + 1 WHERE id = $1 AND stock IS NOT NULL", itemID); err != nil {
+		return fmt.Errorf("ошибка возврата запаса: %w", err)
+	}
+	return nil
+}
+
+// UpdateItemTx применяет изменения к предмету. Пустая категория снимает её.
+// Историю цен ведёт вызывающий.
+func (r *ItemRepository) UpdateItemTx(tx *sql.Tx, name string, upd models.ItemUpdate) (*models.Item, error) {
+	query := `
+        UPDATE items i SET
+            price = CASE WHEN $1 > 0 THEN $1 ELSE price END,
+            stock = CASE WHEN $2 THEN NULL WHEN $3::INT IS NOT NULL THEN $3::INT ELSE stock END,
+            category = CASE WHEN $4::TEXT IS NOT NULL THEN NULLIF($4::TEXT, '') ELSE category END,
+            description = COALESCE($5, description),
+            tags = COALESCE($6, tags),
+            image_urls = COALESCE($7, image_urls),
+            sort_order = COALESCE($8, sort_order)
+        WHERE i.name = $9
+        RETURNING` + catalogItemColumns
+	var stockArg, categoryArg, descriptionArg, sortOrderArg interface{}
+	if upd.Stock != nil {
+		stockArg = *upd.Stock
+	}
+	if upd.Category != nil {
+		categoryArg = *upd.Category
+	}
+	if upd.Description != nil {
+		descriptionArg = *upd.Description
+	}
+	if upd.SortOrder != nil {
+		sortOrderArg = *upd.SortOrder
+	}
+	item, err := scanCatalogItem(tx.QueryRow(query, upd.Price, upd.UnlimitedStock, stockArg, categoryArg,
+		descriptionArg, pq.Array(upd.Tags), pq.Array(upd.ImageURLs), sortOrderArg, name))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка обновления предмета: %w", err)
+	}
+	return item, nil
+}
+
+// LockInventoryTx блокирует строки инвентаря пользователей по указанным предметам
+// в порядке (user_id, item_id) и возвращает количества: userID -> itemID -> quantity
+func (r *ItemRepository) LockInventoryTx(tx *sql.Tx, userIDs, itemIDs []int) (map[int]map[int]int, error) {
+	query := `
+        SELECT user_id, item_id, quantity
+        FROM inventory
+        WHERE user_id = ANY($1) AND item_id = ANY($2)
+        ORDER BY user_id, item_id
+        FOR UPDATE
+    `
+	rows, err := tx.Query(query, pq.Array(userIDs), pq.Array(itemIDs))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка блокировки инвентаря: %w", err)
+	}
+	defer rows.Close()
+
+	inventory := make(map[int]map[int]int, len(userIDs))
+	for _, userID := range userIDs {
+		inventory[userID] = make(map[int]int)
+	}
+	for rows.Next() {
+		var userID, itemID, quantity int
+		if err := rows.Scan(&userID, &itemID, &quantity); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования инвентаря: %w", err)
+		}
+		inventory[userID][itemID] = quantity
+	}
+	return inventory, rows.Err()
+}
+
+// MoveItemsTx перекладывает quantity предметов из инвентаря одного пользователя в инвентарь
+// другого и записывает перемещение в историю. Строки инвентаря должны быть заблокированы.
+func (r *ItemRepository) MoveItemsTx(tx *sql.Tx, t *models.ItemTransfer) error {
+	_, err := tx.Exec("UPDATE inventory SET quantity = quantity - $1 WHERE user_id = $2 AND item_id = $3",
+		t.Quantity, t.FromUserID, t.ItemID)
+	if err != nil {
+		return fmt.Errorf("ошибка списания из инвентаря: %w", err)
+	}
+	_, err = tx.Exec("DELETE FROM inventory WHERE user_id = $1 AND item_id = $2 AND quantity = 0", t.FromUserID, t.ItemID)
+	if err != nil {
+		return fmt.Errorf("ошибка списания из инвентаря: %w", err)
+	}
+	query := `
+        INSERT INTO inventory (user_id, item_id, quantity)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (user_id, item_id)
+        DO UPDATE SET quantity = inventory.quantity + EXCLUDED.quantity
+    `
+	if _, err := tx.Exec(query, t.ToUserID, t.ItemID, t.Quantity); err != nil {
+		return fmt.Errorf("ошибка добавления в инвентарь: %w", err)
+	}
+
+	query = `
+        INSERT INTO item_transfers (from_user_id, to_user_id, item_id, quantity, trade_id)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING id, created_at
+    `
+	err = tx.QueryRow(query, t.FromUserID, t.ToUserID, t.ItemID, t.Quantity, nullableID(t.TradeID)).Scan(&t.ID, &t.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("ошибка записи передачи предмета: %w", err)
+	}
+	return nil
+}
+
+func (r *ItemRepository) CreatePurchaseTx(tx *sql.Tx, p *models.Purchase) error {
+	query := `
+        INSERT INTO purchases (buyer_id, recipient_id, item_id, price, list_price, discount, promo_code, message)
+        VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8)
+        RETURNING id, created_at
+    `
+	err := tx.QueryRow(query, p.BuyerID, p.RecipientID, p.ItemID, p.Price, p.ListPrice, p.Discount, p.PromoCode, p.Message).
+		Scan(&p.ID, &p.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("ошибка записи покупки: %w", err)
+	}
+	return nil
+}
+
 func (r *ItemRepository) GetUserInventory(userID int) ([]gin.H, error) {
 	query := `
         SELECT i.name, inv.quantity
@@ -0,0 +1,80 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+)
+
+// itemPriceSQL — действующая цена предмета i: последняя наступившая запись истории
+// цен, а для предмета без истории — items.price
+const itemPriceSQL = `COALESCE((SELECT ip.price FROM item_prices ip
+            WHERE ip.item_id = i.id AND ip.effective_from <= NOW()
+            ORDER BY ip.effective_from DESC, ip.id DESC LIMIT 1), i.price)`
+
+// CreatePriceTx записывает цену предмета, действующую с p.EffectiveFrom; нулевое
+// время означает начало транзакции. admin — пользователь, который задал цену.
+// Возвращает false, если на это время у предмета уже есть цена.
+func (r *ItemRepository) CreatePriceTx(tx *sql.Tx, p *models.ItemPrice, admin string) (bool, error) {
+	query := `
+        INSERT INTO item_prices (item_id, price, effective_from, created_by)
+        VALUES ($1, $2, COALESCE($3, NOW()), (SELECT id FROM users WHERE username = $4))
+        ON CONFLICT (item_id, effective_from) DO NOTHING
+        RETURNING id, effective_from, created_at
+    `
+	var effectiveFrom interface{}
+	if !p.EffectiveFrom.IsZero() {
+		effectiveFrom = p.EffectiveFrom
+	}
+	err := tx.QueryRow(query, p.ItemID, p.Price, effectiveFrom, admin).Scan(&p.ID, &p.EffectiveFrom, &p.CreatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("ошибка записи цены: %w", err)
+	}
+	p.CreatedBy = admin
+	return true, nil
+}
+
+// GetPrices возвращает историю цен предмета вместе с запланированными изменениями
+func (r *ItemRepository) GetPrices(itemID int) ([]models.ItemPrice, error) {
+	query := `
+        SELECT ip.id, ip.item_id, ip.price, ip.effective_from, ip.effective_from > NOW(),
+            COALESCE(u.username, ''), ip.created_at
+        FROM item_prices ip
+        LEFT JOIN users u ON u.id = ip.created_by
+        WHERE ip.item_id = $1
+        ORDER BY ip.effective_from, ip.id
+    `
+	rows, err := r.db.Query(query, itemID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения истории цен: %w", err)
+	}
+	defer rows.Close()
+
+	prices := []models.ItemPrice{}
+	for rows.Next() {
+		var p models.ItemPrice
+		if err := rows.Scan(&p.ID, &p.ItemID, &p.Price, &p.EffectiveFrom, &p.Scheduled, &p.CreatedBy, &p.CreatedAt); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования цены: %w", err)
+		}
+		prices = append(prices, p)
+	}
+	return prices, rows.Err()
+}
+
+// DeleteScheduledPrice отменяет ещё не наступившее изменение цены;
+// false — такого изменения нет
+func (r *ItemRepository) DeleteScheduledPrice(itemID, id int) (bool, error) {
+	res, err := r.db.Exec("DELETE FROM item_prices WHERE id = $1 AND item_id = $2 AND effective_from > NOW()", id, itemID)
+	if err != nil {
+		return false, fmt.Errorf("ошибка отмены цены: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("ошибка отмены цены: %w", err)
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,120 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+)
+
+// LeaderboardRepository читает из базы исходные данные для пересборки рейтингов
+type LeaderboardRepository struct {
+	db *sql.DB
+}
+
+func NewLeaderboardRepository(db *sql.DB) *LeaderboardRepository {
+	return &LeaderboardRepository{db: db}
+}
+
+// GetTransferDays возвращает суммы переводов между сотрудниками по дням
+func (r *LeaderboardRepository) GetTransferDays() ([]models.TransferDay, error) {
+	query := `
+        SELECT date_trunc('day', t.created_at), fu.username, tu.username, SUM(t.amount)
+        FROM transactions t
+        JOIN users fu ON fu.id = t.from_user_id
+        JOIN users tu ON tu.id = t.to_user_id
+        WHERE t.kind = $1
+        GROUP BY 1, 2, 3
+    `
+	rows, err := r.db.Query(query, models.TransactionKindTransfer)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения переводов: %w", err)
+	}
+	defer rows.Close()
+
+	var days []models.TransferDay
+	for rows.Next() {
+		var d models.TransferDay
+		if err := rows.Scan(&d.Day, &d.From, &d.To, &d.Amount); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования переводов: %w", err)
+		}
+		days = append(days, d)
+	}
+	return days, rows.Err()
+}
+
+// GetInventorySizes возвращает, сколько предметов лежит в инвентаре каждого сотрудника
+func (r *LeaderboardRepository) GetInventorySizes() (map[string]int, error) {
+	query := `
+        SELECT u.username, SUM(inv.quantity)
+        FROM inventory inv
+        JOIN users u ON u.id = inv.user_id
+        GROUP BY u.username
+        HAVING SUM(inv.quantity) > 0
+    `
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения инвентаря: %w", err)
+	}
+	defer rows.Close()
+
+	sizes := make(map[string]int)
+	for rows.Next() {
+		var username string
+		var n int
+		if err := rows.Scan(&username, &n); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования инвентаря: %w", err)
+		}
+		sizes[username] = n
+	}
+	return sizes, rows.Err()
+}
+
+// GetTransferScores считает счета username в рейтингах переводов за каждый из
+// периодов ranges: сколько он получил, отправил и скольким разным коллегам отправлял
+func (r *LeaderboardRepository) GetTransferScores(username string, ranges []models.PeriodRange) ([]models.TransferScores, error) {
+	args := []interface{}{models.TransactionKindTransfer, username}
+	columns := make([]string, 0, 3*len(ranges))
+	for _, pr := range ranges {
+		period := "TRUE"
+		if !pr.From.IsZero() {
+			args = append(args, pr.From, pr.To)
+			period = fmt.Sprintf("t.created_at >= $%d AND t.created_at < $%d", len(args)-1, len(args))
+		}
+		columns = append(columns,
+			fmt.Sprintf("COALESCE(SUM(t.amount) FILTER (WHERE t.to_user_id = u.id AND %s), 0)", period),
+			fmt.Sprintf("COALESCE(SUM(t.amount) FILTER (WHERE t.from_user_id = u.id AND %s), 0)", period),
+			fmt.Sprintf("COUNT(DISTINCT t.to_user_id) FILTER (WHERE t.from_user_id = u.id AND %s)", period))
+	}
+	query := `
+        SELECT ` + strings.Join(columns, ", ") + `
+        FROM users u
+        LEFT JOIN transactions t ON (t.from_user_id = u.id OR t.to_user_id = u.id) AND t.kind = $1
+        WHERE u.username = $2
+    `
+	scores := make([]models.TransferScores, len(ranges))
+	dest := make([]interface{}, 0, 3*len(ranges))
+	for i := range scores {
+		dest = append(dest, &scores[i].Received, &scores[i].Sent, &scores[i].Thanked)
+	}
+	if err := r.db.QueryRow(query, args...).Scan(dest...); err != nil {
+		return nil, fmt.Errorf("ошибка подсчёта переводов %s: %w", username, err)
+	}
+	return scores, nil
+}
+
+// GetInventorySize возвращает, сколько предметов лежит в инвентаре username
+func (r *LeaderboardRepository) GetInventorySize(username string) (int, error) {
+	query := `
+        SELECT COALESCE(SUM(inv.quantity), 0)
+        FROM inventory inv
+        JOIN users u ON u.id = inv.user_id
+        WHERE u.username = $1
+    `
+	var n int
+	if err := r.db.QueryRow(query, username).Scan(&n); err != nil {
+		return 0, fmt.Errorf("ошибка получения инвентаря %s: %w", username, err)
+	}
+	return n, nil
+}
@@ -0,0 +1,160 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+)
+
+type NotificationRepository struct {
+	db *sql.DB
+}
+
+func NewNotificationRepository(db *sql.DB) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+// createNotificationSQL создаёт уведомление, если пользователь не отключил этот вид
+const createNotificationSQL = `
+        INSERT INTO notifications (user_id, kind, message)
+        SELECT $1, $2, $3
+        WHERE NOT EXISTS (
+            SELECT 1 FROM notification_preferences WHERE user_id = $1 AND kind = $2 AND NOT enabled
+        )
+        RETURNING id, created_at
+    `
+
+// CreateNotificationTx создаёт уведомление в той же транзакции, что и событие, о котором оно сообщает.
+// Если пользователь отключил уведомления этого вида, ничего не создаётся и n.ID остаётся нулевым.
+func (r *NotificationRepository) CreateNotificationTx(tx *sql.Tx, n *models.Notification) error {
+	return createNotification(tx.QueryRow(createNotificationSQL, n.UserID, n.Kind, n.Message), n)
+}
+
+// CreateNotification создаёт уведомление о событии вне транзакции (например, о входе)
+func (r *NotificationRepository) CreateNotification(n *models.Notification) error {
+	return createNotification(r.db.QueryRow(createNotificationSQL, n.UserID, n.Kind, n.Message), n)
+}
+
+func createNotification(row *sql.Row, n *models.Notification) error {
+	err := row.Scan(&n.ID, &n.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка создания уведомления: %w", err)
+	}
+	return nil
+}
+
+// GetUserNotifications возвращает уведомления пользователя, новые первыми;
+// unreadOnly — только непрочитанные
+func (r *NotificationRepository) GetUserNotifications(userID int, unreadOnly bool, limit, offset int) ([]models.Notification, error) {
+	query := `
+        SELECT id, user_id, kind, message, created_at, read_at
+        FROM notifications
+        WHERE user_id = $1 AND (NOT $2 OR read_at IS NULL)
+        ORDER BY created_at DESC, id DESC
+        LIMIT $3 OFFSET $4
+    `
+	rows, err := r.db.Query(query, userID, unreadOnly, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения уведомлений: %w", err)
+	}
+	defer rows.Close()
+
+	notifications := []models.Notification{}
+	for rows.Next() {
+		var n models.Notification
+		var readAt sql.NullTime
+		if err := rows.Scan(&n.ID, &n.UserID, &n.Kind, &n.Message, &n.CreatedAt, &readAt); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования уведомления: %w", err)
+		}
+		if readAt.Valid {
+			n.ReadAt = &readAt.Time
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, rows.Err()
+}
+
+func (r *NotificationRepository) CountUnread(userID int) (int, error) {
+	var n int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL", userID).Scan(&n); err != nil {
+		return 0, fmt.Errorf("ошибка подсчёта уведомлений: %w", err)
+	}
+	return n, nil
+}
+
+// MarkRead отмечает уведомление прочитанным; false — у пользователя нет такого уведомления
+func (r *NotificationRepository) MarkRead(userID, id int) (bool, error) {
+	query := "UPDATE notifications SET read_at = COALESCE(read_at, NOW()) WHERE id = $1 AND user_id = $2"
+	res, err := r.db.Exec(query, id, userID)
+	if err != nil {
+		return false, fmt.Errorf("ошибка отметки уведомления: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// MarkAllRead отмечает прочитанными все уведомления пользователя и возвращает их число
+func (r *NotificationRepository) MarkAllRead(userID int) (int64, error) {
+	res, err := r.db.Exec("UPDATE notifications SET read_at = NOW() WHERE user_id = $1 AND read_at IS NULL", userID)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка отметки уведомлений: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// GetPreferences возвращает настройки пользователя: вид -> включён
+func (r *NotificationRepository) GetPreferences(userID int) (map[string]bool, error) {
+	rows, err := r.db.Query("SELECT kind, enabled FROM notification_preferences WHERE user_id = $1", userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения настроек уведомлений: %w", err)
+	}
+	defer rows.Close()
+
+	prefs := make(map[string]bool)
+	for rows.Next() {
+		var kind string
+		var enabled bool
+		if err := rows.Scan(&kind, &enabled); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования настройки уведомлений: %w", err)
+		}
+		prefs[kind] = enabled
+	}
+	return prefs, rows.Err()
+}
+
+func (r *NotificationRepository) SetPreferencesTx(tx *sql.Tx, userID int, prefs map[string]bool) error {
+	query := `
+        INSERT INTO notification_preferences (user_id, kind, enabled)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (user_id, kind) DO UPDATE SET enabled = EXCLUDED.enabled
+    `
+	for kind, enabled := range prefs {
+		if _, err := tx.Exec(query, userID, kind, enabled); err != nil {
+			return fmt.Errorf("ошибка сохранения настройки уведомлений: %w", err)
+		}
+	}
+	return nil
+}
+
+// RememberDeviceTx запоминает устройство, с которого вошёл пользователь. Возвращает,
+// новое ли это устройство и были ли у пользователя устройства до него.
+func (r *NotificationRepository) RememberDeviceTx(tx *sql.Tx, userID int, device string) (bool, bool, error) {
+	query := `
+        WITH prior AS (SELECT COUNT(*) AS n FROM user_devices WHERE user_id = $1),
+        added AS (
+            INSERT INTO user_devices (user_id, device) VALUES ($1, $2)
+            ON CONFLICT (user_id, device) DO NOTHING
+            RETURNING created_at
+        )
+        SELECT EXISTS (SELECT 1 FROM added), (SELECT n FROM prior) > 0
+    `
+	var added, hadDevices bool
+	if err := tx.QueryRow(query, userID, device).Scan(&added, &hadDevices); err != nil {
+		return false, false, fmt.Errorf("ошибка сохранения устройства: %w", err)
+	}
+	return added, hadDevices, nil
+}
@@ -0,0 +1,86 @@
+package repositories
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+	"github.com/lib/pq"
+)
+
+// outboxRelayLock — ключ advisory-блокировки, под которой работает ретранслятор outbox
+const outboxRelayLock = 4701
+
+type OutboxRepository struct {
+	db *sql.DB
+}
+
+func NewOutboxRepository(db *sql.DB) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+// AppendTx записывает событие в outbox в транзакции операции, о которой оно сообщает
+func (r *OutboxRepository) AppendTx(tx *sql.Tx, userID int, p models.OutboxPayload) error {
+	payload, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации события %s: %w", p.EventType(), err)
+	}
+	_, err = tx.Exec("INSERT INTO outbox_events (type, user_id, payload) VALUES ($1, $2, $3)", p.EventType(), userID, payload)
+	if err != nil {
+		return fmt.Errorf("ошибка записи события %s: %w", p.EventType(), err)
+	}
+	return nil
+}
+
+// TryLockRelayTx берёт advisory-блокировку ретранслятора до конца транзакции;
+// false — ретранслирует другой экземпляр сервиса
+func (r *OutboxRepository) TryLockRelayTx(tx *sql.Tx) (bool, error) {
+	var locked bool
+	if err := tx.QueryRow("SELECT pg_try_advisory_xact_lock($1)", outboxRelayLock).Scan(&locked); err != nil {
+		return false, fmt.Errorf("ошибка блокировки ретранслятора: %w", err)
+	}
+	return locked, nil
+}
+
+// GetPendingTx возвращает до limit неопубликованных событий в порядке записи
+func (r *OutboxRepository) GetPendingTx(tx *sql.Tx, limit int) ([]models.OutboxEvent, error) {
+	query := `
+        SELECT id, type, user_id, payload, created_at
+        FROM outbox_events
+        WHERE published_at IS NULL
+        ORDER BY id
+        LIMIT $1
+    `
+	rows, err := tx.Query(query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения событий: %w", err)
+	}
+	defer rows.Close()
+
+	var events []models.OutboxEvent
+	for rows.Next() {
+		var e models.OutboxEvent
+		if err := rows.Scan(&e.ID, &e.Type, &e.UserID, &e.Payload, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования события: %w", err)
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+func (r *OutboxRepository) MarkPublishedTx(tx *sql.Tx, ids []int64) error {
+	if _, err := tx.Exec("UPDATE outbox_events SET published_at = NOW() WHERE id = ANY($1)", pq.Array(ids)); err != nil {
+		return fmt.Errorf("ошибка отметки событий: %w", err)
+	}
+	return nil
+}
+
+// DeletePublished удаляет события, опубликованные раньше, чем olderThanDays дней назад
+func (r *OutboxRepository) DeletePublished(olderThanDays int) (int64, error) {
+	res, err := r.db.Exec("DELETE FROM outbox_events WHERE published_at < NOW() - make_interval(days => $1)", olderThanDays)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка удаления событий: %w", err)
+	}
+	return res.RowsAffected()
+}
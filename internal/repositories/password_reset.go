@@ -0,0 +1,65 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+func (r *UserRepository) IsAdmin(username string) (bool, error) {
+	var isAdmin bool
+	err := r.db.QueryRow("SELECT is_admin FROM users WHERE username = $1", username).Scan(&isAdmin)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check admin flag: %v", err)
+	}
+	return isAdmin, nil
+}
+
+func (r *UserRepository) UpdatePasswordHashTx(tx *sql.Tx, userID int, passwordHash string) error {
+	_, err := tx.Exec("UPDATE users SET password_hash = $1 WHERE id = $2", passwordHash, userID)
+	if err != nil {
+		return fmt.Errorf("ошибка обновления пароля: %v", err)
+	}
+	return nil
+}
+
+func (r *UserRepository) CreatePasswordResetTokenTx(tx *sql.Tx, userID int, tokenHash string, expiresAt time.Time) error {
+	// Ранее выданные и ещё не использованные токены становятся недействительными
+	_, err := tx.Exec("UPDATE password_reset_tokens SET used_at = NOW() WHERE user_id = $1 AND used_at IS NULL", userID)
+	if err != nil {
+		return fmt.Errorf("ошибка отзыва старых токенов сброса: %v", err)
+	}
+	query := `
+        INSERT INTO password_reset_tokens (user_id, token_hash, expires_at)
+        VALUES ($1, $2, $3)
+    `
+	if _, err := tx.Exec(query, userID, tokenHash, expiresAt); err != nil {
+		return fmt.Errorf("ошибка создания токена сброса: %v", err)
+	}
+	return nil
+}
+
+// UsePasswordResetTokenTx помечает токен использованным и возвращает его владельца.
+// Если токен не найден, истёк или уже использован, возвращается (0, "", nil).
+func (r *UserRepository) UsePasswordResetTokenTx(tx *sql.Tx, tokenHash string) (int, string, error) {
+	query := `
+        UPDATE password_reset_tokens t
+        SET used_at = NOW()
+        FROM users u
+        WHERE t.token_hash = $1 AND t.used_at IS NULL AND t.expires_at > NOW() AND u.id = t.user_id
+        RETURNING u.id, u.username
+    `
+	var userID int
+	var username string
+	err := tx.QueryRow(query, tokenHash).Scan(&userID, &username)
+	if err == sql.ErrNoRows {
+		return 0, "", nil
+	}
+	if err != nil {
+		return 0, "", fmt.Errorf("ошибка проверки токена сброса: %v", err)
+	}
+	return userID, username, nil
+}
@@ -0,0 +1,116 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+)
+
+type PaymentRequestRepository struct {
+	db *sql.DB
+}
+
+func NewPaymentRequestRepository(db *sql.DB) *PaymentRequestRepository {
+	return &PaymentRequestRepository{db: db}
+}
+
+// Истёкшие, но ещё не обработанные запросы помечаются expired при чтении
+const paymentRequestColumns = `
+        pr.id, pr.requester_id, pr.payer_id, ru.username, pu.username, pr.amount, pr.memo,
+        CASE WHEN pr.status = 'pending' AND pr.expires_at <= NOW() THEN 'expired' ELSE pr.status END,
+        COALESCE(pr.transaction_id, 0), pr.created_at, pr.expires_at, pr.resolved_at
+`
+
+func scanPaymentRequest(row interface{ Scan(...interface{}) error }) (*models.PaymentRequest, error) {
+	var pr models.PaymentRequest
+	var resolvedAt sql.NullTime
+	err := row.Scan(&pr.ID, &pr.RequesterID, &pr.PayerID, &pr.Requester, &pr.Payer, &pr.Amount, &pr.Memo,
+		&pr.Status, &pr.TransactionID, &pr.CreatedAt, &pr.ExpiresAt, &resolvedAt)
+	if err != nil {
+		return nil, err
+	}
+	if resolvedAt.Valid {
+		pr.ResolvedAt = &resolvedAt.Time
+	}
+	return &pr, nil
+}
+
+func (r *PaymentRequestRepository) CreatePaymentRequest(pr *models.PaymentRequest) error {
+	query := `
+        INSERT INTO payment_requests (requester_id, payer_id, amount, memo, expires_at)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING id, status, created_at
+    `
+	err := r.db.QueryRow(query, pr.RequesterID, pr.PayerID, pr.Amount, pr.Memo, pr.ExpiresAt).
+		Scan(&pr.ID, &pr.Status, &pr.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("ошибка создания запроса монет: %w", err)
+	}
+	return nil
+}
+
+// LockPaymentRequestTx блокирует запрос до конца транзакции, чтобы его нельзя было
+// одновременно подтвердить и отклонить
+func (r *PaymentRequestRepository) LockPaymentRequestTx(tx *sql.Tx, id int) (*models.PaymentRequest, error) {
+	query := `SELECT` + paymentRequestColumns + `
+        FROM payment_requests pr
+        JOIN users ru ON ru.id = pr.requester_id
+        JOIN users pu ON pu.id = pr.payer_id
+        WHERE pr.id = $1
+        FOR UPDATE OF pr
+    `
+	pr, err := scanPaymentRequest(tx.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка блокировки запроса монет: %w", err)
+	}
+	return pr, nil
+}
+
+func (r *PaymentRequestRepository) ResolvePaymentRequestTx(tx *sql.Tx, pr *models.PaymentRequest) error {
+	query := `
+        UPDATE payment_requests SET status = $1, transaction_id = $2, resolved_at = $3
+        WHERE id = $4
+    `
+	now := time.Now()
+	if _, err := tx.Exec(query, pr.Status, nullableID(pr.TransactionID), now, pr.ID); err != nil {
+		return fmt.Errorf("ошибка обновления запроса монет: %w", err)
+	}
+	pr.ResolvedAt = &now
+	return nil
+}
+
+// GetUserPaymentRequests возвращает запросы, где пользователь платит (incoming)
+// и где он просит монеты (outgoing); новые — первыми
+func (r *PaymentRequestRepository) GetUserPaymentRequests(userID int) (incoming, outgoing []models.PaymentRequest, err error) {
+	query := `SELECT` + paymentRequestColumns + `
+        FROM payment_requests pr
+        JOIN users ru ON ru.id = pr.requester_id
+        JOIN users pu ON pu.id = pr.payer_id
+        WHERE pr.payer_id = $1 OR pr.requester_id = $1
+        ORDER BY pr.created_at DESC, pr.id DESC
+    `
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ошибка получения запросов монет: %w", err)
+	}
+	defer rows.Close()
+
+	incoming, outgoing = []models.PaymentRequest{}, []models.PaymentRequest{}
+	for rows.Next() {
+		pr, err := scanPaymentRequest(rows)
+		if err != nil {
+			return nil, nil, fmt.Errorf("ошибка сканирования запроса монет: %w", err)
+		}
+		if pr.PayerID == userID {
+			incoming = append(incoming, *pr)
+		} else {
+			outgoing = append(outgoing, *pr)
+		}
+	}
+	return incoming, outgoing, rows.Err()
+}
@@ -0,0 +1,87 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+)
+
+func (r *UserRepository) GetProfile(username string) (*models.Profile, error) {
+	query := `
+        SELECT username, display_name, department, office, title, avatar_url
+        FROM users
+        WHERE username = $1
+    `
+	var p models.Profile
+	err := r.db.QueryRow(query, username).
+		Scan(&p.Username, &p.DisplayName, &p.Department, &p.Office, &p.Title, &p.AvatarURL)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения профиля: %v", err)
+	}
+	return &p, nil
+}
+
+// UpdateProfile меняет только переданные (не nil) поля профиля.
+func (r *UserRepository) UpdateProfile(username string, upd *models.ProfileUpdate) (*models.Profile, error) {
+	query := `
+        UPDATE users
+        SET display_name = COALESCE($2, display_name),
+            department = COALESCE($3, department),
+            office = COALESCE($4, office),
+            title = COALESCE($5, title),
+            avatar_url = COALESCE($6, avatar_url)
+        WHERE username = $1
+        RETURNING username, display_name, department, office, title, avatar_url
+    `
+	var p models.Profile
+	err := r.db.QueryRow(query, username, upd.DisplayName, upd.Department, upd.Office, upd.Title, upd.AvatarURL).
+		Scan(&p.Username, &p.DisplayName, &p.Department, &p.Office, &p.Title, &p.AvatarURL)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка обновления профиля: %v", err)
+	}
+	return &p, nil
+}
+
+// SearchProfiles ищет активных сотрудников по префиксу и нечёткому совпадению (pg_trgm)
+// логина и отображаемого имени. Сначала идут совпадения по префиксу, затем по похожести.
+func (r *UserRepository) SearchProfiles(q string, limit int) ([]models.Profile, error) {
+	query := `
+        SELECT username, display_name, department, office, title, avatar_url
+        FROM users
+        WHERE is_active AND (
+            username ILIKE $2 OR display_name ILIKE $2 OR display_name ILIKE '% ' || $2
+            OR username % $1 OR display_name % $1
+        )
+        ORDER BY (username ILIKE $2 OR display_name ILIKE $2 OR display_name ILIKE '% ' || $2) DESC,
+                 GREATEST(similarity(username, $1), similarity(display_name, $1)) DESC,
+                 username
+        LIMIT $3
+    `
+	rows, err := r.db.Query(query, q, escapeLike(q)+"%", limit)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка поиска пользователей: %v", err)
+	}
+	defer rows.Close()
+
+	profiles := []models.Profile{}
+	for rows.Next() {
+		var p models.Profile
+		if err := rows.Scan(&p.Username, &p.DisplayName, &p.Department, &p.Office, &p.Title, &p.AvatarURL); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования профиля: %v", err)
+		}
+		profiles = append(profiles, p)
+	}
+	return profiles, nil
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,157 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+)
+
+type PromotionRepository struct {
+	db *sql.DB
+}
+
+func NewPromotionRepository(db *sql.DB) *PromotionRepository {
+	return &PromotionRepository{db: db}
+}
+
+const promotionColumns = `
+        p.id, COALESCE(p.code, ''), p.kind, p.value, COALESCE(p.item_id, 0), COALESCE(pi.name, ''),
+        COALESCE(p.category, ''), p.starts_at, p.ends_at, COALESCE(p.max_uses, 0),
+        COALESCE(p.per_user_limit, 0), p.uses, p.stackable, p.active, p.created_at
+`
+
+func scanPromotion(row interface{ Scan(...interface{}) error }, extra ...interface{}) (*models.Promotion, error) {
+	var p models.Promotion
+	var endsAt sql.NullTime
+	dest := []interface{}{&p.ID, &p.Code, &p.Kind, &p.Value, &p.ItemID, &p.Item,
+		&p.Category, &p.StartsAt, &endsAt, &p.MaxUses,
+		&p.PerUserLimit, &p.Uses, &p.Stackable, &p.Active, &p.CreatedAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	if endsAt.Valid {
+		p.EndsAt = &endsAt.Time
+	}
+	return &p, nil
+}
+
+func (r *PromotionRepository) CreatePromotion(p *models.Promotion) error {
+	query := `
+        INSERT INTO promotions (code, kind, value, item_id, category, starts_at, ends_at, max_uses, per_user_limit, stackable)
+        VALUES (NULLIF($1, ''), $2, $3, $4, NULLIF($5, ''), $6, $7, $8, $9, $10)
+        RETURNING id, uses, active, created_at
+    `
+	var endsAt interface{}
+	if p.EndsAt != nil {
+		endsAt = *p.EndsAt
+	}
+	err := r.db.QueryRow(query, p.Code, p.Kind, p.Value, nullableID(p.ItemID), p.Category, p.StartsAt, endsAt,
+		nullableID(p.MaxUses), nullableID(p.PerUserLimit), p.Stackable).
+		Scan(&p.ID, &p.Uses, &p.Active, &p.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("ошибка создания скидки: %w", err)
+	}
+	return nil
+}
+
+func (r *PromotionRepository) GetPromotions() ([]*models.Promotion, error) {
+	query := "SELECT" + promotionColumns + `
+        FROM promotions p
+        LEFT JOIN items pi ON pi.id = p.item_id
+        ORDER BY p.id DESC
+    `
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения скидок: %w", err)
+	}
+	defer rows.Close()
+
+	promotions := []*models.Promotion{}
+	for rows.Next() {
+		p, err := scanPromotion(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка сканирования скидки: %w", err)
+		}
+		promotions = append(promotions, p)
+	}
+	return promotions, rows.Err()
+}
+
+// DeactivatePromotion отключает скидку; false — скидка не найдена
+func (r *PromotionRepository) DeactivatePromotion(id int) (bool, error) {
+	res, err := r.db.Exec("UPDATE promotions SET active = FALSE WHERE id = $1", id)
+	if err != nil {
+		return false, fmt.Errorf("ошибка отключения скидки: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("ошибка отключения скидки: %w", err)
+	}
+	return n > 0, nil
+}
+
+// GetCandidatesTx возвращает действующие автоматические скидки на предмет и скидку
+// с кодом code независимо от её срока и области действия, чтобы сервис мог
+// объяснить, почему код не подходит. InScope и UserUses заполняются для userID.
+func (r *PromotionRepository) GetCandidatesTx(tx *sql.Tx, userID, itemID int, code string) ([]*models.Promotion, error) {
+	query := "SELECT" + promotionColumns + `,
+            (p.item_id IS NULL OR p.item_id = i.id) AND (p.category IS NULL OR p.category = i.category),
+            (SELECT COUNT(*) FROM promotion_redemptions pr WHERE pr.promotion_id = p.id AND pr.user_id = $1)
+        FROM promotions p
+        JOIN items i ON i.id = $2
+        LEFT JOIN items pi ON pi.id = p.item_id
+        WHERE p.active AND (
+            p.code = $3
+            OR (p.code IS NULL
+                AND (p.item_id IS NULL OR p.item_id = i.id)
+                AND (p.category IS NULL OR p.category = i.category)
+                AND p.starts_at <= NOW() AND (p.ends_at IS NULL OR p.ends_at > NOW()))
+        )
+        ORDER BY p.id
+    `
+	rows, err := tx.Query(query, userID, itemID, code)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения скидок: %w", err)
+	}
+	defer rows.Close()
+
+	var promotions []*models.Promotion
+	for rows.Next() {
+		var inScope bool
+		var userUses int
+		p, err := scanPromotion(rows, &inScope, &userUses)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка сканирования скидки: %w", err)
+		}
+		p.InScope, p.UserUses = inScope, userUses
+		promotions = append(promotions, p)
+	}
+	return promotions, rows.Err()
+}
+
+// UseTx учитывает использование скидки. Возвращает false, если общий лимит
+// использований уже исчерпан.
+func (r *PromotionRepository) UseTx(tx *sql.Tx, promotionID int) (bool, error) {
+	query := "UPDATE promotions SET uses = uses + 1 WHERE id = $1 AND (max_uses IS NULL OR uses < max_uses)"
+	res, err := tx.Exec(query, promotionID)
+	if err != nil {
+		return false, fmt.Errorf("ошибка учёта скидки: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("ошибка учёта скидки: %w", err)
+	}
+	return n > 0, nil
+}
+
+func (r *PromotionRepository) CreateRedemptionTx(tx *sql.Tx, promotionID, userID, purchaseID, discount int) error {
+	query := `
+        INSERT INTO promotion_redemptions (promotion_id, user_id, purchase_id, discount)
+        VALUES ($1, $2, $3, $4)
+    `
+	if _, err := tx.Exec(query, promotionID, userID, purchaseID, discount); err != nil {
+		return fmt.Errorf("ошибка записи применения скидки: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+	"github.com/lib/pq"
+)
+
+type ScheduleRepository struct {
+	db *sql.DB
+}
+
+func NewScheduleRepository(db *sql.DB) *ScheduleRepository {
+	return &ScheduleRepository{db: db}
+}
+
+const scheduleColumns = `
+        s.id, s.owner_id, s.to_user_id, ou.username, tu.username, s.amount,
+        s.recurrence, s.status, s.starts_at, s.next_run_at, s.created_at
+`
+
+const scheduleJoins = `
+        FROM scheduled_transfers s
+        JOIN users ou ON ou.id = s.owner_id
+        JOIN users tu ON tu.id = s.to_user_id
+`
+
+func scanSchedule(row interface{ Scan(...interface{}) error }) (*models.ScheduledTransfer, error) {
+	var st models.ScheduledTransfer
+	err := row.Scan(&st.ID, &st.OwnerID, &st.ToUserID, &st.Owner, &st.ToUser, &st.Amount,
+		&st.Recurrence, &st.Status, &st.StartsAt, &st.NextRunAt, &st.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+func (r *ScheduleRepository) CreateSchedule(st *models.ScheduledTransfer) error {
+	query := `
+        INSERT INTO scheduled_transfers (owner_id, to_user_id, amount, recurrence, starts_at, next_run_at)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING id, status, created_at
+    `
+	err := r.db.QueryRow(query, st.OwnerID, st.ToUserID, st.Amount, st.Recurrence, st.StartsAt, st.NextRunAt).
+		Scan(&st.ID, &st.Status, &st.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("ошибка создания расписания: %w", err)
+	}
+	return nil
+}
+
+func (r *ScheduleRepository) GetUserSchedules(ownerID int) ([]models.ScheduledTransfer, error) {
+	query := "SELECT" + scheduleColumns + scheduleJoins + `
+        WHERE s.owner_id = $1
+        ORDER BY s.id DESC
+    `
+	rows, err := r.db.Query(query, ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения расписаний: %w", err)
+	}
+	defer rows.Close()
+
+	schedules := []models.ScheduledTransfer{}
+	for rows.Next() {
+		st, err := scanSchedule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка сканирования расписания: %w", err)
+		}
+		schedules = append(schedules, *st)
+	}
+	return schedules, rows.Err()
+}
+
+func (r *ScheduleRepository) GetSchedule(id int) (*models.ScheduledTransfer, error) {
+	query := "SELECT" + scheduleColumns + scheduleJoins + "WHERE s.id = $1"
+	st, err := scanSchedule(r.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения расписания: %w", err)
+	}
+	return st, nil
+}
+
+func (r *ScheduleRepository) LockScheduleTx(tx *sql.Tx, id int) (*models.ScheduledTransfer, error) {
+	query := "SELECT" + scheduleColumns + scheduleJoins + "WHERE s.id = $1 FOR UPDATE OF s"
+	st, err := scanSchedule(tx.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка блокировки расписания: %w", err)
+	}
+	return st, nil
+}
+
+// LockDueScheduleTx блокирует самое раннее активное расписание, срок запуска которого наступил,
+// кроме skipIDs. Расписания, которые уже выполняет другой экземпляр сервиса, пропускаются.
+// nil — выполнять нечего.
+func (r *ScheduleRepository) LockDueScheduleTx(tx *sql.Tx, skipIDs []int) (*models.ScheduledTransfer, error) {
+	query := "SELECT" + scheduleColumns + scheduleJoins + `
+        WHERE s.status = $1 AND s.next_run_at <= NOW() AND NOT (s.id = ANY($2))
+        ORDER BY s.next_run_at, s.id
+        LIMIT 1
+        FOR UPDATE OF s SKIP LOCKED
+    `
+	if skipIDs == nil {
+		skipIDs = []int{}
+	}
+	st, err := scanSchedule(tx.QueryRow(query, models.ScheduleStatusActive, pq.Array(skipIDs)))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка выборки расписаний: %w", err)
+	}
+	return st, nil
+}
+
+func (r *ScheduleRepository) UpdateScheduleTx(tx *sql.Tx, st *models.ScheduledTransfer) error {
+	query := "UPDATE scheduled_transfers SET status = $1, next_run_at = $2 WHERE id = $3"
+	if _, err := tx.Exec(query, st.Status, st.NextRunAt, st.ID); err != nil {
+		return fmt.Errorf("ошибка обновления расписания: %w", err)
+	}
+	return nil
+}
+
+func (r *ScheduleRepository) CreateRunTx(tx *sql.Tx, run *models.ScheduledTransferRun) error {
+	query := `
+        INSERT INTO scheduled_transfer_runs (schedule_id, planned_at, status, error, transaction_id)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING id, created_at
+    `
+	err := tx.QueryRow(query, run.ScheduleID, run.PlannedAt, run.Status, run.Error, nullableID(run.TransactionID)).
+		Scan(&run.ID, &run.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("ошибка записи запуска расписания: %w", err)
+	}
+	return nil
+}
+
+func (r *ScheduleRepository) GetScheduleRuns(scheduleID, limit int) ([]models.ScheduledTransferRun, error) {
+	query := `
+        SELECT id, schedule_id, planned_at, status, error, COALESCE(transaction_id, 0), created_at
+        FROM scheduled_transfer_runs
+        WHERE schedule_id = $1
+        ORDER BY created_at DESC, id DESC
+        LIMIT $2
+    `
+	rows, err := r.db.Query(query, scheduleID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения запусков расписания: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []models.ScheduledTransferRun{}
+	for rows.Next() {
+		var run models.ScheduledTransferRun
+		if err := rows.Scan(&run.ID, &run.ScheduleID, &run.PlannedAt, &run.Status, &run.Error, &run.TransactionID, &run.CreatedAt); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования запуска расписания: %w", err)
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
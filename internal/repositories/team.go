@@ -0,0 +1,214 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+)
+
+type TeamRepository struct {
+	db *sql.DB
+}
+
+func NewTeamRepository(db *sql.DB) *TeamRepository {
+	return &TeamRepository{db: db}
+}
+
+func (r *TeamRepository) CreateTeam(team *models.Team) error {
+	query := "INSERT INTO teams (name) VALUES ($1) RETURNING id, coins, created_at"
+	err := r.db.QueryRow(query, team.Name).Scan(&team.ID, &team.Coins, &team.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("ошибка создания команды: %v", err)
+	}
+	return nil
+}
+
+func (r *TeamRepository) GetTeam(teamID int) (*models.Team, error) {
+	var team models.Team
+	query := "SELECT id, name, coins, created_at FROM teams WHERE id = $1"
+	err := r.db.QueryRow(query, teamID).Scan(&team.ID, &team.Name, &team.Coins, &team.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения команды: %v", err)
+	}
+	return &team, nil
+}
+
+func (r *TeamRepository) LockTeamTx(tx *sql.Tx, teamID int) (*models.Team, error) {
+	var team models.Team
+	query := "SELECT id, name, coins, created_at FROM teams WHERE id = $1 FOR UPDATE"
+	err := tx.QueryRow(query, teamID).Scan(&team.ID, &team.Name, &team.Coins, &team.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка блокировки команды: %v", err)
+	}
+	return &team, nil
+}
+
+func (r *TeamRepository) UpdateTeamBalanceTx(tx *sql.Tx, team *models.Team) error {
+	_, err := tx.Exec("UPDATE teams SET coins = $1 WHERE id = $2", team.Coins, team.ID)
+	if err != nil {
+		return fmt.Errorf("ошибка обновления бюджета команды: %v", err)
+	}
+	return nil
+}
+
+func (r *TeamRepository) SetMember(teamID, userID int, role string) error {
+	query := `
+        INSERT INTO team_members (team_id, user_id, role)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (team_id, user_id) DO UPDATE SET role = EXCLUDED.role
+    `
+	if _, err := r.db.Exec(query, teamID, userID, role); err != nil {
+		return fmt.Errorf("ошибка добавления участника: %v", err)
+	}
+	return nil
+}
+
+func (r *TeamRepository) RemoveMember(teamID, userID int) (bool, error) {
+	res, err := r.db.Exec("DELETE FROM team_members WHERE team_id = $1 AND user_id = $2", teamID, userID)
+	if err != nil {
+		return false, fmt.Errorf("ошибка удаления участника: %v", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("ошибка удаления участника: %v", err)
+	}
+	return n > 0, nil
+}
+
+// GetMemberRole возвращает роль пользователя в команде или пустую строку, если он не участник.
+func (r *TeamRepository) GetMemberRole(teamID, userID int) (string, error) {
+	var role string
+	err := r.db.QueryRow("SELECT role FROM team_members WHERE team_id = $1 AND user_id = $2", teamID, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("ошибка получения роли: %v", err)
+	}
+	return role, nil
+}
+
+func (r *TeamRepository) GetMemberRoleTx(tx *sql.Tx, teamID, userID int) (string, error) {
+	var role string
+	err := tx.QueryRow("SELECT role FROM team_members WHERE team_id = $1 AND user_id = $2", teamID, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("ошибка получения роли: %v", err)
+	}
+	return role, nil
+}
+
+func (r *TeamRepository) GetMembers(teamID int) ([]models.TeamMember, error) {
+	query := `
+        SELECT u.id, u.username, m.role
+        FROM team_members m
+        JOIN users u ON u.id = m.user_id
+        WHERE m.team_id = $1
+        ORDER BY m.role DESC, u.username
+    `
+	rows, err := r.db.Query(query, teamID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения участников: %v", err)
+	}
+	defer rows.Close()
+
+	members := []models.TeamMember{}
+	for rows.Next() {
+		var m models.TeamMember
+		if err := rows.Scan(&m.UserID, &m.Username, &m.Role); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования участника: %v", err)
+		}
+		members = append(members, m)
+	}
+	return members, nil
+}
+
+func (r *TeamRepository) GetUserTeams(userID int) ([]models.Team, error) {
+	query := `
+        SELECT t.id, t.name, t.coins, t.created_at, m.role
+        FROM team_members m
+        JOIN teams t ON t.id = m.team_id
+        WHERE m.user_id = $1
+        ORDER BY t.name
+    `
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения команд: %v", err)
+	}
+	defer rows.Close()
+
+	teams := []models.Team{}
+	for rows.Next() {
+		var t models.Team
+		if err := rows.Scan(&t.ID, &t.Name, &t.Coins, &t.CreatedAt, &t.Role); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования команды: %v", err)
+		}
+		teams = append(teams, t)
+	}
+	return teams, nil
+}
+
+func (r *TeamRepository) GetSpendingReport(teamID int, from, to time.Time) (*models.TeamSpendingReport, error) {
+	report := &models.TeamSpendingReport{TeamID: teamID, From: from, To: to}
+
+	query := `
+        SELECT
+            COALESCE(SUM(amount) FILTER (WHERE kind = $4), 0),
+            COALESCE(SUM(amount) FILTER (WHERE kind = $5), 0)
+        FROM transactions
+        WHERE team_id = $1 AND created_at >= $2 AND created_at < $3
+    `
+	err := r.db.QueryRow(query, teamID, from, to, models.TransactionKindTeamFunding, models.TransactionKindTeamReward).
+		Scan(&report.Funded, &report.Spent)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка построения отчёта: %v", err)
+	}
+
+	report.ByRecipient, err = r.spendingBy(teamID, from, to, "to_user_id")
+	if err != nil {
+		return nil, err
+	}
+	report.ByManager, err = r.spendingBy(teamID, from, to, "from_user_id")
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// spendingBy группирует награды из бюджета команды по получателю или по выдавшему менеджеру.
+// column — имя колонки transactions, а не пользовательский ввод.
+func (r *TeamRepository) spendingBy(teamID int, from, to time.Time, column string) ([]models.TeamSpendingLine, error) {
+	query := `
+        SELECT u.username, COUNT(*), SUM(t.amount)
+        FROM transactions t
+        JOIN users u ON u.id = t.` + column + `
+        WHERE t.team_id = $1 AND t.kind = $2 AND t.created_at >= $3 AND t.created_at < $4
+        GROUP BY u.username
+        ORDER BY SUM(t.amount) DESC, u.username
+    `
+	rows, err := r.db.Query(query, teamID, models.TransactionKindTeamReward, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка построения отчёта: %v", err)
+	}
+	defer rows.Close()
+
+	lines := []models.TeamSpendingLine{}
+	for rows.Next() {
+		var l models.TeamSpendingLine
+		if err := rows.Scan(&l.Username, &l.Count, &l.Amount); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования отчёта: %v", err)
+		}
+		lines = append(lines, l)
+	}
+	return lines, nil
+}
@@ -0,0 +1,174 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+	"github.com/lib/pq"
+)
+
+type TradeRepository struct {
+	db *sql.DB
+}
+
+func NewTradeRepository(db *sql.DB) *TradeRepository {
+	return &TradeRepository{db: db}
+}
+
+const tradeColumns = `
+        t.id, t.proposer_id, t.counterparty_id, pu.username, cu.username,
+        t.proposer_coins, t.counterparty_coins, t.status, t.created_at, t.resolved_at
+`
+
+const tradeJoins = `
+        FROM trades t
+        JOIN users pu ON pu.id = t.proposer_id
+        JOIN users cu ON cu.id = t.counterparty_id
+`
+
+func scanTrade(row interface{ Scan(...interface{}) error }) (*models.Trade, error) {
+	var trade models.Trade
+	var resolvedAt sql.NullTime
+	err := row.Scan(&trade.ID, &trade.ProposerID, &trade.CounterpartyID, &trade.Proposer, &trade.Counterparty,
+		&trade.ProposerCoins, &trade.CounterpartyCoins, &trade.Status, &trade.CreatedAt, &resolvedAt)
+	if err != nil {
+		return nil, err
+	}
+	if resolvedAt.Valid {
+		trade.ResolvedAt = &resolvedAt.Time
+	}
+	trade.ProposerItems, trade.CounterpartyItems = []models.TradeItem{}, []models.TradeItem{}
+	return &trade, nil
+}
+
+func (r *TradeRepository) CreateTradeTx(tx *sql.Tx, trade *models.Trade) error {
+	query := `
+        INSERT INTO trades (proposer_id, counterparty_id, proposer_coins, counterparty_coins)
+        VALUES ($1, $2, $3, $4)
+        RETURNING id, status, created_at
+    `
+	err := tx.QueryRow(query, trade.ProposerID, trade.CounterpartyID, trade.ProposerCoins, trade.CounterpartyCoins).
+		Scan(&trade.ID, &trade.Status, &trade.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("ошибка создания обмена: %w", err)
+	}
+
+	sides := []struct {
+		side  string
+		items []models.TradeItem
+	}{
+		{models.TradeSideProposer, trade.ProposerItems},
+		{models.TradeSideCounterparty, trade.CounterpartyItems},
+	}
+	for _, s := range sides {
+		for _, item := range s.items {
+			_, err := tx.Exec("INSERT INTO trade_items (trade_id, side, item_id, quantity) VALUES ($1, $2, $3, $4)",
+				trade.ID, s.side, item.ItemID, item.Quantity)
+			if err != nil {
+				return fmt.Errorf("ошибка добавления предмета в обмен: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+func (r *TradeRepository) LockTradeTx(tx *sql.Tx, id int) (*models.Trade, error) {
+	query := "SELECT" + tradeColumns + tradeJoins + "WHERE t.id = $1 FOR UPDATE OF t"
+	trade, err := scanTrade(tx.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка блокировки обмена: %w", err)
+	}
+	if err := r.loadItems(tx, []*models.Trade{trade}); err != nil {
+		return nil, err
+	}
+	return trade, nil
+}
+
+func (r *TradeRepository) UpdateTradeStatusTx(tx *sql.Tx, trade *models.Trade) error {
+	query := "UPDATE trades SET status = $1, resolved_at = NOW() WHERE id = $2 RETURNING resolved_at"
+	var resolvedAt sql.NullTime
+	if err := tx.QueryRow(query, trade.Status, trade.ID).Scan(&resolvedAt); err != nil {
+		return fmt.Errorf("ошибка обновления обмена: %w", err)
+	}
+	if resolvedAt.Valid {
+		trade.ResolvedAt = &resolvedAt.Time
+	}
+	return nil
+}
+
+// GetUserTrades возвращает обмены, где пользователь одна из сторон; новые — первыми
+func (r *TradeRepository) GetUserTrades(userID int) ([]*models.Trade, error) {
+	query := "SELECT" + tradeColumns + tradeJoins + `
+        WHERE $1 IN (t.proposer_id, t.counterparty_id)
+        ORDER BY t.created_at DESC, t.id DESC
+    `
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения обменов: %w", err)
+	}
+	defer rows.Close()
+
+	trades := []*models.Trade{}
+	for rows.Next() {
+		trade, err := scanTrade(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка сканирования обмена: %w", err)
+		}
+		trades = append(trades, trade)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка получения обменов: %w", err)
+	}
+	if err := r.loadItems(r.db, trades); err != nil {
+		return nil, err
+	}
+	return trades, nil
+}
+
+// loadItems заполняет предметы сторон одним запросом на все обмены
+func (r *TradeRepository) loadItems(q interface {
+	Query(string, ...interface{}) (*sql.Rows, error)
+}, trades []*models.Trade) error {
+	if len(trades) == 0 {
+		return nil
+	}
+	byID := make(map[int]*models.Trade, len(trades))
+	ids := make([]int, 0, len(trades))
+	for _, trade := range trades {
+		byID[trade.ID] = trade
+		ids = append(ids, trade.ID)
+	}
+
+	query := `
+        SELECT ti.trade_id, ti.side, ti.item_id, i.name, ti.quantity
+        FROM trade_items ti
+        JOIN items i ON i.id = ti.item_id
+        WHERE ti.trade_id = ANY($1)
+        ORDER BY ti.trade_id, i.name
+    `
+	rows, err := q.Query(query, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("ошибка получения предметов обмена: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tradeID int
+		var side string
+		var item models.TradeItem
+		if err := rows.Scan(&tradeID, &side, &item.ItemID, &item.Type, &item.Quantity); err != nil {
+			return fmt.Errorf("ошибка сканирования предмета обмена: %w", err)
+		}
+		trade := byID[tradeID]
+		if side == models.TradeSideProposer {
+			trade.ProposerItems = append(trade.ProposerItems, item)
+		} else {
+			trade.CounterpartyItems = append(trade.CounterpartyItems, item)
+		}
+	}
+	return rows.Err()
+}
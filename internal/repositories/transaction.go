@@ -16,21 +16,39 @@ func NewTransactionRepository(db *sql.DB) *TransactionRepository {
 }
 
 func (r *TransactionRepository) CreateTransaction(tx *sql.Tx, t *models.Transaction) error {
+	if t.Kind == "" {
+		t.Kind = models.TransactionKindTransfer
+	}
 	query := `
-        INSERT INTO transactions (from_user_id, to_user_id, amount)
-        VALUES ($1, $2, $3)
+        INSERT INTO transactions (from_user_id, to_user_id, amount, kind, team_id)
+        VALUES ($1, $2, $3, $4, $5)
         RETURNING id, created_at
     `
-	err := tx.QueryRow(query, t.FromUserID, t.ToUserID, t.Amount).Scan(&t.ID, &t.CreatedAt)
+	err := tx.QueryRow(query, nullableID(t.FromUserID), nullableID(t.ToUserID), t.Amount, t.Kind, nullableID(t.TeamID)).
+		Scan(&t.ID, &t.CreatedAt)
 	if err != nil {
-		return fmt.Errorf("ошибка создания транзакции: %v", err)
+		return fmt.Errorf("ошибка создания транзакции: %w", err)
 	}
 	return nil
 }
 
+// GetSentTodayTx возвращает сумму переводов пользователя с начала текущих суток
+func (r *TransactionRepository) GetSentTodayTx(tx *sql.Tx, userID int) (int, error) {
+	query := `
+        SELECT COALESCE(SUM(amount), 0)
+        FROM transactions
+        WHERE from_user_id = $1 AND kind = $2 AND created_at >= date_trunc('day', NOW())
+    `
+	var sent int
+	if err := tx.QueryRow(query, userID, models.TransactionKindTransfer).Scan(&sent); err != nil {
+		return 0, fmt.Errorf("ошибка подсчёта переводов за сутки: %w", err)
+	}
+	return sent, nil
+}
+
 func (r *TransactionRepository) GetUserTransactions(userID int) ([]models.Transaction, error) {
 	query := `
-        SELECT id, from_user_id, to_user_id, amount, created_at 
+        SELECT id, COALESCE(from_user_id, 0), COALESCE(to_user_id, 0), amount, kind, created_at
         FROM transactions 
         WHERE from_user_id = $1 OR to_user_id = $1
     `
@@ -43,10 +61,18 @@ func (r *TransactionRepository) GetUserTransactions(userID int) ([]models.Transa
 	var transactions []models.Transaction
 	for rows.Next() {
 		var t models.Transaction
-		if err := rows.Scan(&t.ID, &t.FromUserID, &t.ToUserID, &t.Amount, &t.CreatedAt); err != nil {
+		if err := rows.Scan(&t.ID, &t.FromUserID, &t.ToUserID, &t.Amount, &t.Kind, &t.CreatedAt); err != nil {
 			return nil, fmt.Errorf("ошибка сканирования транзакции: %v", err)
 		}
 		transactions = append(transactions, t)
 	}
 	return transactions, nil
 }
+
+// nullableID превращает нулевой идентификатор в NULL (например, у начислений нет отправителя)
+func nullableID(id int) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
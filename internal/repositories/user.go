@@ -6,6 +6,7 @@ import (
 
 	"github.com/itocode21/MerchServiceAvito/internal/config"
 	"github.com/itocode21/MerchServiceAvito/internal/models"
+	"github.com/lib/pq"
 )
 
 type UserRepository struct {
@@ -24,8 +25,8 @@ func NewUserRepository(cfg *config.Config) *UserRepository {
 
 func (r *UserRepository) GetUserByUsername(username string) (*models.User, error) {
 	var user models.User
-	query := "SELECT id, username, password_hash, coins FROM users WHERE username = $1"
-	err := r.db.QueryRow(query, username).Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Coins)
+	query := "SELECT id, username, password_hash, coins, is_active FROM users WHERE username = $1"
+	err := r.db.QueryRow(query, username).Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Coins, &user.IsActive)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -35,13 +36,27 @@ func (r *UserRepository) GetUserByUsername(username string) (*models.User, error
 	return &user, nil
 }
 
-func (r *UserRepository) CreateUser(user *models.User) error {
+// GetBalance возвращает баланс пользователя; nil — пользователь не найден
+func (r *UserRepository) GetBalance(username string) (*models.Balance, error) {
+	var b models.Balance
+	err := r.db.QueryRow("SELECT coins, held_coins FROM users WHERE username = $1", username).Scan(&b.Coins, &b.HeldCoins)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения баланса: %w", err)
+	}
+	b.Available = b.Coins - b.HeldCoins
+	return &b, nil
+}
+
+func (r *UserRepository) CreateUserTx(tx *sql.Tx, user *models.User) error {
 	query := `
         INSERT INTO users (username, password_hash, coins) 
         VALUES ($1, $2, $3) 
         RETURNING id, created_at
     `
-	err := r.db.QueryRow(query, user.Username, user.PasswordHash, user.Coins).
+	err := tx.QueryRow(query, user.Username, user.PasswordHash, user.Coins).
 		Scan(&user.ID, &user.CreatedAt)
 	if err != nil {
 		return fmt.Errorf("ошибка создания пользователя: %v", err)
@@ -62,7 +77,60 @@ func (r *UserRepository) UpdateUserBalanceTx(tx *sql.Tx, user *models.User) erro
 	query := "UPDATE users SET coins = $1 WHERE id = $2"
 	_, err := tx.Exec(query, user.Coins, user.ID)
 	if err != nil {
-		return fmt.Errorf("failed to update coins balance: %v", err)
+		return fmt.Errorf("failed to update coins balance: %w", err)
+	}
+	return nil
+}
+
+// UpdateHeldCoinsTx сохраняет сумму монет пользователя, зарезервированных в эскроу
+func (r *UserRepository) UpdateHeldCoinsTx(tx *sql.Tx, user *models.User) error {
+	query := "UPDATE users SET held_coins = $1 WHERE id = $2"
+	if _, err := tx.Exec(query, user.HeldCoins, user.ID); err != nil {
+		return fmt.Errorf("ошибка обновления резерва монет: %w", err)
+	}
+	return nil
+}
+
+// LockUsersTx блокирует строки пользователей в порядке возрастания id, чтобы параллельные
+// транзакции над одними и теми же пользователями не взаимоблокировались.
+// Ненайденные пользователи в результат не попадают.
+func (r *UserRepository) LockUsersTx(tx *sql.Tx, usernames []string) (map[string]*models.User, error) {
+	query := `
+        SELECT id, username, coins, held_coins, is_active
+        FROM users
+        WHERE username = ANY($1)
+        ORDER BY id
+        FOR UPDATE
+    `
+	rows, err := tx.Query(query, pq.Array(usernames))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка блокировки пользователей: %w", err)
+	}
+	defer rows.Close()
+
+	users := make(map[string]*models.User, len(usernames))
+	for rows.Next() {
+		var user models.User
+		if err := rows.Scan(&user.ID, &user.Username, &user.Coins, &user.HeldCoins, &user.IsActive); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования пользователя: %v", err)
+		}
+		users[user.Username] = &user
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка блокировки пользователей: %w", err)
+	}
+	return users, nil
+}
+
+func (r *UserRepository) SetUserActiveTx(tx *sql.Tx, userID int, active bool) error {
+	query := `
+        UPDATE users
+        SET is_active = $1, deactivated_at = CASE WHEN $1 THEN NULL ELSE NOW() END
+        WHERE id = $2
+    `
+	_, err := tx.Exec(query, active, userID)
+	if err != nil {
+		return fmt.Errorf("ошибка изменения статуса пользователя: %v", err)
 	}
 	return nil
 }
@@ -71,6 +139,7 @@ func (r *UserRepository) GetUserInfo(username string) (*models.UserInfo, error)
 	query := `
         SELECT 
             u.coins,
+            u.held_coins,
             COALESCE(
                 json_agg(
                     json_build_object(
@@ -84,7 +153,16 @@ func (r *UserRepository) GetUserInfo(username string) (*models.UserInfo, error)
                 json_agg(
                     json_build_object(
                         'fromUser', t.from_user_id,
-                        'amount', t.amount
+                        'amount', t.amount,
+                        'kind', t.kind,
+                        'fromProfile', CASE WHEN fu.id IS NOT NULL THEN json_build_object(
+                            'username', fu.username,
+                            'displayName', fu.display_name,
+                            'department', fu.department,
+                            'office', fu.office,
+                            'title', fu.title,
+                            'avatarUrl', fu.avatar_url
+                        ) END
                     )
                 ) FILTER (WHERE t.to_user_id = u.id),
                 '[]'::json
@@ -93,21 +171,56 @@ func (r *UserRepository) GetUserInfo(username string) (*models.UserInfo, error)
                 json_agg(
                     json_build_object(
                         'toUser', t.to_user_id,
-                        'amount', t.amount
+                        'amount', t.amount,
+                        'kind', t.kind,
+                        'toProfile', CASE WHEN tu.id IS NOT NULL THEN json_build_object(
+                            'username', tu.username,
+                            'displayName', tu.display_name,
+                            'department', tu.department,
+                            'office', tu.office,
+                            'title', tu.title,
+                            'avatarUrl', tu.avatar_url
+                        ) END
                     )
                 ) FILTER (WHERE t.from_user_id = u.id),
                 '[]'::json
-            ) AS sent
+            ) AS sent,
+            (
+                SELECT COALESCE(json_agg(json_build_object(
+                    'fromUser', b.username,
+                    'type', gi.name,
+                    'message', p.message,
+                    'createdAt', p.created_at
+                ) ORDER BY p.created_at DESC), '[]'::json)
+                FROM purchases p
+                JOIN users b ON b.id = p.buyer_id
+                JOIN items gi ON gi.id = p.item_id
+                WHERE p.recipient_id = u.id AND p.buyer_id <> u.id
+            ) AS gifts_received,
+            (
+                SELECT COALESCE(json_agg(json_build_object(
+                    'toUser', r.username,
+                    'type', gi.name,
+                    'message', p.message,
+                    'createdAt', p.created_at
+                ) ORDER BY p.created_at DESC), '[]'::json)
+                FROM purchases p
+                JOIN users r ON r.id = p.recipient_id
+                JOIN items gi ON gi.id = p.item_id
+                WHERE p.buyer_id = u.id AND p.recipient_id <> u.id
+            ) AS gifts_sent
         FROM users u
         LEFT JOIN inventory inv ON inv.user_id = u.id
         LEFT JOIN items i ON i.id = inv.item_id
         LEFT JOIN transactions t ON t.from_user_id = u.id OR t.to_user_id = u.id
+        LEFT JOIN users fu ON fu.id = t.from_user_id
+        LEFT JOIN users tu ON tu.id = t.to_user_id
         WHERE u.username = $1
-        GROUP BY u.id, u.coins
+        GROUP BY u.id, u.coins, u.held_coins
     `
 
 	var info models.UserInfo
-	err := r.db.QueryRow(query, username).Scan(&info.Coins, &info.InventoryJSON, &info.ReceivedJSON, &info.SentJSON)
+	err := r.db.QueryRow(query, username).Scan(&info.Coins, &info.HeldCoins, &info.InventoryJSON, &info.ReceivedJSON, &info.SentJSON, &info.GiftsReceivedJSON, &info.GiftsSentJSON)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -0,0 +1,242 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+	"github.com/lib/pq"
+)
+
+type WebhookRepository struct {
+	db *sql.DB
+}
+
+func NewWebhookRepository(db *sql.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+const webhookDeliveryColumns = `
+        d.id, d.subscription_id, d.event_id, d.event_type, d.body, d.status, d.attempts,
+        COALESCE(d.last_status_code, 0), COALESCE(d.last_error, ''), d.next_attempt_at,
+        d.created_at, d.delivered_at
+`
+
+func scanWebhookDelivery(row interface{ Scan(...interface{}) error }) (*models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	var body string
+	var nextAttemptAt time.Time
+	var deliveredAt sql.NullTime
+	err := row.Scan(&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &body, &d.Status, &d.Attempts,
+		&d.LastStatusCode, &d.LastError, &nextAttemptAt, &d.CreatedAt, &deliveredAt)
+	if err != nil {
+		return nil, err
+	}
+	d.Body = []byte(body)
+	if d.Status == models.WebhookDeliveryPending {
+		d.NextAttemptAt = &nextAttemptAt
+	}
+	if deliveredAt.Valid {
+		d.DeliveredAt = &deliveredAt.Time
+	}
+	return &d, nil
+}
+
+// CreateSubscription сохраняет подписку; admin — пользователь, который её создал
+func (r *WebhookRepository) CreateSubscription(s *models.WebhookSubscription, admin string) error {
+	query := `
+        INSERT INTO webhook_subscriptions (url, event_types, secret, created_by)
+        VALUES ($1, $2, $3, (SELECT id FROM users WHERE username = $4))
+        RETURNING id, active, created_at
+    `
+	err := r.db.QueryRow(query, s.URL, pq.Array(s.EventTypes), s.Secret, admin).Scan(&s.ID, &s.Active, &s.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("ошибка создания подписки: %w", err)
+	}
+	s.CreatedBy = admin
+	return nil
+}
+
+func (r *WebhookRepository) GetSubscriptions() ([]models.WebhookSubscription, error) {
+	query := `
+        SELECT s.id, s.url, s.event_types, s.active, COALESCE(u.username, ''), s.created_at
+        FROM webhook_subscriptions s
+        LEFT JOIN users u ON u.id = s.created_by
+        ORDER BY s.id
+    `
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения подписок: %w", err)
+	}
+	defer rows.Close()
+
+	subscriptions := []models.WebhookSubscription{}
+	for rows.Next() {
+		var s models.WebhookSubscription
+		if err := rows.Scan(&s.ID, &s.URL, pq.Array(&s.EventTypes), &s.Active, &s.CreatedBy, &s.CreatedAt); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования подписки: %w", err)
+		}
+		subscriptions = append(subscriptions, s)
+	}
+	return subscriptions, rows.Err()
+}
+
+func (r *WebhookRepository) SubscriptionExists(id int) (bool, error) {
+	var exists bool
+	if err := r.db.QueryRow("SELECT EXISTS (SELECT 1 FROM webhook_subscriptions WHERE id = $1)", id).Scan(&exists); err != nil {
+		return false, fmt.Errorf("ошибка проверки подписки: %w", err)
+	}
+	return exists, nil
+}
+
+// DeactivateSubscription отключает подписку и снимает её недоставленные события;
+// false — подписка не найдена или уже отключена
+func (r *WebhookRepository) DeactivateSubscription(id int) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("UPDATE webhook_subscriptions SET active = FALSE WHERE id = $1 AND active", id)
+	if err != nil {
+		return false, fmt.Errorf("ошибка отключения подписки: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil || n == 0 {
+		return false, err
+	}
+	query := "UPDATE webhook_deliveries SET status = $1, last_error = $2 WHERE subscription_id = $3 AND status = $4"
+	if _, err := tx.Exec(query, models.WebhookDeliveryDead, "подписка отключена", id, models.WebhookDeliveryPending); err != nil {
+		return false, fmt.Errorf("ошибка отмены доставок: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+	return true, nil
+}
+
+// EnqueueDeliveries ставит событие в очередь каждой активной подписке на его тип.
+// Повторная публикация того же события новых доставок не создаёт.
+func (r *WebhookRepository) EnqueueDeliveries(eventID int64, eventType, body string) (int64, error) {
+	query := `
+        INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, body)
+        SELECT id, $1, $2, $3 FROM webhook_subscriptions
+        WHERE active AND $2 = ANY(event_types)
+        ON CONFLICT (subscription_id, event_id) DO NOTHING
+    `
+	res, err := r.db.Exec(query, eventID, eventType, body)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка постановки доставок: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// ClaimDue забирает до limit доставок активных подписок, время которых пришло, и откладывает их
+// следующую попытку на lease: если экземпляр упадёт посреди отправки, доставку
+// подхватят после его истечения
+func (r *WebhookRepository) ClaimDue(limit int, lease time.Duration) ([]*models.WebhookDelivery, error) {
+	query := `
+        WITH due AS (
+            SELECT d.id FROM webhook_deliveries d
+            JOIN webhook_subscriptions s ON s.id = d.subscription_id
+            WHERE d.status = $1 AND d.next_attempt_at <= NOW() AND s.active
+            ORDER BY d.next_attempt_at, d.id
+            LIMIT $2
+            FOR UPDATE OF d SKIP LOCKED
+        )
+        UPDATE webhook_deliveries d SET next_attempt_at = NOW() + make_interval(secs => $3)
+        FROM due, webhook_subscriptions s
+        WHERE d.id = due.id AND s.id = d.subscription_id
+        RETURNING d.id, d.subscription_id, d.event_id, d.event_type, d.body, d.attempts, s.url, s.secret
+    `
+	rows, err := r.db.Query(query, models.WebhookDeliveryPending, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("ошибка выборки доставок: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []*models.WebhookDelivery
+	for rows.Next() {
+		var d models.WebhookDelivery
+		var body string
+		if err := rows.Scan(&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &body, &d.Attempts, &d.URL, &d.Secret); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования доставки: %w", err)
+		}
+		d.Body = []byte(body)
+		d.Status = models.WebhookDeliveryPending
+		deliveries = append(deliveries, &d)
+	}
+	return deliveries, rows.Err()
+}
+
+// RecordAttempt сохраняет результат попытки: статус, код ответа, ошибку и время
+// следующей попытки. Доставку, которую во время отправки отменило отключение
+// подписки, попытка в очередь не возвращает.
+func (r *WebhookRepository) RecordAttempt(d *models.WebhookDelivery, nextAttemptAt time.Time) error {
+	query := `
+        UPDATE webhook_deliveries
+        SET status = $1, attempts = $2, last_status_code = NULLIF($3, 0), last_error = NULLIF($4, ''),
+            next_attempt_at = $5, delivered_at = CASE WHEN $1 = 'delivered' THEN NOW() END
+        WHERE id = $6 AND status = 'pending'
+    `
+	_, err := r.db.Exec(query, d.Status, d.Attempts, d.LastStatusCode, d.LastError, nextAttemptAt, d.ID)
+	if err != nil {
+		return fmt.Errorf("ошибка записи попытки доставки: %w", err)
+	}
+	return nil
+}
+
+// GetDeliveries возвращает доставки подписки (0 — всех подписок) со статусом
+// status (пустой — с любым), новые первыми
+func (r *WebhookRepository) GetDeliveries(subscriptionID int, status string, limit, offset int) ([]*models.WebhookDelivery, error) {
+	query := "SELECT" + webhookDeliveryColumns + `
+        FROM webhook_deliveries d
+        WHERE ($1 = 0 OR d.subscription_id = $1) AND ($2 = '' OR d.status = $2)
+        ORDER BY d.id DESC
+        LIMIT $3 OFFSET $4
+    `
+	rows, err := r.db.Query(query, subscriptionID, status, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения доставок: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []*models.WebhookDelivery{}
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка сканирования доставки: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// RequeueDelivery возвращает доставку из списка недоставленных в очередь с
+// новым счётчиком попыток; false — такой недоставленной доставки нет или её
+// подписка отключена
+func (r *WebhookRepository) RequeueDelivery(id int64) (bool, error) {
+	query := `
+        UPDATE webhook_deliveries d
+        SET status = $1, attempts = 0, next_attempt_at = NOW()
+        FROM webhook_subscriptions s
+        WHERE d.id = $2 AND d.status = $3 AND s.id = d.subscription_id AND s.active
+    `
+	res, err := r.db.Exec(query, models.WebhookDeliveryPending, id, models.WebhookDeliveryDead)
+	if err != nil {
+		return false, fmt.Errorf("ошибка повтора доставки: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// DeleteDelivered удаляет доставки, выполненные раньше, чем olderThanDays дней назад
+func (r *WebhookRepository) DeleteDelivered(olderThanDays int) (int64, error) {
+	res, err := r.db.Exec("DELETE FROM webhook_deliveries WHERE delivered_at < NOW() - make_interval(days => $1)", olderThanDays)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка удаления доставок: %w", err)
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,139 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+)
+
+type WishlistRepository struct {
+	db *sql.DB
+}
+
+func NewWishlistRepository(db *sql.DB) *WishlistRepository {
+	return &WishlistRepository{db: db}
+}
+
+const wishlistColumns = `
+        w.user_id, w.item_id, i.name, cp.price, i.stock, u.coins - u.held_coins, w.created_at,
+        w.notified_price, w.was_in_stock, w.was_affordable
+`
+
+const wishlistJoins = `
+        FROM wishlist_items w
+        JOIN items i ON i.id = w.item_id
+        JOIN users u ON u.id = w.user_id
+        CROSS JOIN LATERAL (SELECT ` + itemPriceSQL + ` AS price) cp
+`
+
+func scanWishlistItem(row interface{ Scan(...interface{}) error }) (*models.WishlistItem, error) {
+	var w models.WishlistItem
+	var stock sql.NullInt64
+	err := row.Scan(&w.UserID, &w.ItemID, &w.Item, &w.Price, &stock, &w.Balance, &w.CreatedAt,
+		&w.NotifiedPrice, &w.WasInStock, &w.WasAffordable)
+	if err != nil {
+		return nil, err
+	}
+	if stock.Valid {
+		n := int(stock.Int64)
+		w.Stock = &n
+	}
+	w.Fill()
+	return &w, nil
+}
+
+// AddItem добавляет предмет в список желаний. Текущие цена, наличие и доступность
+// считаются уже известными пользователю. Повторное добавление ничего не меняет.
+func (r *WishlistRepository) AddItem(userID, itemID int) error {
+	query := `
+        INSERT INTO wishlist_items (user_id, item_id, notified_price, was_in_stock, was_affordable)
+        SELECT u.id, i.id, cp.price, i.stock IS NULL OR i.stock > 0, u.coins - u.held_coins >= cp.price
+        FROM users u, items i, LATERAL (SELECT ` + itemPriceSQL + ` AS price) cp
+        WHERE u.id = $1 AND i.id = $2
+        ON CONFLICT (user_id, item_id) DO NOTHING
+    `
+	if _, err := r.db.Exec(query, userID, itemID); err != nil {
+		return fmt.Errorf("ошибка добавления в список желаний: %w", err)
+	}
+	return nil
+}
+
+// RemoveItem удаляет предмет из списка желаний; false — предмета в списке не было
+func (r *WishlistRepository) RemoveItem(userID, itemID int) (bool, error) {
+	res, err := r.db.Exec("DELETE FROM wishlist_items WHERE user_id = $1 AND item_id = $2", userID, itemID)
+	if err != nil {
+		return false, fmt.Errorf("ошибка удаления из списка желаний: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("ошибка удаления из списка желаний: %w", err)
+	}
+	return n > 0, nil
+}
+
+func (r *WishlistRepository) GetUserWishlist(userID int) ([]models.WishlistItem, error) {
+	query := "SELECT" + wishlistColumns + wishlistJoins + `
+        WHERE w.user_id = $1
+        ORDER BY w.created_at, w.item_id
+    `
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения списка желаний: %w", err)
+	}
+	defer rows.Close()
+
+	items := []models.WishlistItem{}
+	for rows.Next() {
+		item, err := scanWishlistItem(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка сканирования списка желаний: %w", err)
+		}
+		items = append(items, *item)
+	}
+	return items, rows.Err()
+}
+
+// LockChangedItemsTx блокирует до limit записей активных пользователей, у которых цена,
+// наличие или доступность предмета изменились с последнего уведомления.
+// Записи, которые уже обрабатывает другой экземпляр сервиса, пропускаются.
+func (r *WishlistRepository) LockChangedItemsTx(tx *sql.Tx, limit int) ([]*models.WishlistItem, error) {
+	query := "SELECT" + wishlistColumns + wishlistJoins + `
+        WHERE u.is_active AND (
+            cp.price <> w.notified_price
+            OR (i.stock IS NULL OR i.stock > 0) <> w.was_in_stock
+            OR (u.coins - u.held_coins >= cp.price) <> w.was_affordable
+        )
+        ORDER BY w.user_id, w.item_id
+        LIMIT $1
+        FOR UPDATE OF w SKIP LOCKED
+    `
+	rows, err := tx.Query(query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка выборки списков желаний: %w", err)
+	}
+	defer rows.Close()
+
+	var items []*models.WishlistItem
+	for rows.Next() {
+		item, err := scanWishlistItem(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка сканирования списка желаний: %w", err)
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// MarkNotifiedTx запоминает текущее состояние предмета как известное пользователю
+func (r *WishlistRepository) MarkNotifiedTx(tx *sql.Tx, w *models.WishlistItem) error {
+	query := `
+        UPDATE wishlist_items SET notified_price = $1, was_in_stock = $2, was_affordable = $3
+        WHERE user_id = $4 AND item_id = $5
+    `
+	if _, err := tx.Exec(query, w.Price, w.InStock, w.Affordable(), w.UserID, w.ItemID); err != nil {
+		return fmt.Errorf("ошибка обновления списка желаний: %w", err)
+	}
+	w.NotifiedPrice, w.WasInStock, w.WasAffordable = w.Price, w.InStock, w.Affordable()
+	return nil
+}
@@ -0,0 +1,204 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+	"github.com/itocode21/MerchServiceAvito/internal/repositories"
+)
+
+const (
+	generousRecipients = 10
+	cupCollectionTag   = "cup"
+	// Сколько значков каждого вида выдаёт один проход Reconcile
+	badgeBatch = 100
+)
+
+// badgeRule — правило выдачи значка: earners находит активных пользователей,
+// которые выполнили условие, но значка ещё не получили.
+type badgeRule struct {
+	badge   models.Badge
+	earners func(r *repositories.AchievementRepository, badge string) ([]models.User, error)
+}
+
+var badgeRules = []badgeRule{
+	{
+		badge: models.Badge{
+			Code:        models.BadgeFirstPurchase,
+			Name:        "Первая покупка",
+			Description: "Купить что-нибудь в магазине мерча",
+		},
+		earners: func(r *repositories.AchievementRepository, badge string) ([]models.User, error) {
+			return r.GetBuyersWithoutBadge(badge, badgeBatch)
+		},
+	},
+	{
+		badge: models.Badge{
+			Code:        models.BadgeGenerous,
+			Name:        "Щедрая душа",
+			Description: fmt.Sprintf("Отправить монеты %d разным коллегам", generousRecipients),
+			Reward:      50,
+		},
+		earners: func(r *repositories.AchievementRepository, badge string) ([]models.User, error) {
+			return r.GetSendersWithoutBadge(badge, generousRecipients, badgeBatch)
+		},
+	},
+	{
+		badge: models.Badge{
+			Code:        models.BadgeCupCollector,
+			Name:        "Коллекционер кружек",
+			Description: "Собрать все кружки из каталога",
+			Reward:      100,
+		},
+		earners: func(r *repositories.AchievementRepository, badge string) ([]models.User, error) {
+			return r.GetCollectorsWithoutBadge(badge, cupCollectionTag, badgeBatch)
+		},
+	},
+}
+
+// AchievementService выдаёт значки за переводы и покупки
+type AchievementService struct {
+	achievementRepo  *repositories.AchievementRepository
+	userRepo         *repositories.UserRepository
+	transRepo        *repositories.TransactionRepository
+	notificationRepo *repositories.NotificationRepository
+	rules            []badgeRule
+	outboxRepo       *repositories.OutboxRepository
+	db               *sql.DB
+}
+
+func NewAchievementService(achievementRepo *repositories.AchievementRepository, userRepo *repositories.UserRepository, transRepo *repositories.TransactionRepository, notificationRepo *repositories.NotificationRepository, outboxRepo *repositories.OutboxRepository) *AchievementService {
+	return &AchievementService{
+		achievementRepo:  achievementRepo,
+		userRepo:         userRepo,
+		transRepo:        transRepo,
+		notificationRepo: notificationRepo,
+		rules:            badgeRules,
+		outboxRepo:       outboxRepo,
+		db:               userRepo.DB,
+	}
+}
+
+// Reconcile выдаёт значки всем, кто выполнил условия правил, и возвращает число
+// выданных значков. Условия проверяются по данным в базе, а не по событиям
+// внутри процесса, поэтому значок не теряется, если сервис упал между операцией
+// и выдачей, а сами переводы и покупки не платят за проверку правил. Ошибка
+// одного значка не мешает выдать остальные.
+func (s *AchievementService) Reconcile() (int, error) {
+	var awarded int
+	var errs []error
+	for _, rule := range s.rules {
+		users, err := rule.earners(s.achievementRepo, rule.badge.Code)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("значок %s: %w", rule.badge.Code, err))
+			continue
+		}
+		for _, user := range users {
+			badge := rule.badge
+			var ok bool
+			err := withTxRetry(func() error {
+				var err error
+				ok, err = s.award(user.Username, user.ID, &badge)
+				return err
+			})
+			if err != nil {
+				errs = append(errs, fmt.Errorf("значок %s для %s: %w", rule.badge.Code, user.Username, err))
+				continue
+			}
+			if ok {
+				awarded++
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return awarded, fmt.Errorf("ошибок при выдаче значков: %d: %w", len(errs), errs[0])
+	}
+	return awarded, nil
+}
+
+// award выдаёт значок и начисляет награду, если она есть; false — значок
+// уже выдан параллельным проходом
+func (s *AchievementService) award(username string, userID int, badge *models.Badge) (bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	awarded, err := s.achievementRepo.AwardTx(tx, userID, badge)
+	if err != nil || !awarded {
+		return false, err
+	}
+
+	message := fmt.Sprintf("Вы получили значок «%s»", badge.Name)
+	if badge.Reward > 0 {
+		users, err := s.userRepo.LockUsersTx(tx, []string{username})
+		if err != nil {
+			return false, err
+		}
+		user := users[username]
+		if user == nil {
+			return false, fmt.Errorf("пользователь %s не найден", username)
+		}
+		if user.Coins > maxAmount-badge.Reward {
+			return false, fmt.Errorf("баланс %s превысит допустимый максимум", username)
+		}
+		user.Coins += badge.Reward
+		if err := s.userRepo.UpdateUserBalanceTx(tx, user); err != nil {
+			return false, fmt.Errorf("ошибка обновления баланса: %w", err)
+		}
+		transaction := &models.Transaction{ToUserID: user.ID, Amount: badge.Reward, Kind: models.TransactionKindAchievement}
+		if err := s.transRepo.CreateTransaction(tx, transaction); err != nil {
+			return false, fmt.Errorf("ошибка записи транзакции: %w", err)
+		}
+		if err := s.outboxRepo.AppendTx(tx, user.ID, transferred("", username, transaction)); err != nil {
+			return false, err
+		}
+		message += fmt.Sprintf(" и %d монет", badge.Reward)
+	}
+	notification := &models.Notification{UserID: userID, Kind: models.NotificationBadgeAwarded, Message: message}
+	if err := s.notificationRepo.CreateNotificationTx(tx, notification); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+	if badge.Reward > 0 {
+		publishBalanceChanged(s.userRepo.Config.Events, username)
+	}
+	return true, nil
+}
+
+// GetBadges возвращает значки пользователя с названиями и описаниями
+func (s *AchievementService) GetBadges(username string) ([]models.Badge, error) {
+	user, err := s.userRepo.GetUserByUsername(username)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении пользователя: %w", err)
+	}
+	if user == nil {
+		return nil, userErrorf("пользователь %s не найден", username)
+	}
+	badges, err := s.achievementRepo.GetBadges(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range badges {
+		for _, rule := range s.rules {
+			if rule.badge.Code == badges[i].Code {
+				badges[i].Name, badges[i].Description = rule.badge.Name, rule.badge.Description
+			}
+		}
+	}
+	return badges, nil
+}
+
+// GetAvailableBadges возвращает все значки, которые можно получить
+func (s *AchievementService) GetAvailableBadges() []models.Badge {
+	badges := make([]models.Badge, 0, len(s.rules))
+	for _, rule := range s.rules {
+		badges = append(badges, rule.badge)
+	}
+	return badges
+}
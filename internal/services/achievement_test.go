@@ -0,0 +1,151 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/itocode21/MerchServiceAvito/internal/config"
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+	"github.com/itocode21/MerchServiceAvito/internal/repositories"
+)
+
+func TestAchievementReconcile(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка создания мока: %v", err)
+	}
+	defer db.Close()
+
+	userRepo := repositories.NewUserRepository(&config.Config{DB: db})
+	service := NewAchievementService(repositories.NewAchievementRepository(db), userRepo,
+		repositories.NewTransactionRepository(db), repositories.NewNotificationRepository(db), repositories.NewOutboxRepository(db))
+	now := time.Now()
+	buyers := "FROM users u\\s+WHERE u.is_active\\s+AND EXISTS \\(SELECT 1 FROM purchases"
+	senders := "FROM transactions t\\s+JOIN users u"
+	collectors := "AND EXISTS \\(SELECT 1 FROM items i"
+	userColumns := []string{"id", "username"}
+
+	tests := []struct {
+		name        string
+		setupMock   func()
+		wantAwarded int
+		wantErr     bool
+	}{
+		{
+			name: "Значки за первую покупку и десять коллег",
+			setupMock: func() {
+				mock.ExpectQuery(buyers).
+					WithArgs("first_purchase", badgeBatch).
+					WillReturnRows(sqlmock.NewRows(userColumns).AddRow(2, "bob"))
+				mock.ExpectBegin()
+				mock.ExpectQuery("INSERT INTO user_badges").
+					WithArgs(2, "first_purchase", 0).
+					WillReturnRows(sqlmock.NewRows([]string{"awarded_at"}).AddRow(now))
+				mock.ExpectQuery("INSERT INTO notifications").
+					WithArgs(2, "badge_awarded", "Вы получили значок «Первая покупка»").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, now))
+				mock.ExpectCommit()
+
+				mock.ExpectQuery(senders).
+					WithArgs("transfer", "generous", generousRecipients, badgeBatch).
+					WillReturnRows(sqlmock.NewRows(userColumns).AddRow(1, "alice"))
+				mock.ExpectBegin()
+				mock.ExpectQuery("INSERT INTO user_badges").
+					WithArgs(1, "generous", 50).
+					WillReturnRows(sqlmock.NewRows([]string{"awarded_at"}).AddRow(now))
+				mock.ExpectQuery("SELECT id, username, coins, held_coins, is_active FROM users").
+					WithArgs(sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "username", "coins", "held_coins", "is_active"}).
+						AddRow(1, "alice", 900, 0, true))
+				mock.ExpectExec("UPDATE users SET coins = \\$1 WHERE id = \\$2").
+					WithArgs(950, 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("INSERT INTO transactions").
+					WithArgs(nil, 1, 50, "achievement", nil).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, now))
+				mock.ExpectExec("INSERT INTO outbox_events").WithArgs(models.EventCoinsTransferred, 1, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectQuery("INSERT INTO notifications").
+					WithArgs(1, "badge_awarded", "Вы получили значок «Щедрая душа» и 50 монет").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(2, now))
+				mock.ExpectCommit()
+
+				mock.ExpectQuery(collectors).
+					WithArgs("cup", "cup_collector", badgeBatch).
+					WillReturnRows(sqlmock.NewRows(userColumns))
+			},
+			wantAwarded: 2,
+		},
+		{
+			name: "Значок уже выдан параллельным проходом",
+			setupMock: func() {
+				mock.ExpectQuery(buyers).
+					WithArgs("first_purchase", badgeBatch).
+					WillReturnRows(sqlmock.NewRows(userColumns).AddRow(2, "bob"))
+				mock.ExpectBegin()
+				mock.ExpectQuery("INSERT INTO user_badges").
+					WithArgs(2, "first_purchase", 0).
+					WillReturnRows(sqlmock.NewRows([]string{"awarded_at"}))
+				mock.ExpectRollback()
+				mock.ExpectQuery(senders).
+					WithArgs("transfer", "generous", generousRecipients, badgeBatch).
+					WillReturnRows(sqlmock.NewRows(userColumns))
+				mock.ExpectQuery(collectors).
+					WithArgs("cup", "cup_collector", badgeBatch).
+					WillReturnRows(sqlmock.NewRows(userColumns))
+			},
+		},
+		{
+			name: "Ошибка одного правила не мешает остальным",
+			setupMock: func() {
+				mock.ExpectQuery(buyers).
+					WithArgs("first_purchase", badgeBatch).
+					WillReturnError(errors.New("соединение потеряно"))
+				mock.ExpectQuery(senders).
+					WithArgs("transfer", "generous", generousRecipients, badgeBatch).
+					WillReturnRows(sqlmock.NewRows(userColumns))
+				mock.ExpectQuery(collectors).
+					WithArgs("cup", "cup_collector", badgeBatch).
+					WillReturnRows(sqlmock.NewRows(userColumns).AddRow(3, "carol"))
+				mock.ExpectBegin()
+				mock.ExpectQuery("INSERT INTO user_badges").
+					WithArgs(3, "cup_collector", 100).
+					WillReturnRows(sqlmock.NewRows([]string{"awarded_at"}).AddRow(now))
+				mock.ExpectQuery("SELECT id, username, coins, held_coins, is_active FROM users").
+					WithArgs(sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "username", "coins", "held_coins", "is_active"}).
+						AddRow(3, "carol", 1000, 0, true))
+				mock.ExpectExec("UPDATE users SET coins = \\$1 WHERE id = \\$2").
+					WithArgs(1100, 3).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("INSERT INTO transactions").
+					WithArgs(nil, 3, 100, "achievement", nil).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(3, now))
+				mock.ExpectExec("INSERT INTO outbox_events").WithArgs(models.EventCoinsTransferred, 3, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectQuery("INSERT INTO notifications").
+					WithArgs(3, "badge_awarded", "Вы получили значок «Коллекционер кружек» и 100 монет").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(3, now))
+				mock.ExpectCommit()
+			},
+			wantAwarded: 1,
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+			n, err := service.Reconcile()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Reconcile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if n != tt.wantAwarded {
+				t.Errorf("Reconcile() = %d, want %d", n, tt.wantAwarded)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Не все ожидания мока выполнены: %v", err)
+			}
+		})
+	}
+}
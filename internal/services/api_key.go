@@ -0,0 +1,95 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"slices"
+
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+	"github.com/itocode21/MerchServiceAvito/internal/repositories"
+)
+
+const apiKeyPrefix = "msk_"
+
+type APIKeyService struct {
+	apiKeyRepo *repositories.APIKeyRepository
+	userRepo   *repositories.UserRepository
+}
+
+func NewAPIKeyService(apiKeyRepo *repositories.APIKeyRepository, userRepo *repositories.UserRepository) *APIKeyService {
+	return &APIKeyService{
+		apiKeyRepo: apiKeyRepo,
+		userRepo:   userRepo,
+	}
+}
+
+// CreateAPIKey выпускает ключ для сервисного аккаунта. Сам ключ возвращается
+// только один раз, в базе хранится его хеш.
+func (s *APIKeyService) CreateAPIKey(username, name string, scopes []string) (string, *models.APIKey, error) {
+	if name == "" {
+		return "", nil, fmt.Errorf("название ключа не может быть пустым")
+	}
+	if len(scopes) == 0 {
+		return "", nil, fmt.Errorf("не указаны права ключа")
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(models.APIKeyScopes, scope) {
+			return "", nil, fmt.Errorf("неизвестное право %s", scope)
+		}
+	}
+
+	user, err := s.userRepo.GetUserByUsername(username)
+	if err != nil {
+		return "", nil, fmt.Errorf("ошибка при получении пользователя: %v", err)
+	}
+	if user == nil {
+		return "", nil, fmt.Errorf("пользователь %s не найден", username)
+	}
+
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", nil, fmt.Errorf("ошибка генерации ключа: %v", err)
+	}
+	rawKey := apiKeyPrefix + hex.EncodeToString(buf)
+
+	key := &models.APIKey{
+		UserID:   user.ID,
+		Username: user.Username,
+		Name:     name,
+		Prefix:   rawKey[:len(apiKeyPrefix)+8],
+		KeyHash:  hashToken(rawKey),
+		Scopes:   scopes,
+	}
+	if err := s.apiKeyRepo.CreateAPIKey(key); err != nil {
+		return "", nil, err
+	}
+	return rawKey, key, nil
+}
+
+func (s *APIKeyService) ListAPIKeys() ([]models.APIKey, error) {
+	return s.apiKeyRepo.ListAPIKeys()
+}
+
+func (s *APIKeyService) RevokeAPIKey(id int) error {
+	revoked, err := s.apiKeyRepo.RevokeAPIKey(id)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return fmt.Errorf("ключ %d не найден или уже отозван", id)
+	}
+	return nil
+}
+
+// ValidateAPIKey возвращает владельца ключа и его права.
+func (s *APIKeyService) ValidateAPIKey(rawKey string) (string, []string, error) {
+	key, err := s.apiKeyRepo.GetActiveAPIKeyByHash(hashToken(rawKey))
+	if err != nil {
+		return "", nil, err
+	}
+	if key == nil {
+		return "", nil, fmt.Errorf("недействительный API-ключ")
+	}
+	return key.Username, key.Scopes, nil
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-redis/redis/v8"
+	"github.com/itocode21/MerchServiceAvito/internal/config"
+	"github.com/itocode21/MerchServiceAvito/internal/repositories"
+)
+
+func TestCreateAPIKey(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка создания мока: %v", err)
+	}
+	defer db.Close()
+
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+	})
+
+	cfg := &config.Config{
+		DB:        db,
+		JWTSecret: []byte("test_secret_key"),
+		Redis:     redisClient,
+	}
+
+	userRepo := repositories.NewUserRepository(cfg)
+	apiKeyRepo := repositories.NewAPIKeyRepository(db)
+	service := NewAPIKeyService(apiKeyRepo, userRepo)
+
+	tests := []struct {
+		name      string
+		username  string
+		scopes    []string
+		setupMock func()
+		wantErr   bool
+		errMsg    string
+	}{
+		{
+			name:     "Успешное создание ключа",
+			username: "kudos-bot",
+			scopes:   []string{"transfer:send-as-bot", "read:info"},
+			setupMock: func() {
+				mock.ExpectQuery("SELECT id, username, password_hash, coins, is_active FROM users WHERE username = \\$1").
+					WithArgs("kudos-bot").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password_hash", "coins", "is_active"}).
+						AddRow(7, "kudos-bot", "hash", 1000, true))
+				mock.ExpectQuery("INSERT INTO api_keys \\(user_id, name, key_prefix, key_hash, scopes\\)").
+					WithArgs(7, "slack", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+			},
+			wantErr: false,
+		},
+		{
+			name:      "Неизвестное право",
+			username:  "kudos-bot",
+			scopes:    []string{"admin:all"},
+			setupMock: func() {},
+			wantErr:   true,
+			errMsg:    "неизвестное право admin:all",
+		},
+		{
+			name:     "Пользователь не найден",
+			username: "ghost",
+			scopes:   []string{"read:info"},
+			setupMock: func() {
+				mock.ExpectQuery("SELECT id, username, password_hash, coins, is_active FROM users WHERE username = \\$1").
+					WithArgs("ghost").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password_hash", "coins", "is_active"}))
+			},
+			wantErr: true,
+			errMsg:  "пользователь ghost не найден",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+			rawKey, key, err := service.CreateAPIKey(tt.username, "slack", tt.scopes)
+			if tt.wantErr {
+				if err == nil || err.Error() != tt.errMsg {
+					t.Errorf("CreateAPIKey() error = %v, want %q", err, tt.errMsg)
+				}
+			} else if err != nil {
+				t.Errorf("CreateAPIKey() error = %v, want nil", err)
+			} else {
+				if !strings.HasPrefix(rawKey, key.Prefix) {
+					t.Errorf("CreateAPIKey() prefix = %q, key = %q", key.Prefix, rawKey)
+				}
+				if key.KeyHash != hashToken(rawKey) {
+					t.Errorf("CreateAPIKey() stored hash does not match key")
+				}
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Не все ожидания мока выполнены: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateAPIKey(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка создания мока: %v", err)
+	}
+	defer db.Close()
+
+	cfg := &config.Config{DB: db}
+	userRepo := repositories.NewUserRepository(cfg)
+	apiKeyRepo := repositories.NewAPIKeyRepository(db)
+	service := NewAPIKeyService(apiKeyRepo, userRepo)
+
+	mock.ExpectQuery("SELECT k.id, k.user_id, u.username, k.name, k.key_prefix, k.scopes, k.created_at FROM api_keys k").
+		WithArgs(hashToken("msk_valid")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "username", "name", "key_prefix", "scopes", "created_at"}).
+			AddRow(1, 7, "kudos-bot", "slack", "msk_vali", "{read:info,transfer:send-as-bot}", time.Now()))
+	username, scopes, err := service.ValidateAPIKey("msk_valid")
+	if err != nil {
+		t.Fatalf("ValidateAPIKey() error = %v, want nil", err)
+	}
+	if username != "kudos-bot" || len(scopes) != 2 || scopes[0] != "read:info" {
+		t.Errorf("ValidateAPIKey() = %q, %v", username, scopes)
+	}
+
+	mock.ExpectQuery("SELECT k.id, k.user_id, u.username, k.name, k.key_prefix, k.scopes, k.created_at FROM api_keys k").
+		WithArgs(hashToken("msk_revoked")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "username", "name", "key_prefix", "scopes", "created_at"}))
+	if _, _, err := service.ValidateAPIKey("msk_revoked"); err == nil || err.Error() != "недействительный API-ключ" {
+		t.Errorf("ValidateAPIKey() error = %v, want %q", err, "недействительный API-ключ")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Не все ожидания мока выполнены: %v", err)
+	}
+}
@@ -0,0 +1,361 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/itocode21/MerchServiceAvito/internal/events"
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+	"github.com/itocode21/MerchServiceAvito/internal/repositories"
+)
+
+const (
+	maxAuctionDuration = 30 * 24 * time.Hour
+	auctionCloseBatch  = 100
+)
+
+type AuctionService struct {
+	auctionRepo      *repositories.AuctionRepository
+	escrow           *EscrowService
+	itemRepo         *repositories.ItemRepository
+	userRepo         *repositories.UserRepository
+	notificationRepo *repositories.NotificationRepository
+	outboxRepo       *repositories.OutboxRepository
+	db               *sql.DB
+}
+
+func NewAuctionService(auctionRepo *repositories.AuctionRepository, escrow *EscrowService, itemRepo *repositories.ItemRepository, userRepo *repositories.UserRepository, notificationRepo *repositories.NotificationRepository, outboxRepo *repositories.OutboxRepository) *AuctionService {
+	return &AuctionService{
+		auctionRepo:      auctionRepo,
+		escrow:           escrow,
+		itemRepo:         itemRepo,
+		userRepo:         userRepo,
+		notificationRepo: notificationRepo,
+		outboxRepo:       outboxRepo,
+		db:               userRepo.DB,
+	}
+}
+
+// CreateAuction выставляет предмет каталога на торги до endsAt. Лот сразу списывается
+// из запаса предмета и возвращается в него, если аукцион закончится без ставок.
+// Нулевой minIncrement означает шаг в одну монету.
+func (s *AuctionService) CreateAuction(admin, itemName string, startingPrice, minIncrement int, endsAt time.Time) (*models.Auction, error) {
+	if startingPrice <= 0 || startingPrice > maxAmount {
+		return nil, userErrorf("стартовая цена должна быть от 1 до %d", maxAmount)
+	}
+	if minIncrement == 0 {
+		minIncrement = 1
+	}
+	if minIncrement < 0 || minIncrement > maxAmount {
+		return nil, userErrorf("шаг ставки должен быть от 1 до %d", maxAmount)
+	}
+	now := time.Now()
+	if !endsAt.After(now) || endsAt.After(now.Add(maxAuctionDuration)) {
+		return nil, userErrorf("аукцион должен завершиться в пределах %d дней", int(maxAuctionDuration.Hours()/24))
+	}
+
+	item, err := s.itemRepo.GetItemByName(itemName)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения предмета: %w", err)
+	}
+	if item == nil {
+		return nil, userErrorf("предмет %s не найден", itemName)
+	}
+	user, err := s.userRepo.GetUserByUsername(admin)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении пользователя: %w", err)
+	}
+	if user == nil {
+		return nil, userErrorf("пользователь %s не найден", admin)
+	}
+
+	auction := &models.Auction{
+		ItemID:        item.ID,
+		Item:          item.Name,
+		CreatedByID:   user.ID,
+		StartingPrice: startingPrice,
+		MinIncrement:  minIncrement,
+		EndsAt:        endsAt,
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	inStock, err := s.itemRepo.TakeFromStockTx(tx, item.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !inStock {
+		return nil, userErrorf("предмет %s закончился", itemName)
+	}
+	if err := s.auctionRepo.CreateAuctionTx(tx, auction); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+	return auction, nil
+}
+
+// PlaceBid делает ставку: сумма резервируется у нового лидера, а резерв
+// прежнего лидера сразу возвращается ему. Лидер может повысить свою ставку.
+func (s *AuctionService) PlaceBid(username string, id, amount int) (*models.AuctionBid, error) {
+	if amount <= 0 || amount > maxAmount {
+		return nil, userErrorf("ставка должна быть от 1 до %d", maxAmount)
+	}
+
+	var bid *models.AuctionBid
+	var outbid string
+	err := withTxRetry(func() error {
+		outbid = ""
+		tx, err := s.db.Begin()
+		if err != nil {
+			return fmt.Errorf("ошибка начала транзакции: %w", err)
+		}
+		defer tx.Rollback()
+
+		auction, err := s.auctionRepo.LockAuctionTx(tx, id)
+		if err != nil {
+			return err
+		}
+		if auction == nil {
+			return userErrorf("аукцион %d не найден", id)
+		}
+		if auction.Status != models.AuctionStatusOpen || !time.Now().Before(auction.EndsAt) {
+			return userErrorf("аукцион %d завершён", id)
+		}
+		if amount < auction.MinNextBid() {
+			return userErrorf("ставка должна быть не меньше %d", auction.MinNextBid())
+		}
+
+		usernames := []string{username}
+		if auction.Leader != "" && auction.Leader != username {
+			usernames = append(usernames, auction.Leader)
+		}
+		users, err := s.userRepo.LockUsersTx(tx, usernames)
+		if err != nil {
+			return err
+		}
+		bidder := users[username]
+		if bidder == nil {
+			return userErrorf("пользователь %s не найден", username)
+		}
+		if !bidder.IsActive {
+			return userErrorf("пользователь %s деактивирован", username)
+		}
+
+		if auction.LeaderID != 0 {
+			if err := s.releaseLeaderHoldTx(tx, auction, users[auction.Leader]); err != nil {
+				return err
+			}
+			outbid = auction.Leader
+			if auction.Leader != username {
+				notification := &models.Notification{
+					UserID:  auction.LeaderID,
+					Kind:    models.NotificationAuctionOutbid,
+					Message: fmt.Sprintf("Вашу ставку на %s перебили: %d монет", auction.Item, amount),
+				}
+				if err := s.notificationRepo.CreateNotificationTx(tx, notification); err != nil {
+					return err
+				}
+			}
+		}
+		if err := s.escrow.HoldTx(tx, bidder, &models.CoinHold{AuctionID: auction.ID, Amount: amount}); err != nil {
+			return err
+		}
+
+		bid = &models.AuctionBid{AuctionID: auction.ID, UserID: bidder.ID, Username: username, Amount: amount}
+		if err := s.auctionRepo.CreateBidTx(tx, bid); err != nil {
+			return err
+		}
+		auction.LeaderID, auction.Leader, auction.CurrentBid = bidder.ID, username, amount
+		if err := s.auctionRepo.UpdateAuctionTx(tx, auction); err != nil {
+			return err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("ошибка фиксации транзакции: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	publishBalanceChanged(s.userRepo.Config.Events, username, outbid)
+	return bid, nil
+}
+
+// CloseAuctions закрывает до auctionCloseBatch аукционов, время которых вышло, каждый
+// в своей короткой транзакции: лидер оплачивает лот из резерва и получает предмет.
+// О выигрыше после фиксации сообщается как о покупке. Аукцион, упавший с ошибкой,
+// пропускается до следующего запуска задачи и не мешает остальным. Возвращает число
+// закрытых аукционов.
+func (s *AuctionService) CloseAuctions() (int, error) {
+	var closed int
+	var skipped []int
+	var errs []error
+	for closed+len(skipped) < auctionCloseBatch {
+		var auction *models.Auction
+		var e *events.Event
+		err := withTxRetry(func() error {
+			auction, e = nil, nil
+			tx, err := s.db.Begin()
+			if err != nil {
+				return fmt.Errorf("ошибка начала транзакции: %w", err)
+			}
+			defer tx.Rollback()
+
+			auction, err = s.auctionRepo.LockEndedAuctionTx(tx, skipped)
+			if err != nil || auction == nil {
+				return err
+			}
+			if e, err = s.closeAuctionTx(tx, auction); err != nil {
+				return err
+			}
+
+			if err := tx.Commit(); err != nil {
+				return fmt.Errorf("ошибка фиксации транзакции: %w", err)
+			}
+			return nil
+		})
+		if auction == nil {
+			if err != nil {
+				errs = append(errs, err)
+			}
+			break
+		}
+		if err != nil {
+			skipped = append(skipped, auction.ID)
+			errs = append(errs, fmt.Errorf("аукцион %d: %w", auction.ID, err))
+			continue
+		}
+		if e != nil {
+			s.userRepo.Config.Events.Publish(*e)
+		}
+		closed++
+	}
+	if len(errs) > 0 {
+		return closed, fmt.Errorf("ошибок при закрытии аукционов: %d: %w", len(errs), errs[0])
+	}
+	return closed, nil
+}
+
+func (s *AuctionService) GetAuctions() ([]*models.Auction, error) {
+	return s.auctionRepo.GetOpenAuctions()
+}
+
+func (s *AuctionService) GetAuction(id int) (*models.Auction, error) {
+	auction, err := s.auctionRepo.GetAuction(id)
+	if err != nil {
+		return nil, err
+	}
+	if auction == nil {
+		return nil, userErrorf("аукцион %d не найден", id)
+	}
+	return auction, nil
+}
+
+func (s *AuctionService) GetBids(id int) ([]models.AuctionBid, error) {
+	if _, err := s.GetAuction(id); err != nil {
+		return nil, err
+	}
+	return s.auctionRepo.GetBids(id)
+}
+
+// closeAuctionTx закрывает аукцион и возвращает событие для публикации после фиксации:
+// о покупке лота или о снятом резерве деактивированного лидера; nil — ставок не было.
+// Лот деактивированного лидера не продаётся: резерв возвращается, предмет — в запас.
+func (s *AuctionService) closeAuctionTx(tx *sql.Tx, auction *models.Auction) (*events.Event, error) {
+	if auction.LeaderID == 0 {
+		return nil, s.closeUnsoldTx(tx, auction)
+	}
+
+	users, err := s.userRepo.LockUsersTx(tx, []string{auction.Leader})
+	if err != nil {
+		return nil, err
+	}
+	winner := users[auction.Leader]
+	if err := s.releaseLeaderHoldTx(tx, auction, winner); err != nil {
+		return nil, err
+	}
+	if !winner.IsActive {
+		if err := s.closeUnsoldTx(tx, auction); err != nil {
+			return nil, err
+		}
+		return &events.Event{Kind: events.BalanceChanged, UserID: winner.ID, User: winner.Username}, nil
+	}
+	winner.Coins -= auction.CurrentBid
+	if err := s.userRepo.UpdateUserBalanceTx(tx, winner); err != nil {
+		return nil, fmt.Errorf("ошибка обновления баланса: %w", err)
+	}
+	if err := s.itemRepo.AddToInventory(tx, winner.ID, auction.ItemID); err != nil {
+		return nil, fmt.Errorf("ошибка добавления в инвентарь: %w", err)
+	}
+	purchase := &models.Purchase{
+		BuyerID:     winner.ID,
+		RecipientID: winner.ID,
+		ItemID:      auction.ItemID,
+		Price:       auction.CurrentBid,
+		ListPrice:   auction.CurrentBid,
+	}
+	if err := s.itemRepo.CreatePurchaseTx(tx, purchase); err != nil {
+		return nil, err
+	}
+	item := &models.Item{ID: auction.ItemID, Name: auction.Item}
+	if err := s.outboxRepo.AppendTx(tx, winner.ID, purchased(winner.Username, winner.Username, item, purchase)); err != nil {
+		return nil, err
+	}
+	notification := &models.Notification{
+		UserID:  winner.ID,
+		Kind:    models.NotificationAuctionWon,
+		Message: fmt.Sprintf("Вы выиграли аукцион №%d: %s за %d монет", auction.ID, auction.Item, auction.CurrentBid),
+	}
+	if err := s.notificationRepo.CreateNotificationTx(tx, notification); err != nil {
+		return nil, err
+	}
+
+	auction.Status = models.AuctionStatusSold
+	if err := s.auctionRepo.UpdateAuctionTx(tx, auction); err != nil {
+		return nil, err
+	}
+	return &events.Event{
+		Kind:     events.ItemPurchased,
+		UserID:   winner.ID,
+		User:     winner.Username,
+		TargetID: winner.ID,
+		Target:   winner.Username,
+		ItemID:   auction.ItemID,
+		Amount:   auction.CurrentBid,
+		At:       purchase.CreatedAt,
+	}, nil
+}
+
+// closeUnsoldTx закрывает аукцион без продажи и возвращает лот в запас
+func (s *AuctionService) closeUnsoldTx(tx *sql.Tx, auction *models.Auction) error {
+	if err := s.itemRepo.ReturnToStockTx(tx, auction.ItemID); err != nil {
+		return err
+	}
+	auction.Status = models.AuctionStatusUnsold
+	return s.auctionRepo.UpdateAuctionTx(tx, auction)
+}
+
+// releaseLeaderHoldTx возвращает лидеру аукциона зарезервированную ставку.
+// Строка leader должна быть заблокирована в транзакции tx.
+func (s *AuctionService) releaseLeaderHoldTx(tx *sql.Tx, auction *models.Auction, leader *models.User) error {
+	if leader == nil {
+		return fmt.Errorf("лидер аукциона %d не найден", auction.ID)
+	}
+	hold, err := s.escrow.LockAuctionHoldTx(tx, auction.ID, leader.ID)
+	if err != nil {
+		return err
+	}
+	if hold == nil {
+		return fmt.Errorf("нет резерва лидера аукциона %d", auction.ID)
+	}
+	return s.escrow.ReleaseTx(tx, leader, hold)
+}
@@ -0,0 +1,334 @@
+package services
+
+import (
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/itocode21/MerchServiceAvito/internal/config"
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+	"github.com/itocode21/MerchServiceAvito/internal/repositories"
+	"github.com/lib/pq"
+)
+
+var auctionColumns = []string{"id", "item_id", "name", "created_by", "starting_price", "min_increment",
+	"leader_id", "leader", "current_bid", "status", "created_at", "ends_at", "closed_at"}
+
+func newTestAuctionService(t *testing.T) (*AuctionService, sqlmock.Sqlmock, func()) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка создания мока: %v", err)
+	}
+	cfg := &config.Config{DB: db}
+	userRepo := repositories.NewUserRepository(cfg)
+	transRepo := repositories.NewTransactionRepository(db)
+	escrow := NewEscrowService(repositories.NewHoldRepository(db), userRepo, transRepo)
+	service := NewAuctionService(repositories.NewAuctionRepository(db), escrow, repositories.NewItemRepository(db),
+		userRepo, repositories.NewNotificationRepository(db), repositories.NewOutboxRepository(db))
+	return service, mock, func() { db.Close() }
+}
+
+func TestCreateAuction(t *testing.T) {
+	service, mock, done := newTestAuctionService(t)
+	defer done()
+
+	endsAt := time.Now().Add(time.Hour)
+	tests := []struct {
+		name    string
+		inStock bool
+		errMsg  string
+	}{
+		{name: "Лот списывается из запаса", inStock: true},
+		{name: "Предмет закончился", errMsg: "предмет book закончился"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock.ExpectQuery("SELECT i.id, i.name, .* FROM items i WHERE i.name = \\$1").
+				WithArgs("book").
+				WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price"}).AddRow(5, "book", 50))
+			mock.ExpectQuery("SELECT id, username, password_hash, coins, is_active FROM users WHERE username = \\$1").
+				WithArgs("admin").
+				WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password_hash", "coins", "is_active"}).AddRow(3, "admin", "hash", 0, true))
+			mock.ExpectBegin()
+			stockRows := int64(0)
+			if tt.inStock {
+				stockRows = 1
+			}
+			mock.ExpectExec("UPDATE items SET stock = stock - 1 WHERE id = \\$1").
+				WithArgs(5).
+				WillReturnResult(sqlmock.NewResult(0, stockRows))
+			if tt.inStock {
+				mock.ExpectQuery("INSERT INTO auctions").
+					WithArgs(5, 3, 50, 10, endsAt).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "status", "created_at"}).AddRow(1, "open", time.Now()))
+				mock.ExpectCommit()
+			} else {
+				mock.ExpectRollback()
+			}
+
+			_, err := service.CreateAuction("admin", "book", 50, 10, endsAt)
+			if tt.errMsg != "" {
+				if err == nil || err.Error() != tt.errMsg {
+					t.Errorf("CreateAuction() error = %v, want %q", err, tt.errMsg)
+				}
+			} else if err != nil {
+				t.Errorf("CreateAuction() error = %v, want nil", err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Не все ожидания мока выполнены: %v", err)
+			}
+		})
+	}
+}
+
+func TestPlaceBid(t *testing.T) {
+	service, mock, done := newTestAuctionService(t)
+	defer done()
+
+	auctionQuery := "SELECT a.id, .* FROM auctions a .* WHERE a.id = \\$1 FOR UPDATE OF a"
+	lockColumns := []string{"id", "username", "coins", "held_coins", "is_active"}
+	holdColumns := []string{"id", "user_id", "auction_id", "amount", "status", "created_at"}
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		amount    int
+		setupMock func()
+		wantErr   bool
+		errMsg    string
+	}{
+		{
+			name:   "Перебитому лидеру сразу возвращается резерв",
+			amount: 120,
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(auctionQuery).
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows(auctionColumns).
+						AddRow(1, 5, "book", 3, 50, 10, 2, "bob", 100, "open", now, now.Add(time.Hour), nil))
+				mock.ExpectQuery("SELECT id, username, coins, held_coins, is_active FROM users").
+					WithArgs(sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows(lockColumns).
+						AddRow(1, "alice", 1000, 0, true).
+						AddRow(2, "bob", 500, 100, true))
+				mock.ExpectQuery("SELECT id, user_id, auction_id, amount, status, created_at FROM coin_holds").
+					WithArgs(1, 2, "held").
+					WillReturnRows(sqlmock.NewRows(holdColumns).AddRow(7, 2, 1, 100, "held", now))
+				mock.ExpectExec("UPDATE users SET held_coins = \\$1 WHERE id = \\$2").
+					WithArgs(0, 2).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("UPDATE coin_holds SET status = \\$1, released_at = NOW\\(\\) WHERE id = \\$2").
+					WithArgs("released", 7).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("INSERT INTO transactions").
+					WithArgs(nil, 2, 100, "hold_release", nil).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, now))
+				mock.ExpectQuery("INSERT INTO notifications").
+					WithArgs(2, "auction_outbid", "Вашу ставку на book перебили: 120 монет").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, now))
+				mock.ExpectExec("UPDATE users SET held_coins = \\$1 WHERE id = \\$2").
+					WithArgs(120, 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("INSERT INTO coin_holds").
+					WithArgs(1, nil, 1, 120).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "status", "created_at"}).AddRow(8, "held", now))
+				mock.ExpectQuery("INSERT INTO transactions").
+					WithArgs(1, nil, 120, "hold", nil).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(2, now))
+				mock.ExpectQuery("INSERT INTO auction_bids").
+					WithArgs(1, 1, 120).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(4, now))
+				mock.ExpectQuery("UPDATE auctions SET leader_id = \\$1, current_bid = \\$2, status = \\$3").
+					WithArgs(1, 120, "open", false, 1).
+					WillReturnRows(sqlmock.NewRows([]string{"closed_at"}).AddRow(nil))
+				mock.ExpectCommit()
+			},
+			wantErr: false,
+		},
+		{
+			name:   "Ставка меньше минимального шага",
+			amount: 105,
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(auctionQuery).
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows(auctionColumns).
+						AddRow(1, 5, "book", 3, 50, 10, 2, "bob", 100, "open", now, now.Add(time.Hour), nil))
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+			errMsg:  "ставка должна быть не меньше 110",
+		},
+		{
+			name:   "Аукцион уже закончился",
+			amount: 200,
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(auctionQuery).
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows(auctionColumns).
+						AddRow(1, 5, "book", 3, 50, 10, 2, "bob", 100, "open", now.Add(-2*time.Hour), now.Add(-time.Hour), nil))
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+			errMsg:  "аукцион 1 завершён",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+			bid, err := service.PlaceBid("alice", 1, tt.amount)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("PlaceBid() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && err != nil && err.Error() != tt.errMsg {
+				t.Errorf("PlaceBid() error message = %v, want %v", err.Error(), tt.errMsg)
+			}
+			if !tt.wantErr && bid.Amount != tt.amount {
+				t.Errorf("PlaceBid() amount = %d, want %d", bid.Amount, tt.amount)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Не все ожидания мока выполнены: %v", err)
+			}
+		})
+	}
+}
+
+func TestCloseAuctions(t *testing.T) {
+	service, mock, done := newTestAuctionService(t)
+	defer done()
+
+	now := time.Now()
+	endedQuery := "SELECT a.id, .* FROM auctions a .* FOR UPDATE OF a SKIP LOCKED"
+	expectEnded := func(skipped []int, rows ...[]driver.Value) {
+		result := sqlmock.NewRows(auctionColumns)
+		for _, row := range rows {
+			result.AddRow(row...)
+		}
+		mock.ExpectQuery(endedQuery).WithArgs("open", pq.Array(skipped)).WillReturnRows(result)
+	}
+	expectNoneEnded := func(skipped []int) {
+		mock.ExpectBegin()
+		expectEnded(skipped)
+		mock.ExpectRollback()
+	}
+	expectLeader := func(active bool) {
+		mock.ExpectQuery("SELECT id, username, coins, held_coins, is_active FROM users").
+			WithArgs(sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "username", "coins", "held_coins", "is_active"}).
+				AddRow(2, "bob", 500, 100, active))
+	}
+	expectRelease := func() {
+		mock.ExpectQuery("SELECT id, user_id, auction_id, amount, status, created_at FROM coin_holds").
+			WithArgs(1, 2, "held").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "auction_id", "amount", "status", "created_at"}).
+				AddRow(7, 2, 1, 100, "held", now))
+		mock.ExpectExec("UPDATE users SET held_coins = \\$1 WHERE id = \\$2").
+			WithArgs(0, 2).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE coin_holds SET status = \\$1, released_at = NOW\\(\\) WHERE id = \\$2").
+			WithArgs("released", 7).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("INSERT INTO transactions").
+			WithArgs(nil, 2, 100, "hold_release", nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, now))
+	}
+	expectUnsold := func(auctionID, itemID int) {
+		mock.ExpectExec("UPDATE items SET stock = stock \\+ 1 WHERE id = \\$1 AND stock IS NOT NULL").
+			WithArgs(itemID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("UPDATE auctions SET leader_id = \\$1").
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "unsold", true, auctionID).
+			WillReturnRows(sqlmock.NewRows([]string{"closed_at"}).AddRow(now))
+	}
+	sold := []driver.Value{1, 5, "book", 3, 50, 10, 2, "bob", 100, "open", now, now, nil}
+	noBids := []driver.Value{2, 6, "mug", 3, 50, 10, 0, "", 0, "open", now, now, nil}
+
+	tests := []struct {
+		name       string
+		setupMock  func()
+		wantClosed int
+		wantErr    bool
+	}{
+		{
+			name: "Лидер оплачивает лот, лот без ставок возвращается в запас",
+			setupMock: func() {
+				mock.ExpectBegin()
+				expectEnded([]int{}, sold)
+				expectLeader(true)
+				expectRelease()
+				mock.ExpectExec("UPDATE users SET coins = \\$1 WHERE id = \\$2").
+					WithArgs(400, 2).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("INSERT INTO inventory").
+					WithArgs(2, 5).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("INSERT INTO purchases").
+					WithArgs(2, 2, 5, 100, 100, 0, "", "").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, now))
+				mock.ExpectExec("INSERT INTO outbox_events").WithArgs(models.EventItemPurchased, 2, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectQuery("INSERT INTO notifications").
+					WithArgs(2, "auction_won", "Вы выиграли аукцион №1: book за 100 монет").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, now))
+				mock.ExpectQuery("UPDATE auctions SET leader_id = \\$1").
+					WithArgs(2, 100, "sold", true, 1).
+					WillReturnRows(sqlmock.NewRows([]string{"closed_at"}).AddRow(now))
+				mock.ExpectCommit()
+				mock.ExpectBegin()
+				expectEnded([]int{}, noBids)
+				expectUnsold(2, 6)
+				mock.ExpectCommit()
+				expectNoneEnded([]int{})
+			},
+			wantClosed: 2,
+		},
+		{
+			name: "Деактивированный лидер не покупает лот",
+			setupMock: func() {
+				mock.ExpectBegin()
+				expectEnded([]int{}, sold)
+				expectLeader(false)
+				expectRelease()
+				expectUnsold(1, 5)
+				mock.ExpectCommit()
+				expectNoneEnded([]int{})
+			},
+			wantClosed: 1,
+		},
+		{
+			name: "Сбой одного аукциона не останавливает остальные",
+			setupMock: func() {
+				mock.ExpectBegin()
+				expectEnded([]int{}, sold)
+				expectLeader(true)
+				mock.ExpectQuery("SELECT id, user_id, auction_id, amount, status, created_at FROM coin_holds").
+					WithArgs(1, 2, "held").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "auction_id", "amount", "status", "created_at"}))
+				mock.ExpectRollback()
+				mock.ExpectBegin()
+				expectEnded([]int{1}, noBids)
+				expectUnsold(2, 6)
+				mock.ExpectCommit()
+				expectNoneEnded([]int{1})
+			},
+			wantClosed: 1,
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+			closed, err := service.CloseAuctions()
+			if (err != nil) != tt.wantErr || closed != tt.wantClosed {
+				t.Errorf("CloseAuctions() = %d, %v, want %d, wantErr %v", closed, err, tt.wantClosed, tt.wantErr)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Не все ожидания мока выполнены: %v", err)
+			}
+		})
+	}
+}
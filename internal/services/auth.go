@@ -1,7 +1,9 @@
 package services
 
 import (
-	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"log"
 	"sync"
@@ -12,6 +14,8 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+const passwordResetTTL = time.Hour
+
 type AuthService struct {
 	userRepo *repositories.UserRepository
 }
@@ -20,15 +24,9 @@ func NewAuthService(userRepo *repositories.UserRepository) *AuthService {
 	return &AuthService{userRepo: userRepo}
 }
 
+// Authenticate выдаёт токен по паролю. Пользователь всегда читается из базы: хеш из
+// кэша не говорит, не деактивирована ли учётная запись после того, как он туда попал.
 func (s *AuthService) Authenticate(username, password string) (string, error) {
-	cacheKey := "user_hash:" + username
-	cachedHash, err := s.userRepo.Config.Redis.Get(context.Background(), cacheKey).Result()
-	if err == nil {
-		if err := bcrypt.CompareHashAndPassword([]byte(cachedHash), []byte(password)); err == nil {
-			return auth.GenerateJWT(username)
-		}
-	}
-
 	user, err := s.userRepo.GetUserByUsername(username)
 	if err != nil {
 		return "", fmt.Errorf("ошибка при получении пользователя: %v", err)
@@ -36,6 +34,9 @@ func (s *AuthService) Authenticate(username, password string) (string, error) {
 	if user == nil {
 		return "", fmt.Errorf("пользователь не найден")
 	}
+	if !user.IsActive {
+		return "", fmt.Errorf("учётная запись деактивирована")
+	}
 
 	var wg sync.WaitGroup
 	var hashErr error
@@ -54,11 +55,125 @@ func (s *AuthService) Authenticate(username, password string) (string, error) {
 		return "", fmt.Errorf("неверный пароль")
 	}
 
-	s.userRepo.Config.Redis.Set(context.Background(), cacheKey, user.PasswordHash, 5*time.Minute)
-
 	token, err := auth.GenerateJWT(user.Username)
 	if err != nil {
 		return "", fmt.Errorf("ошибка генерации токена: %v", err)
 	}
 	return token, nil
 }
+
+// ChangePassword меняет пароль пользователя, отзывает все выданные ранее токены
+// и возвращает новый токен для текущей сессии.
+func (s *AuthService) ChangePassword(username, oldPassword, newPassword string) (string, error) {
+	if newPassword == "" {
+		return "", fmt.Errorf("новый пароль не может быть пустым")
+	}
+
+	user, err := s.userRepo.GetUserByUsername(username)
+	if err != nil {
+		return "", fmt.Errorf("ошибка при получении пользователя: %v", err)
+	}
+	if user == nil {
+		return "", fmt.Errorf("пользователь не найден")
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(oldPassword)); err != nil {
+		return "", fmt.Errorf("неверный пароль")
+	}
+
+	if err := s.setPassword(user.ID, username, newPassword, nil); err != nil {
+		return "", err
+	}
+
+	token, err := auth.GenerateJWT(username)
+	if err != nil {
+		return "", fmt.Errorf("ошибка генерации токена: %v", err)
+	}
+	return token, nil
+}
+
+// IssuePasswordReset выдаёт одноразовый токен сброса пароля. В базе хранится только его хеш.
+func (s *AuthService) IssuePasswordReset(username string) (string, time.Time, error) {
+	user, err := s.userRepo.GetUserByUsername(username)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("ошибка при получении пользователя: %v", err)
+	}
+	if user == nil {
+		return "", time.Time{}, fmt.Errorf("пользователь не найден")
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", time.Time{}, fmt.Errorf("ошибка генерации токена сброса: %v", err)
+	}
+	token := hex.EncodeToString(buf)
+	expiresAt := time.Now().Add(passwordResetTTL)
+
+	tx, err := s.userRepo.DB.Begin()
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := s.userRepo.CreatePasswordResetTokenTx(tx, user.ID, hashToken(token), expiresAt); err != nil {
+		return "", time.Time{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return "", time.Time{}, fmt.Errorf("ошибка фиксации транзакции: %v", err)
+	}
+	return token, expiresAt, nil
+}
+
+// ResetPassword устанавливает новый пароль по одноразовому токену сброса.
+func (s *AuthService) ResetPassword(token, newPassword string) error {
+	if token == "" {
+		return fmt.Errorf("токен сброса не указан")
+	}
+	if newPassword == "" {
+		return fmt.Errorf("новый пароль не может быть пустым")
+	}
+	return s.setPassword(0, "", newPassword, &token)
+}
+
+// setPassword сохраняет новый хеш пароля. Если передан resetToken, пользователь
+// определяется по нему, а сам токен помечается использованным в той же транзакции.
+func (s *AuthService) setPassword(userID int, username, newPassword string, resetToken *string) error {
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("ошибка хеширования пароля: %v", err)
+	}
+
+	tx, err := s.userRepo.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	if resetToken != nil {
+		userID, username, err = s.userRepo.UsePasswordResetTokenTx(tx, hashToken(*resetToken))
+		if err != nil {
+			return err
+		}
+		if userID == 0 {
+			return fmt.Errorf("токен сброса недействителен или истёк")
+		}
+	}
+
+	if err := s.userRepo.UpdatePasswordHashTx(tx, userID, string(passwordHash)); err != nil {
+		return err
+	}
+
+	// Отзываем сессии до фиксации: если Redis недоступен, пароль не меняется
+	if err := auth.RevokeTokens(username); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка фиксации транзакции: %v", err)
+	}
+	return nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
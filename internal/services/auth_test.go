@@ -50,10 +50,10 @@ func TestAuthenticate(t *testing.T) {
 			username: "user1",
 			password: "12345",
 			setupMock: func() {
-				mock.ExpectQuery("SELECT id, username, password_hash, coins FROM users WHERE username = \\$1").
+				mock.ExpectQuery("SELECT id, username, password_hash, coins, is_active FROM users WHERE username = \\$1").
 					WithArgs("user1").
-					WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password_hash", "coins"}).
-						AddRow(1, "user1", string(hashedPassword), 1000))
+					WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password_hash", "coins", "is_active"}).
+						AddRow(1, "user1", string(hashedPassword), 1000, true))
 			},
 			wantErr: false,
 		},
@@ -62,22 +62,35 @@ func TestAuthenticate(t *testing.T) {
 			username: "user1",
 			password: "wrongpass",
 			setupMock: func() {
-				mock.ExpectQuery("SELECT id, username, password_hash, coins FROM users WHERE username = \\$1").
+				mock.ExpectQuery("SELECT id, username, password_hash, coins, is_active FROM users WHERE username = \\$1").
 					WithArgs("user1").
-					WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password_hash", "coins"}).
-						AddRow(1, "user1", string(hashedPassword), 1000))
+					WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password_hash", "coins", "is_active"}).
+						AddRow(1, "user1", string(hashedPassword), 1000, true))
 			},
 			wantErr: true,
 			errMsg:  "неверный пароль",
 		},
+		{
+			name:     "Учётная запись деактивирована",
+			username: "leaver",
+			password: "12345",
+			setupMock: func() {
+				mock.ExpectQuery("SELECT id, username, password_hash, coins, is_active FROM users WHERE username = \\$1").
+					WithArgs("leaver").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password_hash", "coins", "is_active"}).
+						AddRow(2, "leaver", string(hashedPassword), 0, false))
+			},
+			wantErr: true,
+			errMsg:  "учётная запись деактивирована",
+		},
 		{
 			name:     "Пользователь не найден",
 			username: "unknown",
 			password: "12345",
 			setupMock: func() {
-				mock.ExpectQuery("SELECT id, username, password_hash, coins FROM users WHERE username = \\$1").
+				mock.ExpectQuery("SELECT id, username, password_hash, coins, is_active FROM users WHERE username = \\$1").
 					WithArgs("unknown").
-					WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password_hash", "coins"}))
+					WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password_hash", "coins", "is_active"}))
 			},
 			wantErr: true,
 			errMsg:  "пользователь не найден",
@@ -106,3 +119,170 @@ func TestAuthenticate(t *testing.T) {
 		})
 	}
 }
+
+func TestChangePassword(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка создания мока: %v", err)
+	}
+	defer db.Close()
+
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+	})
+
+	cfg := &config.Config{
+		DB:        db,
+		JWTSecret: []byte("test_secret_key"),
+		Redis:     redisClient,
+	}
+
+	userRepo := repositories.NewUserRepository(cfg)
+	service := NewAuthService(userRepo)
+
+	auth.SetJWTSecret([]byte("test_secret_key"))
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("12345"), bcrypt.DefaultCost)
+
+	tests := []struct {
+		name        string
+		oldPassword string
+		newPassword string
+		setupMock   func()
+		wantErr     bool
+		errMsg      string
+	}{
+		{
+			name:        "Успешная смена пароля",
+			oldPassword: "12345",
+			newPassword: "54321",
+			setupMock: func() {
+				mock.ExpectQuery("SELECT id, username, password_hash, coins, is_active FROM users WHERE username = \\$1").
+					WithArgs("user1").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password_hash", "coins", "is_active"}).
+						AddRow(1, "user1", string(hashedPassword), 1000, true))
+				mock.ExpectBegin()
+				mock.ExpectExec("UPDATE users SET password_hash = \\$1 WHERE id = \\$2").
+					WithArgs(sqlmock.AnyArg(), 1).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+			},
+			wantErr: false,
+		},
+		{
+			name:        "Неверный старый пароль",
+			oldPassword: "wrongpass",
+			newPassword: "54321",
+			setupMock: func() {
+				mock.ExpectQuery("SELECT id, username, password_hash, coins, is_active FROM users WHERE username = \\$1").
+					WithArgs("user1").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password_hash", "coins", "is_active"}).
+						AddRow(1, "user1", string(hashedPassword), 1000, true))
+			},
+			wantErr: true,
+			errMsg:  "неверный пароль",
+		},
+		{
+			name:        "Пустой новый пароль",
+			oldPassword: "12345",
+			newPassword: "",
+			setupMock:   func() {},
+			wantErr:     true,
+			errMsg:      "новый пароль не может быть пустым",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+			token, err := service.ChangePassword("user1", tt.oldPassword, tt.newPassword)
+			if tt.wantErr {
+				if err == nil || err.Error() != tt.errMsg {
+					t.Errorf("ChangePassword() error = %v, want %q", err, tt.errMsg)
+				}
+			} else if err != nil {
+				t.Errorf("ChangePassword() error = %v, want nil", err)
+			} else if token == "" {
+				t.Errorf("ChangePassword() token is empty, want non-empty")
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Не все ожидания мока выполнены: %v", err)
+			}
+		})
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка создания мока: %v", err)
+	}
+	defer db.Close()
+
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+	})
+
+	cfg := &config.Config{
+		DB:        db,
+		JWTSecret: []byte("test_secret_key"),
+		Redis:     redisClient,
+	}
+
+	userRepo := repositories.NewUserRepository(cfg)
+	service := NewAuthService(userRepo)
+
+	tests := []struct {
+		name      string
+		token     string
+		setupMock func()
+		wantErr   bool
+		errMsg    string
+	}{
+		{
+			name:  "Успешный сброс",
+			token: "valid-token",
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery("UPDATE password_reset_tokens t SET used_at = NOW\\(\\)").
+					WithArgs(hashToken("valid-token")).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "username"}).AddRow(1, "user1"))
+				mock.ExpectExec("UPDATE users SET password_hash = \\$1 WHERE id = \\$2").
+					WithArgs(sqlmock.AnyArg(), 1).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+			},
+			wantErr: false,
+		},
+		{
+			name:  "Токен истёк или использован",
+			token: "used-token",
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery("UPDATE password_reset_tokens t SET used_at = NOW\\(\\)").
+					WithArgs(hashToken("used-token")).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "username"}))
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+			errMsg:  "токен сброса недействителен или истёк",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+			err := service.ResetPassword(tt.token, "54321")
+			if tt.wantErr {
+				if err == nil || err.Error() != tt.errMsg {
+					t.Errorf("ResetPassword() error = %v, want %q", err, tt.errMsg)
+				}
+			} else if err != nil {
+				t.Errorf("ResetPassword() error = %v, want nil", err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Не все ожидания мока выполнены: %v", err)
+			}
+		})
+	}
+}
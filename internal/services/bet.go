@@ -0,0 +1,338 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+	"github.com/itocode21/MerchServiceAvito/internal/repositories"
+)
+
+const (
+	defaultBetTTL     = 7 * 24 * time.Hour
+	maxBetTTL         = 90 * 24 * time.Hour
+	maxBetDescription = 255
+	betExpiryBatch    = 100
+)
+
+type BetService struct {
+	betRepo    *repositories.BetRepository
+	escrow     *EscrowService
+	userRepo   *repositories.UserRepository
+	transRepo  *repositories.TransactionRepository
+	outboxRepo *repositories.OutboxRepository
+	db         *sql.DB
+}
+
+func NewBetService(betRepo *repositories.BetRepository, escrow *EscrowService, userRepo *repositories.UserRepository, transRepo *repositories.TransactionRepository, outboxRepo *repositories.OutboxRepository) *BetService {
+	return &BetService{
+		betRepo:    betRepo,
+		escrow:     escrow,
+		userRepo:   userRepo,
+		transRepo:  transRepo,
+		outboxRepo: outboxRepo,
+		db:         userRepo.DB,
+	}
+}
+
+// CreateBet предлагает пари: ставка автора сразу резервируется, ставка соперника —
+// когда он примет пари. Нулевой expiresAt означает срок по умолчанию.
+func (s *BetService) CreateBet(creator, opponent, arbiter string, stake int, description string, expiresAt time.Time) (*models.Bet, error) {
+	if creator == opponent {
+		return nil, userErrorf("нельзя заключить пари с самим собой")
+	}
+	if arbiter == creator || arbiter == opponent {
+		return nil, userErrorf("арбитр не может быть участником пари")
+	}
+	if stake <= 0 {
+		return nil, userErrorf("ставка должна быть положительной")
+	}
+	if stake > maxAmount {
+		return nil, userErrorf("ставка %d слишком велика", stake)
+	}
+	description = strings.TrimSpace(description)
+	if description == "" {
+		return nil, userErrorf("не указан предмет пари")
+	}
+	if utf8.RuneCountInString(description) > maxBetDescription {
+		return nil, userErrorf("описание пари длиннее %d символов", maxBetDescription)
+	}
+	now := time.Now()
+	if expiresAt.IsZero() {
+		expiresAt = now.Add(defaultBetTTL)
+	}
+	if !expiresAt.After(now) || expiresAt.After(now.Add(maxBetTTL)) {
+		return nil, userErrorf("срок пари должен быть в пределах %d дней", int(maxBetTTL.Hours()/24))
+	}
+
+	bet := &models.Bet{
+		Creator:     creator,
+		Opponent:    opponent,
+		Arbiter:     arbiter,
+		Stake:       stake,
+		Description: description,
+		ExpiresAt:   expiresAt,
+	}
+	err := withTxRetry(func() error {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return fmt.Errorf("ошибка начала транзакции: %w", err)
+		}
+		defer tx.Rollback()
+
+		users, err := s.userRepo.LockUsersTx(tx, []string{creator, opponent, arbiter})
+		if err != nil {
+			return err
+		}
+		for _, username := range []string{creator, opponent, arbiter} {
+			user := users[username]
+			if user == nil {
+				return userErrorf("пользователь %s не найден", username)
+			}
+			if !user.IsActive {
+				return userErrorf("пользователь %s деактивирован", username)
+			}
+		}
+
+		bet.CreatorID = users[creator].ID
+		bet.OpponentID = users[opponent].ID
+		bet.ArbiterID = users[arbiter].ID
+		if err := s.betRepo.CreateBetTx(tx, bet); err != nil {
+			return err
+		}
+		if err := s.escrow.HoldTx(tx, users[creator], &models.CoinHold{BetID: bet.ID, Amount: stake}); err != nil {
+			return err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("ошибка фиксации транзакции: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	publishBalanceChanged(s.userRepo.Config.Events, creator)
+	return bet, nil
+}
+
+// AcceptBet резервирует ставку соперника и делает пари действующим
+func (s *BetService) AcceptBet(username string, id int) (*models.Bet, error) {
+	return s.updateBet(id, func(tx *sql.Tx, bet *models.Bet) error {
+		if bet.Opponent != username {
+			return userErrorf("пари %d не найдено", id)
+		}
+		if bet.Status != models.BetStatusOpen {
+			return userErrorf("пари %d уже не ожидает ответа: %s", id, bet.Status)
+		}
+		users, err := s.userRepo.LockUsersTx(tx, []string{username})
+		if err != nil {
+			return err
+		}
+		if users[username] == nil {
+			return userErrorf("пользователь %s не найден", username)
+		}
+		if err := s.escrow.HoldTx(tx, users[username], &models.CoinHold{BetID: bet.ID, Amount: bet.Stake}); err != nil {
+			return err
+		}
+		bet.Status = models.BetStatusActive
+		return nil
+	})
+}
+
+// DeclineBet отменяет ещё не принятое пари; отказаться может соперник, отозвать — автор
+func (s *BetService) DeclineBet(username string, id int) (*models.Bet, error) {
+	return s.updateBet(id, func(tx *sql.Tx, bet *models.Bet) error {
+		if bet.Opponent != username && bet.Creator != username {
+			return userErrorf("пари %d не найдено", id)
+		}
+		if bet.Status != models.BetStatusOpen {
+			return userErrorf("пари %d уже не ожидает ответа: %s", id, bet.Status)
+		}
+		if _, err := s.releaseBetHoldsTx(tx, bet); err != nil {
+			return err
+		}
+		bet.Status = models.BetStatusDeclined
+		return nil
+	})
+}
+
+// SettleBet снимает резервы обоих участников и переводит ставку проигравшего победителю
+func (s *BetService) SettleBet(arbiter string, id int, winner string) (*models.Bet, error) {
+	return s.updateBet(id, func(tx *sql.Tx, bet *models.Bet) error {
+		if bet.Arbiter != arbiter {
+			return userErrorf("пари %d не найдено", id)
+		}
+		if bet.Status != models.BetStatusActive {
+			return userErrorf("пари %d не действует: %s", id, bet.Status)
+		}
+		loser := bet.Opponent
+		switch winner {
+		case bet.Creator:
+		case bet.Opponent:
+			loser = bet.Creator
+		default:
+			return userErrorf("победителем может быть только %s или %s", bet.Creator, bet.Opponent)
+		}
+
+		users, err := s.releaseBetHoldsTx(tx, bet)
+		if err != nil {
+			return err
+		}
+		winnerUser, loserUser := users[winner], users[loser]
+		if winnerUser.Coins > maxAmount-bet.Stake {
+			return userErrorf("баланс победителя %s превысит допустимый максимум", winner)
+		}
+		loserUser.Coins -= bet.Stake
+		winnerUser.Coins += bet.Stake
+		if err := s.userRepo.UpdateUserBalanceTx(tx, loserUser); err != nil {
+			return fmt.Errorf("ошибка обновления баланса проигравшего: %w", err)
+		}
+		if err := s.userRepo.UpdateUserBalanceTx(tx, winnerUser); err != nil {
+			return fmt.Errorf("ошибка обновления баланса победителя: %w", err)
+		}
+		transaction := &models.Transaction{
+			FromUserID: loserUser.ID,
+			ToUserID:   winnerUser.ID,
+			Amount:     bet.Stake,
+			Kind:       models.TransactionKindBetPayout,
+		}
+		if err := s.transRepo.CreateTransaction(tx, transaction); err != nil {
+			return fmt.Errorf("ошибка записи транзакции: %w", err)
+		}
+		if err := s.outboxRepo.AppendTx(tx, loserUser.ID, transferred(loser, winner, transaction)); err != nil {
+			return err
+		}
+
+		bet.Status = models.BetStatusSettled
+		bet.WinnerID = winnerUser.ID
+		bet.Winner = winner
+		return nil
+	})
+}
+
+// ExpireBets возвращает ставки по просроченным пари и помечает их refunded.
+// Возвращает число обработанных пари.
+func (s *BetService) ExpireBets() (int, error) {
+	var expired int
+	var participants []string
+	err := withTxRetry(func() error {
+		participants = nil
+		tx, err := s.db.Begin()
+		if err != nil {
+			return fmt.Errorf("ошибка начала транзакции: %w", err)
+		}
+		defer tx.Rollback()
+
+		bets, err := s.betRepo.LockExpiredBetsTx(tx, betExpiryBatch)
+		if err != nil {
+			return err
+		}
+		for _, bet := range bets {
+			if _, err := s.releaseBetHoldsTx(tx, bet); err != nil {
+				return err
+			}
+			bet.Status = models.BetStatusRefunded
+			if err := s.betRepo.UpdateBetTx(tx, bet); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("ошибка фиксации транзакции: %w", err)
+		}
+		expired = len(bets)
+		for _, bet := range bets {
+			participants = append(participants, bet.Creator, bet.Opponent)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	publishBalanceChanged(s.userRepo.Config.Events, participants...)
+	return expired, nil
+}
+
+func (s *BetService) GetBets(username string) ([]models.Bet, error) {
+	user, err := s.userRepo.GetUserByUsername(username)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении пользователя: %w", err)
+	}
+	if user == nil {
+		return nil, userErrorf("пользователь %s не найден", username)
+	}
+	return s.betRepo.GetUserBets(user.ID)
+}
+
+// updateBet блокирует пари, применяет к нему apply и сохраняет новый статус.
+// Просроченное пари изменить нельзя: его ставки вернёт ExpireBets. Участникам
+// после фиксации сообщается новый баланс.
+func (s *BetService) updateBet(id int, apply func(*sql.Tx, *models.Bet) error) (*models.Bet, error) {
+	var bet *models.Bet
+	err := withTxRetry(func() error {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return fmt.Errorf("ошибка начала транзакции: %w", err)
+		}
+		defer tx.Rollback()
+
+		bet, err = s.betRepo.LockBetTx(tx, id)
+		if err != nil {
+			return err
+		}
+		if bet == nil {
+			return userErrorf("пари %d не найдено", id)
+		}
+		if !time.Now().Before(bet.ExpiresAt) && (bet.Status == models.BetStatusOpen || bet.Status == models.BetStatusActive) {
+			return userErrorf("пари %d истекло", id)
+		}
+		if err := apply(tx, bet); err != nil {
+			return err
+		}
+		if err := s.betRepo.UpdateBetTx(tx, bet); err != nil {
+			return err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("ошибка фиксации транзакции: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	publishBalanceChanged(s.userRepo.Config.Events, bet.Creator, bet.Opponent)
+	return bet, nil
+}
+
+// releaseBetHoldsTx блокирует участников пари и снимает все действующие резервы по нему.
+// Возвращает заблокированных участников по имени.
+func (s *BetService) releaseBetHoldsTx(tx *sql.Tx, bet *models.Bet) (map[string]*models.User, error) {
+	users, err := s.userRepo.LockUsersTx(tx, []string{bet.Creator, bet.Opponent})
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int]*models.User, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+
+	holds, err := s.escrow.LockBetHoldsTx(tx, bet.ID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range holds {
+		user := byID[holds[i].UserID]
+		if user == nil {
+			return nil, fmt.Errorf("резерв %d принадлежит не участнику пари %d", holds[i].ID, bet.ID)
+		}
+		if err := s.escrow.ReleaseTx(tx, user, &holds[i]); err != nil {
+			return nil, err
+		}
+	}
+	return users, nil
+}
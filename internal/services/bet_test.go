@@ -0,0 +1,182 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/itocode21/MerchServiceAvito/internal/config"
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+	"github.com/itocode21/MerchServiceAvito/internal/repositories"
+)
+
+func TestSettleBet(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка создания мока: %v", err)
+	}
+	defer db.Close()
+
+	cfg := &config.Config{DB: db}
+	userRepo := repositories.NewUserRepository(cfg)
+	transRepo := repositories.NewTransactionRepository(db)
+	escrow := NewEscrowService(repositories.NewHoldRepository(db), userRepo, transRepo)
+	service := NewBetService(repositories.NewBetRepository(db), escrow, userRepo, transRepo, repositories.NewOutboxRepository(db))
+
+	betQuery := "SELECT b.id, .* FROM bets b .* WHERE b.id = \\$1 FOR UPDATE OF b"
+	betColumns := []string{"id", "creator_id", "opponent_id", "arbiter_id", "winner_id", "creator", "opponent", "arbiter",
+		"winner", "stake", "description", "status", "created_at", "expires_at", "resolved_at"}
+	lockColumns := []string{"id", "username", "coins", "held_coins", "is_active"}
+	holdColumns := []string{"id", "user_id", "bet_id", "amount", "status", "created_at"}
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		arbiter   string
+		winner    string
+		setupMock func()
+		wantErr   bool
+		errMsg    string
+	}{
+		{
+			name:    "Победитель получает ставку проигравшего",
+			arbiter: "referee",
+			winner:  "bob",
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(betQuery).
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows(betColumns).
+						AddRow(1, 1, 2, 3, 0, "alice", "bob", "referee", "", 100, "финал", "active", now, now.Add(time.Hour), nil))
+				mock.ExpectQuery("SELECT id, username, coins, held_coins, is_active FROM users").
+					WithArgs(sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows(lockColumns).
+						AddRow(1, "alice", 1000, 100, true).
+						AddRow(2, "bob", 500, 100, true))
+				mock.ExpectQuery("SELECT id, user_id, COALESCE\\(bet_id, 0\\), amount, status, created_at FROM coin_holds").
+					WithArgs(1, "held").
+					WillReturnRows(sqlmock.NewRows(holdColumns).
+						AddRow(10, 1, 1, 100, "held", now).
+						AddRow(11, 2, 1, 100, "held", now))
+				for _, userID := range []int{1, 2} {
+					mock.ExpectExec("UPDATE users SET held_coins = \\$1 WHERE id = \\$2").
+						WithArgs(0, userID).
+						WillReturnResult(sqlmock.NewResult(0, 1))
+					mock.ExpectExec("UPDATE coin_holds SET status = \\$1, released_at = NOW\\(\\) WHERE id = \\$2").
+						WithArgs("released", 9+userID).
+						WillReturnResult(sqlmock.NewResult(0, 1))
+					mock.ExpectQuery("INSERT INTO transactions").
+						WithArgs(nil, userID, 100, "hold_release", nil).
+						WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(userID, now))
+				}
+				mock.ExpectExec("UPDATE users SET coins = \\$1 WHERE id = \\$2").
+					WithArgs(900, 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("UPDATE users SET coins = \\$1 WHERE id = \\$2").
+					WithArgs(600, 2).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("INSERT INTO transactions").
+					WithArgs(1, 2, 100, "bet_payout", nil).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(3, now))
+				mock.ExpectExec("INSERT INTO outbox_events").WithArgs(models.EventCoinsTransferred, 1, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectQuery("UPDATE bets SET status = \\$1, winner_id = \\$2").
+					WithArgs("settled", 2, true, 1).
+					WillReturnRows(sqlmock.NewRows([]string{"resolved_at"}).AddRow(now))
+				mock.ExpectCommit()
+			},
+			wantErr: false,
+		},
+		{
+			name:    "Победитель не участвует в пари",
+			arbiter: "referee",
+			winner:  "referee",
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(betQuery).
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows(betColumns).
+						AddRow(1, 1, 2, 3, 0, "alice", "bob", "referee", "", 100, "финал", "active", now, now.Add(time.Hour), nil))
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+			errMsg:  "победителем может быть только alice или bob",
+		},
+		{
+			name:    "Пари ещё не принято",
+			arbiter: "referee",
+			winner:  "alice",
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(betQuery).
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows(betColumns).
+						AddRow(1, 1, 2, 3, 0, "alice", "bob", "referee", "", 100, "финал", "open", now, now.Add(time.Hour), nil))
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+			errMsg:  "пари 1 не действует: open",
+		},
+		{
+			name:    "Пари истекло",
+			arbiter: "referee",
+			winner:  "alice",
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(betQuery).
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows(betColumns).
+						AddRow(1, 1, 2, 3, 0, "alice", "bob", "referee", "", 100, "финал", "active", now.Add(-2*time.Hour), now.Add(-time.Hour), nil))
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+			errMsg:  "пари 1 истекло",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+			bet, err := service.SettleBet(tt.arbiter, 1, tt.winner)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SettleBet() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && err != nil && err.Error() != tt.errMsg {
+				t.Errorf("SettleBet() error message = %v, want %v", err.Error(), tt.errMsg)
+			}
+			if !tt.wantErr && (bet.Status != "settled" || bet.Winner != tt.winner) {
+				t.Errorf("SettleBet() = %+v, want settled with winner %s", bet, tt.winner)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Не все ожидания мока выполнены: %v", err)
+			}
+		})
+	}
+}
+
+func TestSendCoinsRespectsHeldCoins(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка создания мока: %v", err)
+	}
+	defer db.Close()
+
+	cfg := &config.Config{DB: db}
+	userRepo := repositories.NewUserRepository(cfg)
+	service := NewTransactionService(userRepo, repositories.NewTransactionRepository(db), repositories.NewOutboxRepository(db), repositories.NewNotificationRepository(db))
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, username, coins, held_coins, is_active FROM users").
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "coins", "held_coins", "is_active"}).
+			AddRow(1, "alice", 1000, 950, true).
+			AddRow(2, "bob", 500, 0, true))
+	mock.ExpectRollback()
+
+	err = service.SendCoins("alice", "bob", 100)
+	if err == nil || err.Error() != "недостаточно монет у alice: 50 < 100" {
+		t.Errorf("SendCoins() error = %v, want insufficient spendable coins", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Не все ожидания мока выполнены: %v", err)
+	}
+}
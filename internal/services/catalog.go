@@ -0,0 +1,180 @@
+package services
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+)
+
+const (
+	catalogDefaultLimit  = 50
+	catalogMaxLimit      = 100
+	maxCatalogQuery      = 255
+	maxItemDescription   = 2000
+	maxItemTags          = 10
+	maxItemImages        = 5
+	maxImageURL          = 1024
+	maxCategoryName      = 128
+	maxCatalogSortOrder  = 1000000
+	catalogSearchMinText = 2
+)
+
+// Слаги категорий и теги: строчные латинские буквы, цифры и дефис
+var (
+	categorySlugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,63}$`)
+	itemTagPattern      = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+)
+
+// ListItems возвращает предметы каталога, подходящие под фильтр
+func (s *ItemService) ListItems(f models.CatalogFilter) ([]models.Item, error) {
+	f.Query = strings.TrimSpace(f.Query)
+	if f.Query != "" && utf8.RuneCountInString(f.Query) < catalogSearchMinText {
+		return nil, userErrorf("запрос должен содержать не менее %d символов", catalogSearchMinText)
+	}
+	if utf8.RuneCountInString(f.Query) > maxCatalogQuery {
+		return nil, userErrorf("запрос слишком длинный")
+	}
+	tags, err := normalizeTags(f.Tags)
+	if err != nil {
+		return nil, err
+	}
+	f.Tags = tags
+	if f.MinPrice < 0 || f.MaxPrice < 0 || (f.MaxPrice > 0 && f.MinPrice > f.MaxPrice) {
+		return nil, userErrorf("неверный диапазон цен")
+	}
+	if f.Limit <= 0 {
+		f.Limit = catalogDefaultLimit
+	}
+	if f.Limit > catalogMaxLimit {
+		f.Limit = catalogMaxLimit
+	}
+	if f.Offset < 0 {
+		f.Offset = 0
+	}
+	return s.itemRepo.ListItems(f)
+}
+
+func (s *ItemService) GetItem(name string) (*models.Item, error) {
+	item, err := s.itemRepo.GetCatalogItem(name)
+	if err != nil {
+		return nil, err
+	}
+	if item == nil {
+		return nil, userErrorf("предмет %s не найден", name)
+	}
+	return item, nil
+}
+
+func (s *ItemService) GetCategories() ([]models.Category, error) {
+	return s.itemRepo.GetCategories()
+}
+
+// SaveCategory создаёт категорию или переименовывает существующую
+func (s *ItemService) SaveCategory(c *models.Category) error {
+	c.Slug = strings.TrimSpace(c.Slug)
+	if !categorySlugPattern.MatchString(c.Slug) {
+		return userErrorf("код категории может содержать только строчные латинские буквы, цифры и дефис (до 64 символов)")
+	}
+	c.Name = strings.TrimSpace(c.Name)
+	if c.Name == "" || utf8.RuneCountInString(c.Name) > maxCategoryName {
+		return userErrorf("название категории должно быть от 1 до %d символов", maxCategoryName)
+	}
+	if c.SortOrder < -maxCatalogSortOrder || c.SortOrder > maxCatalogSortOrder {
+		return userErrorf("порядок сортировки должен быть в пределах ±%d", maxCatalogSortOrder)
+	}
+	return s.itemRepo.SaveCategory(c)
+}
+
+// validateItemUpdate проверяет и нормализует изменения предмета
+func (s *ItemService) validateItemUpdate(upd *models.ItemUpdate) error {
+	if upd.Price < 0 || upd.Price > maxAmount {
+		return userErrorf("цена должна быть от 1 до %d", maxAmount)
+	}
+	if upd.Stock != nil && *upd.Stock < 0 {
+		return userErrorf("запас не может быть отрицательным")
+	}
+	if upd.Stock != nil && upd.UnlimitedStock {
+		return userErrorf("укажите либо запас, либо неограниченный запас")
+	}
+	if upd.Category != nil {
+		category := strings.TrimSpace(*upd.Category)
+		if category != "" {
+			exists, err := s.itemRepo.CategoryExists(category)
+			if err != nil {
+				return err
+			}
+			if !exists {
+				return userErrorf("категория %s не найдена", category)
+			}
+		}
+		upd.Category = &category
+	}
+	if upd.Description != nil {
+		description := strings.TrimSpace(*upd.Description)
+		if utf8.RuneCountInString(description) > maxItemDescription {
+			return userErrorf("описание длиннее %d символов", maxItemDescription)
+		}
+		upd.Description = &description
+	}
+	if upd.Tags != nil {
+		if len(upd.Tags) > maxItemTags {
+			return userErrorf("у предмета может быть не больше %d тегов", maxItemTags)
+		}
+		tags, err := normalizeTags(upd.Tags)
+		if err != nil {
+			return err
+		}
+		upd.Tags = tags
+	}
+	if upd.ImageURLs != nil {
+		if len(upd.ImageURLs) > maxItemImages {
+			return userErrorf("у предмета может быть не больше %d изображений", maxItemImages)
+		}
+		for i, raw := range upd.ImageURLs {
+			raw = strings.TrimSpace(raw)
+			if err := validateImageURL(raw); err != nil {
+				return err
+			}
+			upd.ImageURLs[i] = raw
+		}
+	}
+	if upd.SortOrder != nil && (*upd.SortOrder < -maxCatalogSortOrder || *upd.SortOrder > maxCatalogSortOrder) {
+		return userErrorf("порядок сортировки должен быть в пределах ±%d", maxCatalogSortOrder)
+	}
+	return nil
+}
+
+// normalizeTags приводит теги к нижнему регистру, убирает дубли и проверяет формат.
+// Для nil возвращает nil, для пустого среза — пустой срез.
+func normalizeTags(tags []string) ([]string, error) {
+	if tags == nil {
+		return nil, nil
+	}
+	seen := make(map[string]bool, len(tags))
+	normalized := []string{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if !itemTagPattern.MatchString(tag) {
+			return nil, userErrorf("тег %q может содержать только латинские буквы, цифры и дефис (до 32 символов)", tag)
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized, nil
+}
+
+func validateImageURL(raw string) error {
+	if len(raw) > maxImageURL {
+		return userErrorf("ссылка на изображение длиннее %d символов", maxImageURL)
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return userErrorf("ссылка на изображение должна быть абсолютным http(s) URL: %.64s", raw)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/itocode21/MerchServiceAvito/internal/config"
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+	"github.com/itocode21/MerchServiceAvito/internal/repositories"
+	"github.com/lib/pq"
+)
+
+var catalogColumns = []string{"id", "name", "price", "stock", "category", "description", "tags", "image_urls", "sort_order"}
+
+func newTestItemService(t *testing.T) (*ItemService, sqlmock.Sqlmock, func()) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка создания мока: %v", err)
+	}
+	userRepo := repositories.NewUserRepository(&config.Config{DB: db})
+	itemRepo := repositories.NewItemRepository(db)
+	service := NewItemService(itemRepo, userRepo, repositories.NewNotificationRepository(db),
+		NewPromotionService(repositories.NewPromotionRepository(db), itemRepo, userRepo), repositories.NewOutboxRepository(db))
+	return service, mock, func() { db.Close() }
+}
+
+func TestUpdateItem(t *testing.T) {
+	service, mock, done := newTestItemService(t)
+	defer done()
+
+	str := func(s string) *string { return &s }
+
+	tests := []struct {
+		name      string
+		update    models.ItemUpdate
+		setupMock func()
+		wantErr   bool
+		errMsg    string
+	}{
+		{
+			name: "Метаданные нормализуются и сохраняются",
+			update: models.ItemUpdate{
+				Category:    str(" apparel "),
+				Description: str("  Тёплое худи с логотипом  "),
+				Tags:        []string{"Winter", "logo", "winter"},
+				ImageURLs:   []string{" https://cdn.example.com/hoody.png "},
+			},
+			setupMock: func() {
+				mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM categories WHERE slug = \\$1\\)").
+					WithArgs("apparel").
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+				mock.ExpectBegin()
+				mock.ExpectQuery("UPDATE items i SET").
+					WithArgs(0, false, nil, "apparel", "Тёплое худи с логотипом",
+						pq.Array([]string{"winter", "logo"}), pq.Array([]string{"https://cdn.example.com/hoody.png"}), nil, "hoody").
+					WillReturnRows(sqlmock.NewRows(catalogColumns).
+						AddRow(3, "hoody", 300, nil, "apparel", "Тёплое худи с логотипом", "{winter,logo}", "{https://cdn.example.com/hoody.png}", 0))
+				mock.ExpectCommit()
+			},
+			wantErr: false,
+		},
+		{
+			name:   "Неизвестная категория",
+			update: models.ItemUpdate{Category: str("food")},
+			setupMock: func() {
+				mock.ExpectQuery("SELECT EXISTS").
+					WithArgs("food").
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+			},
+			wantErr: true,
+			errMsg:  "категория food не найдена",
+		},
+		{
+			name:      "Тег с пробелом",
+			update:    models.ItemUpdate{Tags: []string{"new year"}},
+			setupMock: func() {},
+			wantErr:   true,
+			errMsg:    `тег "new year" может содержать только латинские буквы, цифры и дефис (до 32 символов)`,
+		},
+		{
+			name:      "Ссылка на изображение без схемы",
+			update:    models.ItemUpdate{ImageURLs: []string{"cdn.example.com/hoody.png"}},
+			setupMock: func() {},
+			wantErr:   true,
+			errMsg:    "ссылка на изображение должна быть абсолютным http(s) URL: cdn.example.com/hoody.png",
+		},
+		{
+			name:      "Слишком много изображений",
+			update:    models.ItemUpdate{ImageURLs: make([]string, maxItemImages+1)},
+			setupMock: func() {},
+			wantErr:   true,
+			errMsg:    "у предмета может быть не больше 5 изображений",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+			item, err := service.UpdateItem("admin", "hoody", tt.update)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("UpdateItem() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && err != nil && err.Error() != tt.errMsg {
+				t.Errorf("UpdateItem() error message = %v, want %v", err.Error(), tt.errMsg)
+			}
+			if !tt.wantErr && (item.Category != "apparel" || len(item.Tags) != 2) {
+				t.Errorf("UpdateItem() = %+v", item)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Не все ожидания мока выполнены: %v", err)
+			}
+		})
+	}
+}
+
+func TestListItems(t *testing.T) {
+	service, mock, done := newTestItemService(t)
+	defer done()
+
+	mock.ExpectQuery("FROM items i CROSS JOIN LATERAL \\(.*\\) cp LEFT JOIN categories c ON c.slug = i.category "+
+		"WHERE i.category = \\$1 AND i.tags @> \\$2 AND cp.price <= \\$3 AND \\(i.stock IS NULL OR i.stock > 0\\) "+
+		"ORDER BY COALESCE\\(c.sort_order, 2147483647\\), i.category, i.sort_order, i.name LIMIT \\$4 OFFSET \\$5").
+		WithArgs("office", pq.Array([]string{"eco"}), 500, catalogDefaultLimit, 0).
+		WillReturnRows(sqlmock.NewRows(catalogColumns).
+			AddRow(1, "pen", 10, 25, "office", "", "{eco}", "{}", 0))
+
+	items, err := service.ListItems(models.CatalogFilter{Category: "office", Tags: []string{"ECO"}, MaxPrice: 500, InStock: true})
+	if err != nil {
+		t.Fatalf("ListItems() error = %v", err)
+	}
+	if len(items) != 1 || items[0].Name != "pen" || *items[0].Stock != 25 {
+		t.Errorf("ListItems() = %+v", items)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Не все ожидания мока выполнены: %v", err)
+	}
+
+	if _, err := service.ListItems(models.CatalogFilter{MinPrice: 300, MaxPrice: 100}); err == nil || !IsUserError(err) {
+		t.Errorf("ListItems() error = %v, want invalid price range", err)
+	}
+}
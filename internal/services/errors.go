@@ -0,0 +1,25 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+)
+
+// UserError — ошибка, вызванная данными запроса (нет получателя, не хватает монет и т.п.).
+// Её текст можно вернуть клиенту; все остальные ошибки считаются внутренними.
+type UserError struct {
+	msg string
+}
+
+func (e *UserError) Error() string {
+	return e.msg
+}
+
+func userErrorf(format string, args ...interface{}) error {
+	return &UserError{msg: fmt.Sprintf(format, args...)}
+}
+
+func IsUserError(err error) bool {
+	var userErr *UserError
+	return errors.As(err, &userErr)
+}
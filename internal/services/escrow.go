@@ -0,0 +1,83 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+	"github.com/itocode21/MerchServiceAvito/internal/repositories"
+)
+
+// EscrowService резервирует монеты пользователей. Зарезервированные монеты остаются
+// в балансе, но их нельзя потратить, пока резерв не снят.
+type EscrowService struct {
+	holdRepo  *repositories.HoldRepository
+	userRepo  *repositories.UserRepository
+	transRepo *repositories.TransactionRepository
+}
+
+func NewEscrowService(holdRepo *repositories.HoldRepository, userRepo *repositories.UserRepository, transRepo *repositories.TransactionRepository) *EscrowService {
+	return &EscrowService{
+		holdRepo:  holdRepo,
+		userRepo:  userRepo,
+		transRepo: transRepo,
+	}
+}
+
+// HoldTx резервирует hold.Amount монет пользователя. Строка user должна быть
+// заблокирована в транзакции tx.
+func (s *EscrowService) HoldTx(tx *sql.Tx, user *models.User, hold *models.CoinHold) error {
+	if user.SpendableCoins() < hold.Amount {
+		return userErrorf("недостаточно монет у %s: %d < %d", user.Username, user.SpendableCoins(), hold.Amount)
+	}
+
+	user.HeldCoins += hold.Amount
+	if err := s.userRepo.UpdateHeldCoinsTx(tx, user); err != nil {
+		return err
+	}
+	hold.UserID = user.ID
+	if err := s.holdRepo.CreateHoldTx(tx, hold); err != nil {
+		return err
+	}
+
+	transaction := &models.Transaction{
+		FromUserID: user.ID,
+		Amount:     hold.Amount,
+		Kind:       models.TransactionKindHold,
+	}
+	if err := s.transRepo.CreateTransaction(tx, transaction); err != nil {
+		return fmt.Errorf("ошибка записи транзакции: %w", err)
+	}
+	return nil
+}
+
+// ReleaseTx возвращает зарезервированные монеты в доступный баланс пользователя
+func (s *EscrowService) ReleaseTx(tx *sql.Tx, user *models.User, hold *models.CoinHold) error {
+	user.HeldCoins -= hold.Amount
+	if err := s.userRepo.UpdateHeldCoinsTx(tx, user); err != nil {
+		return err
+	}
+	if err := s.holdRepo.ReleaseHoldTx(tx, hold); err != nil {
+		return err
+	}
+
+	transaction := &models.Transaction{
+		ToUserID: user.ID,
+		Amount:   hold.Amount,
+		Kind:     models.TransactionKindHoldRelease,
+	}
+	if err := s.transRepo.CreateTransaction(tx, transaction); err != nil {
+		return fmt.Errorf("ошибка записи транзакции: %w", err)
+	}
+	return nil
+}
+
+// LockBetHoldsTx блокирует действующие резервы по пари
+func (s *EscrowService) LockBetHoldsTx(tx *sql.Tx, betID int) ([]models.CoinHold, error) {
+	return s.holdRepo.LockBetHoldsTx(tx, betID)
+}
+
+// LockAuctionHoldTx блокирует действующий резерв пользователя по аукциону
+func (s *EscrowService) LockAuctionHoldTx(tx *sql.Tx, auctionID, userID int) (*models.CoinHold, error) {
+	return s.holdRepo.LockAuctionHoldTx(tx, auctionID, userID)
+}
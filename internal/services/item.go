@@ -3,37 +3,48 @@ package services
 import (
 	"database/sql"
 	"fmt"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
+	"github.com/itocode21/MerchServiceAvito/internal/events"
 	"github.com/itocode21/MerchServiceAvito/internal/models"
 	"github.com/itocode21/MerchServiceAvito/internal/repositories"
 )
 
+const maxGiftMessage = 500
+
 type ItemService struct {
-	itemRepo *repositories.ItemRepository
-	userRepo *repositories.UserRepository
-	db       *sql.DB
+	itemRepo         *repositories.ItemRepository
+	userRepo         *repositories.UserRepository
+	notificationRepo *repositories.NotificationRepository
+	promotions       *PromotionService
+	outboxRepo       *repositories.OutboxRepository
+	db               *sql.DB
 }
 
-func NewItemService(itemRepo *repositories.ItemRepository, userRepo *repositories.UserRepository) *ItemService {
+func NewItemService(itemRepo *repositories.ItemRepository, userRepo *repositories.UserRepository, notificationRepo *repositories.NotificationRepository, promotions *PromotionService, outboxRepo *repositories.OutboxRepository) *ItemService {
 	return &ItemService{
-		itemRepo: itemRepo,
-		userRepo: userRepo,
-		db:       userRepo.DB,
+		itemRepo:         itemRepo,
+		userRepo:         userRepo,
+		notificationRepo: notificationRepo,
+		promotions:       promotions,
+		outboxRepo:       outboxRepo,
+		db:               userRepo.DB,
 	}
 }
 
-func (s *ItemService) BuyItem(username, itemName string) error {
+// BuyItem покупает предмет по цене с учётом действующих скидок и промокода promoCode
+func (s *ItemService) BuyItem(username, itemName, promoCode string) error {
 	tx, err := s.db.Begin()
 	if err != nil {
 		return fmt.Errorf("ошибка начала транзакции: %v", err)
 	}
 	defer tx.Rollback()
 
-	var userID int
-	var userCoins int
-	err = tx.QueryRow("SELECT id, coins FROM users WHERE username = $1 FOR UPDATE", username).
-		Scan(&userID, &userCoins)
+	var userID, userCoins, userHeldCoins int
+	err = tx.QueryRow("SELECT id, coins, held_coins FROM users WHERE username = $1 FOR UPDATE", username).
+		Scan(&userID, &userCoins, &userHeldCoins)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return fmt.Errorf("пользователь %s не найден", username)
@@ -49,11 +60,23 @@ func (s *ItemService) BuyItem(username, itemName string) error {
 		return fmt.Errorf("предмет %s не найден", itemName)
 	}
 
-	if userCoins < item.Price {
-		return fmt.Errorf("недостаточно монет: %d < %d", userCoins, item.Price)
+	quote, err := s.promotions.QuoteTx(tx, userID, item, promoCode)
+	if err != nil {
+		return err
 	}
 
-	user := &models.User{ID: userID, Coins: userCoins - item.Price}
+	if userCoins-userHeldCoins < quote.Price {
+		return fmt.Errorf("недостаточно монет: %d < %d", userCoins-userHeldCoins, quote.Price)
+	}
+	inStock, err := s.itemRepo.TakeFromStockTx(tx, item.ID)
+	if err != nil {
+		return err
+	}
+	if !inStock {
+		return userErrorf("предмет %s закончился", itemName)
+	}
+
+	user := &models.User{ID: userID, Coins: userCoins - quote.Price}
 	if err := s.userRepo.UpdateUserBalanceTx(tx, user); err != nil {
 		return fmt.Errorf("ошибка обновления баланса: %v", err)
 	}
@@ -62,13 +85,203 @@ func (s *ItemService) BuyItem(username, itemName string) error {
 		return fmt.Errorf("ошибка добавления в инвентарь: %v", err)
 	}
 
+	purchase := &models.Purchase{
+		BuyerID:     user.ID,
+		RecipientID: user.ID,
+		ItemID:      item.ID,
+		Price:       quote.Price,
+		ListPrice:   quote.ListPrice,
+		Discount:    quote.Discount,
+		PromoCode:   quote.Code,
+	}
+	if err := s.itemRepo.CreatePurchaseTx(tx, purchase); err != nil {
+		return err
+	}
+	if err := s.promotions.RedeemTx(tx, user.ID, purchase, quote); err != nil {
+		return err
+	}
+	if err := s.outboxRepo.AppendTx(tx, user.ID, purchased(username, username, item, purchase)); err != nil {
+		return err
+	}
+
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("ошибка фиксации транзакции: %v", err)
 	}
 
+	s.publishPurchase(username, username, purchase)
 	return nil
 }
 
+// GiftItem покупает предмет за счёт buyer и кладёт его в инвентарь получателя.
+// Получатель видит подарок в истории и получает уведомление. Скидки и промокод
+// применяются так же, как при обычной покупке.
+func (s *ItemService) GiftItem(buyer, recipient, itemName, message, promoCode string) (*models.Purchase, error) {
+	if buyer == recipient {
+		return nil, userErrorf("для покупки себе используйте /api/buy")
+	}
+	message = strings.TrimSpace(message)
+	if utf8.RuneCountInString(message) > maxGiftMessage {
+		return nil, userErrorf("сообщение длиннее %d символов", maxGiftMessage)
+	}
+
+	item, err := s.itemRepo.GetItemByName(itemName)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения предмета: %w", err)
+	}
+	if item == nil {
+		return nil, userErrorf("предмет %s не найден", itemName)
+	}
+
+	var purchase *models.Purchase
+	err = withTxRetry(func() error {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return fmt.Errorf("ошибка начала транзакции: %w", err)
+		}
+		defer tx.Rollback()
+
+		users, err := s.userRepo.LockUsersTx(tx, []string{buyer, recipient})
+		if err != nil {
+			return err
+		}
+		buyerUser, recipientUser := users[buyer], users[recipient]
+		if buyerUser == nil {
+			return userErrorf("пользователь %s не найден", buyer)
+		}
+		if recipientUser == nil {
+			return userErrorf("получатель %s не найден", recipient)
+		}
+		if !recipientUser.IsActive {
+			return userErrorf("получатель %s деактивирован", recipient)
+		}
+		quote, err := s.promotions.QuoteTx(tx, buyerUser.ID, item, promoCode)
+		if err != nil {
+			return err
+		}
+		if buyerUser.SpendableCoins() < quote.Price {
+			return userErrorf("недостаточно монет: %d < %d", buyerUser.SpendableCoins(), quote.Price)
+		}
+		inStock, err := s.itemRepo.TakeFromStockTx(tx, item.ID)
+		if err != nil {
+			return err
+		}
+		if !inStock {
+			return userErrorf("предмет %s закончился", item.Name)
+		}
+
+		buyerUser.Coins -= quote.Price
+		if err := s.userRepo.UpdateUserBalanceTx(tx, buyerUser); err != nil {
+			return fmt.Errorf("ошибка обновления баланса: %w", err)
+		}
+		if err := s.itemRepo.AddToInventory(tx, recipientUser.ID, item.ID); err != nil {
+			return fmt.Errorf("ошибка добавления в инвентарь: %w", err)
+		}
+		purchase = &models.Purchase{
+			BuyerID:     buyerUser.ID,
+			RecipientID: recipientUser.ID,
+			ItemID:      item.ID,
+			Price:       quote.Price,
+			ListPrice:   quote.ListPrice,
+			Discount:    quote.Discount,
+			PromoCode:   quote.Code,
+			Message:     message,
+		}
+		if err := s.itemRepo.CreatePurchaseTx(tx, purchase); err != nil {
+			return err
+		}
+		if err := s.promotions.RedeemTx(tx, buyerUser.ID, purchase, quote); err != nil {
+			return err
+		}
+		if err := s.outboxRepo.AppendTx(tx, buyerUser.ID, purchased(buyer, recipient, item, purchase)); err != nil {
+			return err
+		}
+
+		text := fmt.Sprintf("%s подарил(а) вам %s", buyer, item.Name)
+		if message != "" {
+			text += ": " + message
+		}
+		notification := &models.Notification{UserID: recipientUser.ID, Kind: models.NotificationGiftReceived, Message: text}
+		if err := s.notificationRepo.CreateNotificationTx(tx, notification); err != nil {
+			return err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("ошибка фиксации транзакции: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.publishPurchase(buyer, recipient, purchase)
+	return purchase, nil
+}
+
+func purchased(buyer, recipient string, item *models.Item, p *models.Purchase) models.ItemPurchased {
+	return models.ItemPurchased{
+		PurchaseID: p.ID,
+		Buyer:      buyer,
+		Recipient:  recipient,
+		Item:       item.Name,
+		Price:      p.Price,
+		ListPrice:  p.ListPrice,
+		PromoCode:  p.PromoCode,
+		At:         p.CreatedAt,
+	}
+}
+
+// publishPurchase сообщает подписчикам о зафиксированной покупке или подарке
+func (s *ItemService) publishPurchase(buyer, recipient string, p *models.Purchase) {
+	s.userRepo.Config.Events.Publish(events.Event{
+		Kind:     events.ItemPurchased,
+		UserID:   p.BuyerID,
+		User:     buyer,
+		TargetID: p.RecipientID,
+		Target:   recipient,
+		ItemID:   p.ItemID,
+		Amount:   p.Price,
+		At:       p.CreatedAt,
+	})
+}
+
+// UpdateItem меняет цену, запас и данные витрины предмета каталога.
+// Новая цена действует сразу и попадает в историю цен от имени admin.
+func (s *ItemService) UpdateItem(admin, name string, upd models.ItemUpdate) (*models.Item, error) {
+	if err := s.validateItemUpdate(&upd); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	item, err := s.itemRepo.UpdateItemTx(tx, name, upd)
+	if err != nil {
+		return nil, err
+	}
+	if item == nil {
+		return nil, userErrorf("предмет %s не найден", name)
+	}
+	if upd.Price > 0 {
+		price := &models.ItemPrice{ItemID: item.ID, Price: upd.Price}
+		created, err := s.itemRepo.CreatePriceTx(tx, price, admin)
+		if err != nil {
+			return nil, err
+		}
+		if !created {
+			return nil, userErrorf("цена предмета %s только что изменилась, повторите запрос", name)
+		}
+		item.Price = upd.Price
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+	return item, nil
+}
+
 func (s *ItemService) GetUserInventory(userID int) ([]gin.H, error) {
 	return s.itemRepo.GetUserInventory(userID)
 }
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+)
+
+const maxPriceSchedule = 365 * 24 * time.Hour
+
+// SchedulePrice планирует смену цены предмета на момент effectiveFrom.
+// До этого момента покупки идут по прежней цене.
+func (s *ItemService) SchedulePrice(admin, itemName string, price int, effectiveFrom time.Time) (*models.ItemPrice, error) {
+	if price <= 0 || price > maxAmount {
+		return nil, userErrorf("цена должна быть от 1 до %d", maxAmount)
+	}
+	now := time.Now()
+	if !effectiveFrom.After(now) || effectiveFrom.After(now.Add(maxPriceSchedule)) {
+		return nil, userErrorf("смену цены можно запланировать в пределах %d дней", int(maxPriceSchedule.Hours()/24))
+	}
+	item, err := s.itemRepo.GetItemByName(itemName)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения предмета: %w", err)
+	}
+	if item == nil {
+		return nil, userErrorf("предмет %s не найден", itemName)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	p := &models.ItemPrice{ItemID: item.ID, Price: price, EffectiveFrom: effectiveFrom, Scheduled: true}
+	created, err := s.itemRepo.CreatePriceTx(tx, p, admin)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return nil, userErrorf("на это время цена %s уже запланирована", itemName)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+	return p, nil
+}
+
+// CancelScheduledPrice отменяет ещё не наступившую смену цены
+func (s *ItemService) CancelScheduledPrice(itemName string, id int) error {
+	item, err := s.itemRepo.GetItemByName(itemName)
+	if err != nil {
+		return fmt.Errorf("ошибка получения предмета: %w", err)
+	}
+	if item == nil {
+		return userErrorf("предмет %s не найден", itemName)
+	}
+	found, err := s.itemRepo.DeleteScheduledPrice(item.ID, id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return userErrorf("запланированная смена цены %d не найдена", id)
+	}
+	return nil
+}
+
+// GetPriceTimeline возвращает действующую цену предмета и историю её изменений,
+// включая запланированные. У каждой записи, кроме последней, есть время окончания.
+func (s *ItemService) GetPriceTimeline(itemName string) (*models.PriceTimeline, error) {
+	item, err := s.itemRepo.GetItemByName(itemName)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения предмета: %w", err)
+	}
+	if item == nil {
+		return nil, userErrorf("предмет %s не найден", itemName)
+	}
+	prices, err := s.itemRepo.GetPrices(item.ID)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i+1 < len(prices); i++ {
+		prices[i].EffectiveTo = &prices[i+1].EffectiveFrom
+	}
+	return &models.PriceTimeline{Item: item.Name, Price: item.Price, Prices: prices}, nil
+}
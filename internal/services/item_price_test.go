@@ -0,0 +1,156 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+)
+
+func TestUpdateItemPriceHistory(t *testing.T) {
+	service, mock, done := newTestItemService(t)
+	defer done()
+
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE items i SET").
+		WithArgs(250, false, nil, nil, nil, sqlmock.AnyArg(), sqlmock.AnyArg(), nil, "hoody").
+		WillReturnRows(sqlmock.NewRows(catalogColumns).
+			AddRow(3, "hoody", 300, nil, "apparel", "", "{}", "{}", 0))
+	mock.ExpectQuery("INSERT INTO item_prices").
+		WithArgs(3, 250, nil, "admin").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "effective_from", "created_at"}).AddRow(5, now, now))
+	mock.ExpectCommit()
+
+	item, err := service.UpdateItem("admin", "hoody", models.ItemUpdate{Price: 250})
+	if err != nil {
+		t.Fatalf("UpdateItem() error = %v", err)
+	}
+	if item.Price != 250 {
+		t.Errorf("UpdateItem() price = %d, want 250", item.Price)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Не все ожидания мока выполнены: %v", err)
+	}
+}
+
+func TestSchedulePrice(t *testing.T) {
+	service, mock, done := newTestItemService(t)
+	defer done()
+
+	monday := time.Now().Add(72 * time.Hour)
+
+	tests := []struct {
+		name          string
+		price         int
+		effectiveFrom time.Time
+		setupMock     func()
+		wantErr       bool
+		errMsg        string
+	}{
+		{
+			name:          "Распродажа запланирована",
+			price:         150,
+			effectiveFrom: monday,
+			setupMock: func() {
+				mock.ExpectQuery("SELECT i.id, i.name, .* FROM items i WHERE i.name = \\$1").
+					WithArgs("hoody").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price"}).AddRow(3, "hoody", 300))
+				mock.ExpectBegin()
+				mock.ExpectQuery("INSERT INTO item_prices .* ON CONFLICT \\(item_id, effective_from\\) DO NOTHING").
+					WithArgs(3, 150, monday, "admin").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "effective_from", "created_at"}).AddRow(6, monday, time.Now()))
+				mock.ExpectCommit()
+			},
+			wantErr: false,
+		},
+		{
+			name:          "Цена на это время уже запланирована",
+			price:         150,
+			effectiveFrom: monday,
+			setupMock: func() {
+				mock.ExpectQuery("SELECT i.id, i.name, .* FROM items i WHERE i.name = \\$1").
+					WithArgs("hoody").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price"}).AddRow(3, "hoody", 300))
+				mock.ExpectBegin()
+				mock.ExpectQuery("INSERT INTO item_prices").
+					WithArgs(3, 150, monday, "admin").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "effective_from", "created_at"}))
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+			errMsg:  "на это время цена hoody уже запланирована",
+		},
+		{
+			name:          "Время уже прошло",
+			price:         150,
+			effectiveFrom: time.Now().Add(-time.Hour),
+			setupMock:     func() {},
+			wantErr:       true,
+			errMsg:        "смену цены можно запланировать в пределах 365 дней",
+		},
+		{
+			name:          "Нулевая цена",
+			price:         0,
+			effectiveFrom: monday,
+			setupMock:     func() {},
+			wantErr:       true,
+			errMsg:        "цена должна быть от 1 до 2147483647",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+			price, err := service.SchedulePrice("admin", "hoody", tt.price, tt.effectiveFrom)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SchedulePrice() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && err != nil && err.Error() != tt.errMsg {
+				t.Errorf("SchedulePrice() error message = %v, want %v", err.Error(), tt.errMsg)
+			}
+			if !tt.wantErr && (price.ID != 6 || !price.Scheduled) {
+				t.Errorf("SchedulePrice() = %+v", price)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Не все ожидания мока выполнены: %v", err)
+			}
+		})
+	}
+}
+
+func TestGetPriceTimeline(t *testing.T) {
+	service, mock, done := newTestItemService(t)
+	defer done()
+
+	start := time.Now().Add(-48 * time.Hour)
+	sale := time.Now().Add(24 * time.Hour)
+	saleEnd := sale.Add(5 * 24 * time.Hour)
+	mock.ExpectQuery("SELECT i.id, i.name, .* FROM items i WHERE i.name = \\$1").
+		WithArgs("hoody").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price"}).AddRow(3, "hoody", 300))
+	mock.ExpectQuery("FROM item_prices ip .* ORDER BY ip.effective_from, ip.id").
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "item_id", "price", "effective_from", "scheduled", "created_by", "created_at"}).
+			AddRow(1, 3, 300, start, false, "", start).
+			AddRow(7, 3, 200, sale, true, "admin", start).
+			AddRow(8, 3, 300, saleEnd, true, "admin", start))
+
+	timeline, err := service.GetPriceTimeline("hoody")
+	if err != nil {
+		t.Fatalf("GetPriceTimeline() error = %v", err)
+	}
+	if timeline.Price != 300 || len(timeline.Prices) != 3 {
+		t.Fatalf("GetPriceTimeline() = %+v", timeline)
+	}
+	if !timeline.Prices[0].EffectiveTo.Equal(sale) || !timeline.Prices[1].EffectiveTo.Equal(saleEnd) {
+		t.Errorf("GetPriceTimeline() effective_to = %v, %v", timeline.Prices[0].EffectiveTo, timeline.Prices[1].EffectiveTo)
+	}
+	if timeline.Prices[2].EffectiveTo != nil {
+		t.Errorf("GetPriceTimeline() last effective_to = %v, want nil", timeline.Prices[2].EffectiveTo)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Не все ожидания мока выполнены: %v", err)
+	}
+}
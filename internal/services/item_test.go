@@ -3,10 +3,12 @@ package services
 import (
 	"database/sql"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/go-redis/redis/v8"
 	"github.com/itocode21/MerchServiceAvito/internal/config"
+	"github.com/itocode21/MerchServiceAvito/internal/models"
 	"github.com/itocode21/MerchServiceAvito/internal/repositories"
 )
 
@@ -30,12 +32,14 @@ func TestBuyItem(t *testing.T) {
 
 	userRepo := repositories.NewUserRepository(cfg)
 	itemRepo := repositories.NewItemRepository(db)
-	service := NewItemService(itemRepo, userRepo)
+	service := NewItemService(itemRepo, userRepo, repositories.NewNotificationRepository(db),
+		NewPromotionService(repositories.NewPromotionRepository(db), itemRepo, userRepo), repositories.NewOutboxRepository(db))
 
 	tests := []struct {
 		name      string
 		username  string
 		itemName  string
+		promoCode string
 		setupMock func()
 		wantErr   bool
 		errMsg    string
@@ -47,17 +51,26 @@ func TestBuyItem(t *testing.T) {
 			setupMock: func() {
 				// Мокаем SELECT FOR UPDATE
 				mock.ExpectBegin()
-				mock.ExpectQuery("SELECT id, coins FROM users WHERE username = \\$1 FOR UPDATE").
+				mock.ExpectQuery("SELECT id, coins, held_coins FROM users WHERE username = \\$1 FOR UPDATE").
 					WithArgs("user1").
-					WillReturnRows(sqlmock.NewRows([]string{"id", "coins"}).
-						AddRow(1, 1000))
+					WillReturnRows(sqlmock.NewRows([]string{"id", "coins", "held_coins"}).
+						AddRow(1, 1000, 0))
 
 				// Мокаем GetItemByName
-				mock.ExpectQuery("SELECT id, name, price FROM items WHERE name = \\$1").
+				mock.ExpectQuery("SELECT i.id, i.name, .* FROM items i WHERE i.name = \\$1").
 					WithArgs("t-shirt").
 					WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price"}).
 						AddRow(1, "t-shirt", 80))
 
+				mock.ExpectQuery("FROM promotions p").
+					WithArgs(1, 1, "").
+					WillReturnRows(sqlmock.NewRows(promotionColumns))
+
+				// Мокаем TakeFromStockTx
+				mock.ExpectExec("UPDATE items SET stock = stock - 1 WHERE id = \\$1").
+					WithArgs(1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+
 				// Мокаем UpdateUserBalanceTx
 				mock.ExpectExec("UPDATE users SET coins = \\$1 WHERE id = \\$2").
 					WithArgs(920, 1).
@@ -68,43 +81,148 @@ func TestBuyItem(t *testing.T) {
 					WithArgs(1, 1).
 					WillReturnResult(sqlmock.NewResult(1, 1))
 
+				// Мокаем запись покупки
+				mock.ExpectQuery("INSERT INTO purchases \\(buyer_id, recipient_id, item_id, price, list_price, discount, promo_code, message\\)").
+					WithArgs(1, 1, 1, 80, 80, 0, "", "").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+
+				mock.ExpectExec("INSERT INTO outbox_events").WithArgs(models.EventItemPurchased, 1, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
 				mock.ExpectCommit()
 			},
 			wantErr: false,
 		},
+		{
+			name:      "Покупка по промокоду",
+			username:  "user1",
+			itemName:  "t-shirt",
+			promoCode: " event ",
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT id, coins, held_coins FROM users WHERE username = \\$1 FOR UPDATE").
+					WithArgs("user1").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "coins", "held_coins"}).
+						AddRow(1, 1000, 0))
+				mock.ExpectQuery("SELECT i.id, i.name, .* FROM items i WHERE i.name = \\$1").
+					WithArgs("t-shirt").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price"}).
+						AddRow(1, "t-shirt", 80))
+				mock.ExpectQuery("FROM promotions p").
+					WithArgs(1, 1, "EVENT").
+					WillReturnRows(sqlmock.NewRows(promotionColumns).
+						AddRow(5, "EVENT", "percent", 25, 0, "", "", time.Now().Add(-time.Hour), nil, 100, 1, 3, false, true, time.Now(), true, 0))
+				mock.ExpectExec("UPDATE items SET stock = stock - 1 WHERE id = \\$1").
+					WithArgs(1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("UPDATE users SET coins = \\$1 WHERE id = \\$2").
+					WithArgs(940, 1).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec("INSERT INTO inventory").
+					WithArgs(1, 1).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectQuery("INSERT INTO purchases").
+					WithArgs(1, 1, 1, 60, 80, 20, "EVENT", "").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(7, time.Now()))
+				mock.ExpectExec("UPDATE promotions SET uses = uses \\+ 1").
+					WithArgs(5).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("INSERT INTO promotion_redemptions").
+					WithArgs(5, 1, 7, 20).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec("INSERT INTO outbox_events").WithArgs(models.EventItemPurchased, 1, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+			},
+			wantErr: false,
+		},
+		{
+			name:      "Промокод уже использован",
+			username:  "user1",
+			itemName:  "t-shirt",
+			promoCode: "EVENT",
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT id, coins, held_coins FROM users WHERE username = \\$1 FOR UPDATE").
+					WithArgs("user1").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "coins", "held_coins"}).
+						AddRow(1, 1000, 0))
+				mock.ExpectQuery("SELECT i.id, i.name, .* FROM items i WHERE i.name = \\$1").
+					WithArgs("t-shirt").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price"}).
+						AddRow(1, "t-shirt", 80))
+				mock.ExpectQuery("FROM promotions p").
+					WithArgs(1, 1, "EVENT").
+					WillReturnRows(sqlmock.NewRows(promotionColumns).
+						AddRow(5, "EVENT", "percent", 25, 0, "", "", time.Now().Add(-time.Hour), nil, 100, 1, 4, false, true, time.Now(), true, 1))
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+			errMsg:  "промокод EVENT уже использован вами максимальное число раз",
+		},
 		{
 			name:     "Недостаточно монет",
 			username: "user1",
 			itemName: "hoody",
 			setupMock: func() {
 				mock.ExpectBegin()
-				mock.ExpectQuery("SELECT id, coins FROM users WHERE username = \\$1 FOR UPDATE").
+				mock.ExpectQuery("SELECT id, coins, held_coins FROM users WHERE username = \\$1 FOR UPDATE").
 					WithArgs("user1").
-					WillReturnRows(sqlmock.NewRows([]string{"id", "coins"}).
-						AddRow(1, 200))
+					WillReturnRows(sqlmock.NewRows([]string{"id", "coins", "held_coins"}).
+						AddRow(1, 200, 0))
 
-				mock.ExpectQuery("SELECT id, name, price FROM items WHERE name = \\$1").
+				mock.ExpectQuery("SELECT i.id, i.name, .* FROM items i WHERE i.name = \\$1").
 					WithArgs("hoody").
 					WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price"}).
 						AddRow(2, "hoody", 300))
 
+				mock.ExpectQuery("FROM promotions p").
+					WithArgs(1, 2, "").
+					WillReturnRows(sqlmock.NewRows(promotionColumns))
+
 				mock.ExpectRollback() // Транзакция откатывается из-за ошибки
 			},
 			wantErr: true,
 			errMsg:  "недостаточно монет: 200 < 300",
 		},
+		{
+			name:     "Предмет закончился",
+			username: "user1",
+			itemName: "hoody",
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT id, coins, held_coins FROM users WHERE username = \\$1 FOR UPDATE").
+					WithArgs("user1").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "coins", "held_coins"}).
+						AddRow(1, 1000, 0))
+
+				mock.ExpectQuery("SELECT i.id, i.name, .* FROM items i WHERE i.name = \\$1").
+					WithArgs("hoody").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price"}).
+						AddRow(2, "hoody", 300))
+
+				mock.ExpectQuery("FROM promotions p").
+					WithArgs(1, 2, "").
+					WillReturnRows(sqlmock.NewRows(promotionColumns))
+
+				mock.ExpectExec("UPDATE items SET stock = stock - 1 WHERE id = \\$1").
+					WithArgs(2).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+			errMsg:  "предмет hoody закончился",
+		},
 		{
 			name:     "Предмет не найден",
 			username: "user1",
 			itemName: "nonexistent",
 			setupMock: func() {
 				mock.ExpectBegin()
-				mock.ExpectQuery("SELECT id, coins FROM users WHERE username = \\$1 FOR UPDATE").
+				mock.ExpectQuery("SELECT id, coins, held_coins FROM users WHERE username = \\$1 FOR UPDATE").
 					WithArgs("user1").
-					WillReturnRows(sqlmock.NewRows([]string{"id", "coins"}).
-						AddRow(1, 1000))
+					WillReturnRows(sqlmock.NewRows([]string{"id", "coins", "held_coins"}).
+						AddRow(1, 1000, 0))
 
-				mock.ExpectQuery("SELECT id, name, price FROM items WHERE name = \\$1").
+				mock.ExpectQuery("SELECT i.id, i.name, .* FROM items i WHERE i.name = \\$1").
 					WithArgs("nonexistent").
 					WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price"}))
 
@@ -119,7 +237,7 @@ func TestBuyItem(t *testing.T) {
 			itemName: "t-shirt",
 			setupMock: func() {
 				mock.ExpectBegin()
-				mock.ExpectQuery("SELECT id, coins FROM users WHERE username = \\$1 FOR UPDATE").
+				mock.ExpectQuery("SELECT id, coins, held_coins FROM users WHERE username = \\$1 FOR UPDATE").
 					WithArgs("user999").
 					WillReturnError(sql.ErrNoRows)
 
@@ -133,7 +251,7 @@ func TestBuyItem(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.setupMock()
-			err := service.BuyItem(tt.username, tt.itemName)
+			err := service.BuyItem(tt.username, tt.itemName, tt.promoCode)
 			if tt.wantErr {
 				if err == nil {
 					t.Errorf("BuyItem() error = nil, want error %q", tt.errMsg)
@@ -149,3 +267,107 @@ func TestBuyItem(t *testing.T) {
 		})
 	}
 }
+
+func TestGiftItem(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка создания мока: %v", err)
+	}
+	defer db.Close()
+
+	cfg := &config.Config{DB: db}
+	userRepo := repositories.NewUserRepository(cfg)
+	itemRepo := repositories.NewItemRepository(db)
+	service := NewItemService(itemRepo, userRepo, repositories.NewNotificationRepository(db),
+		NewPromotionService(repositories.NewPromotionRepository(db), itemRepo, userRepo), repositories.NewOutboxRepository(db))
+
+	itemQuery := "SELECT i.id, i.name, .* FROM items i WHERE i.name = \\$1"
+	lockQuery := "SELECT id, username, coins, held_coins, is_active FROM users"
+	lockColumns := []string{"id", "username", "coins", "held_coins", "is_active"}
+
+	tests := []struct {
+		name      string
+		recipient string
+		setupMock func()
+		wantErr   bool
+		errMsg    string
+	}{
+		{
+			name:      "Успешный подарок",
+			recipient: "user2",
+			setupMock: func() {
+				mock.ExpectQuery(itemQuery).
+					WithArgs("cup").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price"}).AddRow(2, "cup", 20))
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockQuery).
+					WithArgs(sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows(lockColumns).
+						AddRow(1, "user1", 1000, 0, true).
+						AddRow(2, "user2", 500, 0, true))
+				mock.ExpectQuery("FROM promotions p").
+					WithArgs(1, 2, "").
+					WillReturnRows(sqlmock.NewRows(promotionColumns))
+				mock.ExpectExec("UPDATE items SET stock = stock - 1 WHERE id = \\$1").
+					WithArgs(2).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("UPDATE users SET coins = \\$1 WHERE id = \\$2").
+					WithArgs(980, 1).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec("INSERT INTO inventory").
+					WithArgs(2, 2).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectQuery("INSERT INTO purchases").
+					WithArgs(1, 2, 2, 20, 20, 0, "", "С днём рождения!").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+				mock.ExpectExec("INSERT INTO outbox_events").WithArgs(models.EventItemPurchased, 1, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectQuery("INSERT INTO notifications").
+					WithArgs(2, "gift_received", "user1 подарил(а) вам cup: С днём рождения!").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+				mock.ExpectCommit()
+			},
+			wantErr: false,
+		},
+		{
+			name:      "Получатель деактивирован",
+			recipient: "user2",
+			setupMock: func() {
+				mock.ExpectQuery(itemQuery).
+					WithArgs("cup").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price"}).AddRow(2, "cup", 20))
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockQuery).
+					WithArgs(sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows(lockColumns).
+						AddRow(1, "user1", 1000, 0, true).
+						AddRow(2, "user2", 500, 0, false))
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+			errMsg:  "получатель user2 деактивирован",
+		},
+		{
+			name:      "Подарок самому себе",
+			recipient: "user1",
+			setupMock: func() {},
+			wantErr:   true,
+			errMsg:    "для покупки себе используйте /api/buy",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+			_, err := service.GiftItem("user1", tt.recipient, "cup", " С днём рождения! ", "")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GiftItem() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && err != nil && err.Error() != tt.errMsg {
+				t.Errorf("GiftItem() error message = %v, want %v", err.Error(), tt.errMsg)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Не все ожидания мока выполнены: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,386 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/itocode21/MerchServiceAvito/internal/events"
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+	"github.com/itocode21/MerchServiceAvito/internal/repositories"
+)
+
+const (
+	leaderboardKeyPrefix    = "leaderboard:"
+	leaderboardDefaultLimit = 10
+	leaderboardMaxLimit     = 100
+
+	// Служебные ключи пересборки лежат вне leaderboard:, чтобы их не удалила
+	// чистка устаревших рейтингов
+	leaderboardLockKey        = "leaderboard-rebuild:lock"
+	leaderboardDirtyKey       = "leaderboard-rebuild:dirty"
+	leaderboardRebuildLockTTL = 10 * time.Minute
+
+	transfersMarkKind  = "transfers"
+	collectorsMarkKind = "collectors"
+)
+
+var leaderboardPeriods = []string{models.PeriodWeek, models.PeriodMonth, models.PeriodYear, models.PeriodAll}
+
+// unlockScript снимает блокировку KEYS[1], только если её держит владелец ARGV[1]
+var unlockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+    return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// LeaderboardService ведёт рейтинги в sorted set'ах Redis: по получателям и
+// отправителям монет, числу разных коллег, которым отправляли монеты, и числу
+// предметов в инвентаре. По событиям счета участников пересчитываются из базы,
+// а периодически рейтинги пересобираются целиком, поэтому Redis можно очистить
+// без потери данных.
+type LeaderboardService struct {
+	leaderboardRepo *repositories.LeaderboardRepository
+	redis           *redis.Client
+}
+
+func NewLeaderboardService(leaderboardRepo *repositories.LeaderboardRepository, userRepo *repositories.UserRepository) *LeaderboardService {
+	return &LeaderboardService{
+		leaderboardRepo: leaderboardRepo,
+		redis:           userRepo.Config.Redis,
+	}
+}
+
+func leaderboardKey(board, bucket string) string {
+	return leaderboardKeyPrefix + board + ":" + bucket
+}
+
+// periodBucket возвращает обозначение периода, в который попадает t
+func periodBucket(period string, t time.Time) string {
+	switch period {
+	case models.PeriodWeek:
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	case models.PeriodMonth:
+		return t.Format("2006-01")
+	case models.PeriodYear:
+		return t.Format("2006")
+	default:
+		return models.PeriodAll
+	}
+}
+
+// periodRange возвращает границы периода, в который попадает t; у всего времени границ нет
+func periodRange(period string, t time.Time) models.PeriodRange {
+	year, month, day := t.Date()
+	switch period {
+	case models.PeriodWeek:
+		start := time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+		start = start.AddDate(0, 0, -(int(start.Weekday())+6)%7)
+		return models.PeriodRange{From: start, To: start.AddDate(0, 0, 7)}
+	case models.PeriodMonth:
+		start := time.Date(year, month, 1, 0, 0, 0, 0, t.Location())
+		return models.PeriodRange{From: start, To: start.AddDate(0, 1, 0)}
+	case models.PeriodYear:
+		start := time.Date(year, 1, 1, 0, 0, 0, 0, t.Location())
+		return models.PeriodRange{From: start, To: start.AddDate(1, 0, 0)}
+	default:
+		return models.PeriodRange{}
+	}
+}
+
+// transfersMark — отметка о том, что переводы username за день at надо пересчитать
+func transfersMark(username string, at time.Time) string {
+	year, month, day := at.Date()
+	return transfersMarkKind + "|" + username + "|" + time.Date(year, month, day, 0, 0, 0, 0, at.Location()).Format(time.RFC3339)
+}
+
+// collectorsMark — отметка о том, что инвентарь username надо пересчитать
+func collectorsMark(username string) string {
+	return collectorsMarkKind + "|" + username
+}
+
+// Subscribe подписывает рейтинги на события шины bus
+func (s *LeaderboardService) Subscribe(bus *events.Bus) {
+	for _, kind := range []string{events.CoinsSent, events.ItemPurchased, events.InventoryChanged} {
+		bus.Subscribe(kind, "leaderboards", s.HandleEvent)
+	}
+}
+
+// HandleEvent пересчитывает из базы счета участников перевода во всех периодах или
+// размер инвентаря получившего предметы. Счёт записывается целиком, а не прибавляется,
+// поэтому повторное или запоздавшее событие рейтинг не портит. Пересчитанные
+// участники отмечаются для пересборки, которая может идти в это время (см. Rebuild).
+func (s *LeaderboardService) HandleEvent(e events.Event) error {
+	var marks []interface{}
+	switch e.Kind {
+	case events.CoinsSent:
+		marks = []interface{}{transfersMark(e.User, e.At), transfersMark(e.Target, e.At)}
+	case events.ItemPurchased:
+		marks = []interface{}{collectorsMark(e.Target)}
+	case events.InventoryChanged:
+		marks = []interface{}{collectorsMark(e.User)}
+	default:
+		return nil
+	}
+	ctx := context.Background()
+	var errs []error
+	for _, mark := range marks {
+		errs = append(errs, s.refresh(ctx, mark.(string)))
+	}
+	if err := s.redis.SAdd(ctx, leaderboardDirtyKey, marks...).Err(); err != nil {
+		errs = append(errs, fmt.Errorf("ошибка отметки рейтинга: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// refresh пересчитывает из базы счета, на которые указывает отметка mark
+func (s *LeaderboardService) refresh(ctx context.Context, mark string) error {
+	kind, rest, _ := strings.Cut(mark, "|")
+	switch kind {
+	case transfersMarkKind:
+		i := strings.LastIndex(rest, "|")
+		if i < 0 {
+			break
+		}
+		day, err := time.Parse(time.RFC3339, rest[i+1:])
+		if err != nil {
+			break
+		}
+		return s.refreshTransfers(ctx, rest[:i], day)
+	case collectorsMarkKind:
+		return s.refreshCollector(ctx, rest)
+	}
+	return fmt.Errorf("повреждённая отметка рейтинга: %s", mark)
+}
+
+// refreshTransfers записывает счета username в рейтингах переводов за все периоды,
+// в которые попадает at
+func (s *LeaderboardService) refreshTransfers(ctx context.Context, username string, at time.Time) error {
+	ranges := make([]models.PeriodRange, len(leaderboardPeriods))
+	for i, period := range leaderboardPeriods {
+		ranges[i] = periodRange(period, at)
+	}
+	scores, err := s.leaderboardRepo.GetTransferScores(username, ranges)
+	if err != nil {
+		return err
+	}
+	_, err = s.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, period := range leaderboardPeriods {
+			bucket := periodBucket(period, at)
+			setScore(ctx, pipe, leaderboardKey(models.LeaderboardReceived, bucket), username, scores[i].Received)
+			setScore(ctx, pipe, leaderboardKey(models.LeaderboardSent, bucket), username, scores[i].Sent)
+			setScore(ctx, pipe, leaderboardKey(models.LeaderboardThanked, bucket), username, scores[i].Thanked)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("ошибка обновления рейтинга: %w", err)
+	}
+	return nil
+}
+
+// refreshCollector записывает размер инвентаря username в рейтинг collectors
+func (s *LeaderboardService) refreshCollector(ctx context.Context, username string) error {
+	n, err := s.leaderboardRepo.GetInventorySize(username)
+	if err != nil {
+		return err
+	}
+	_, err = s.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		setScore(ctx, pipe, leaderboardKey(models.LeaderboardCollectors, models.PeriodAll), username, n)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("ошибка обновления рейтинга: %w", err)
+	}
+	return nil
+}
+
+// setScore записывает счёт участника; с нулевым счётом участник из рейтинга убирается,
+// как и при пересборке
+func setScore(ctx context.Context, pipe redis.Pipeliner, key, member string, score int) {
+	if score > 0 {
+		pipe.ZAdd(ctx, key, &redis.Z{Score: float64(score), Member: member})
+	} else {
+		pipe.ZRem(ctx, key, member)
+	}
+}
+
+// GetLeaderboard возвращает первые limit мест рейтинга board за период, в который
+// попадает at, и место пользователя username
+func (s *LeaderboardService) GetLeaderboard(board, period string, at time.Time, username string, limit int) (*models.Leaderboard, error) {
+	switch board {
+	case models.LeaderboardReceived, models.LeaderboardSent, models.LeaderboardThanked:
+	case models.LeaderboardCollectors:
+		if period != models.PeriodAll {
+			return nil, userErrorf("рейтинг collectors считается только за всё время (period=all)")
+		}
+	default:
+		return nil, userErrorf("неизвестный рейтинг: %s", board)
+	}
+	switch period {
+	case models.PeriodWeek, models.PeriodMonth, models.PeriodYear, models.PeriodAll:
+	default:
+		return nil, userErrorf("неизвестный период: %s", period)
+	}
+	if limit == 0 {
+		limit = leaderboardDefaultLimit
+	}
+	if limit < 0 || limit > leaderboardMaxLimit {
+		return nil, userErrorf("limit должен быть от 1 до %d", leaderboardMaxLimit)
+	}
+
+	ctx := context.Background()
+	bucket := periodBucket(period, at)
+	key := leaderboardKey(board, bucket)
+	top, err := s.redis.ZRevRangeWithScores(ctx, key, 0, int64(limit-1)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения рейтинга: %w", err)
+	}
+	lb := &models.Leaderboard{Board: board, Period: period, Bucket: bucket, Entries: make([]models.LeaderboardEntry, 0, len(top))}
+	for i, z := range top {
+		lb.Entries = append(lb.Entries, models.LeaderboardEntry{Rank: i + 1, Username: z.Member.(string), Score: int(z.Score)})
+	}
+
+	rank, err := s.redis.ZRevRank(ctx, key, username).Result()
+	if err == redis.Nil {
+		return lb, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения места в рейтинге: %w", err)
+	}
+	score, err := s.redis.ZScore(ctx, key, username).Result()
+	if err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("ошибка получения места в рейтинге: %w", err)
+	}
+	lb.Me = &models.LeaderboardEntry{Rank: int(rank) + 1, Username: username, Score: int(score)}
+	return lb, nil
+}
+
+// Rebuild пересобирает все рейтинги из базы. Каждый ключ собирается во временном
+// ключе и подменяется атомарно, поэтому читатели не видят полусобранных рейтингов.
+// Ключи периодов, по которым в базе больше нет данных, удаляются.
+//
+// Снимок базы может оказаться старше событий, обработанных во время пересборки, и
+// подмена затёрла бы их счета. Поэтому перед снимком очищаются отметки HandleEvent,
+// а после подмены отмеченные за это время счета пересчитываются заново. Пересборку
+// ведёт только один экземпляр сервиса: остальные её пропускают.
+func (s *LeaderboardService) Rebuild() error {
+	ctx := context.Background()
+	token := strconv.FormatInt(time.Now().UnixNano(), 10)
+	locked, err := s.redis.SetNX(ctx, leaderboardLockKey, token, leaderboardRebuildLockTTL).Result()
+	if err != nil {
+		return fmt.Errorf("ошибка блокировки пересборки рейтингов: %w", err)
+	}
+	if !locked {
+		return nil
+	}
+	defer unlockScript.Run(ctx, s.redis, []string{leaderboardLockKey}, token)
+
+	if err := s.redis.Del(ctx, leaderboardDirtyKey).Err(); err != nil {
+		return fmt.Errorf("ошибка сброса отметок рейтинга: %w", err)
+	}
+	days, err := s.leaderboardRepo.GetTransferDays()
+	if err != nil {
+		return err
+	}
+	sizes, err := s.leaderboardRepo.GetInventorySizes()
+	if err != nil {
+		return err
+	}
+	boards := buildLeaderboards(days, sizes)
+
+	fresh := make(map[string]bool, len(boards))
+	for key, scores := range boards {
+		members := make([]*redis.Z, 0, len(scores))
+		for member, score := range scores {
+			members = append(members, &redis.Z{Score: float64(score), Member: member})
+		}
+		if err := s.replace(ctx, key, func(pipe redis.Pipeliner, tmp string) {
+			pipe.ZAdd(ctx, tmp, members...)
+		}); err != nil {
+			return err
+		}
+		fresh[key] = true
+	}
+
+	iter := s.redis.Scan(ctx, 0, leaderboardKeyPrefix+"*", 1000).Iterator()
+	var stale []string
+	for iter.Next(ctx) {
+		if !fresh[iter.Val()] {
+			stale = append(stale, iter.Val())
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return fmt.Errorf("ошибка обхода рейтингов: %w", err)
+	}
+	if len(stale) > 0 {
+		if err := s.redis.Del(ctx, stale...).Err(); err != nil {
+			return fmt.Errorf("ошибка удаления устаревших рейтингов: %w", err)
+		}
+	}
+
+	marks, err := s.redis.SMembers(ctx, leaderboardDirtyKey).Result()
+	if err != nil {
+		return fmt.Errorf("ошибка получения отметок рейтинга: %w", err)
+	}
+	var errs []error
+	for _, mark := range marks {
+		errs = append(errs, s.refresh(ctx, mark))
+	}
+	return errors.Join(errs...)
+}
+
+// replace заполняет временный ключ через fill и атомарно подменяет им key
+func (s *LeaderboardService) replace(ctx context.Context, key string, fill func(pipe redis.Pipeliner, tmp string)) error {
+	tmp := key + ":rebuild"
+	_, err := s.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, tmp)
+		fill(pipe, tmp)
+		pipe.Rename(ctx, tmp, key)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("ошибка пересборки рейтинга %s: %w", key, err)
+	}
+	return nil
+}
+
+// buildLeaderboards раскладывает дневные суммы переводов и размеры инвентаря по
+// ключам рейтингов: ключ -> участник -> счёт
+func buildLeaderboards(days []models.TransferDay, sizes map[string]int) map[string]map[string]int {
+	boards := make(map[string]map[string]int)
+	add := func(key, member string, score int) {
+		if boards[key] == nil {
+			boards[key] = make(map[string]int)
+		}
+		boards[key][member] += score
+	}
+	// коллеги, которым уже засчитан перевод: ключ рейтинга thanked -> пара отправитель/получатель
+	thanked := make(map[string]map[[2]string]bool)
+
+	for _, d := range days {
+		for _, period := range leaderboardPeriods {
+			bucket := periodBucket(period, d.Day)
+			add(leaderboardKey(models.LeaderboardReceived, bucket), d.To, d.Amount)
+			add(leaderboardKey(models.LeaderboardSent, bucket), d.From, d.Amount)
+			key := leaderboardKey(models.LeaderboardThanked, bucket)
+			if thanked[key] == nil {
+				thanked[key] = make(map[[2]string]bool)
+			}
+			if pair := [2]string{d.From, d.To}; !thanked[key][pair] {
+				thanked[key][pair] = true
+				add(key, d.From, 1)
+			}
+		}
+	}
+	for username, n := range sizes {
+		add(leaderboardKey(models.LeaderboardCollectors, models.PeriodAll), username, n)
+	}
+	return boards
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/itocode21/MerchServiceAvito/internal/config"
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+	"github.com/itocode21/MerchServiceAvito/internal/repositories"
+)
+
+func TestPeriodBucket(t *testing.T) {
+	at := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		period string
+		want   string
+	}{
+		{models.PeriodWeek, "2026-W01"},
+		{models.PeriodMonth, "2026-01"},
+		{models.PeriodYear, "2026"},
+		{models.PeriodAll, "all"},
+	}
+	for _, tt := range tests {
+		if got := periodBucket(tt.period, at); got != tt.want {
+			t.Errorf("periodBucket(%s) = %s, want %s", tt.period, got, tt.want)
+		}
+	}
+}
+
+func TestPeriodRange(t *testing.T) {
+	at := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		period   string
+		from, to time.Time
+	}{
+		{models.PeriodWeek, time.Date(2025, 12, 29, 0, 0, 0, 0, time.UTC), time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)},
+		{models.PeriodMonth, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{models.PeriodYear, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{models.PeriodAll, time.Time{}, time.Time{}},
+	}
+	for _, tt := range tests {
+		got := periodRange(tt.period, at)
+		if !got.From.Equal(tt.from) || !got.To.Equal(tt.to) {
+			t.Errorf("periodRange(%s) = [%v, %v), want [%v, %v)", tt.period, got.From, got.To, tt.from, tt.to)
+		}
+		// Границы должны совпадать с периодом, который показывает GetLeaderboard
+		if tt.period != models.PeriodAll && periodBucket(tt.period, got.From) != periodBucket(tt.period, at) {
+			t.Errorf("periodRange(%s) начинается в другом периоде: %s", tt.period, periodBucket(tt.period, got.From))
+		}
+	}
+}
+
+func TestTransfersMark(t *testing.T) {
+	msk := time.FixedZone("MSK", 3*60*60)
+	at := time.Date(2026, 10, 5, 1, 30, 0, 0, msk)
+	mark := transfersMark("ivan|petrov", at)
+	if want := "transfers|ivan|petrov|2026-10-05T00:00:00+03:00"; mark != want {
+		t.Fatalf("transfersMark() = %s, want %s", mark, want)
+	}
+	// День отметки разбирается в том же поясе, поэтому попадает в те же периоды
+	kind, rest, _ := strings.Cut(mark, "|")
+	i := strings.LastIndex(rest, "|")
+	day, err := time.Parse(time.RFC3339, rest[i+1:])
+	if kind != transfersMarkKind || rest[:i] != "ivan|petrov" || err != nil {
+		t.Fatalf("отметка %s разобрана как %s, %s, %v", mark, kind, rest[:i], err)
+	}
+	for _, period := range leaderboardPeriods {
+		if got, want := periodRange(period, day), periodRange(period, at); !got.From.Equal(want.From) {
+			t.Errorf("periodRange(%s) по отметке = %v, want %v", period, got.From, want.From)
+		}
+	}
+}
+
+func TestBuildLeaderboards(t *testing.T) {
+	sep30 := time.Date(2026, 9, 30, 0, 0, 0, 0, time.UTC)
+	oct1 := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	oct2 := time.Date(2026, 10, 2, 0, 0, 0, 0, time.UTC)
+	days := []models.TransferDay{
+		{Day: sep30, From: "alice", To: "bob", Amount: 100},
+		{Day: oct1, From: "alice", To: "bob", Amount: 50},
+		{Day: oct2, From: "alice", To: "carol", Amount: 30},
+		{Day: oct2, From: "bob", To: "carol", Amount: 20},
+	}
+	boards := buildLeaderboards(days, map[string]int{"carol": 3})
+
+	checks := []struct {
+		key, member string
+		want        int
+	}{
+		{"leaderboard:received:2026-10", "bob", 50},
+		{"leaderboard:received:2026-10", "carol", 50},
+		{"leaderboard:received:2026-09", "bob", 100},
+		{"leaderboard:received:all", "bob", 150},
+		{"leaderboard:sent:2026-10", "alice", 80},
+		{"leaderboard:thanked:2026-10", "alice", 2},
+		{"leaderboard:thanked:all", "alice", 2},
+		{"leaderboard:thanked:2026-09", "alice", 1},
+		{"leaderboard:thanked:2026-10", "bob", 1},
+		{"leaderboard:collectors:all", "carol", 3},
+	}
+	for _, c := range checks {
+		if got := boards[c.key][c.member]; got != c.want {
+			t.Errorf("%s[%s] = %d, want %d", c.key, c.member, got, c.want)
+		}
+	}
+	if _, ok := boards["leaderboard:collectors:2026-10"]; ok {
+		t.Errorf("рейтинг collectors не должен делиться на периоды")
+	}
+}
+
+func TestGetLeaderboardValidation(t *testing.T) {
+	userRepo := repositories.NewUserRepository(&config.Config{})
+	service := NewLeaderboardService(repositories.NewLeaderboardRepository(nil), userRepo)
+
+	tests := []struct {
+		name   string
+		board  string
+		period string
+		limit  int
+		errMsg string
+	}{
+		{"Неизвестный рейтинг", "richest", models.PeriodMonth, 0, "неизвестный рейтинг: richest"},
+		{"Неизвестный период", models.LeaderboardSent, "decade", 0, "неизвестный период: decade"},
+		{"Коллекционеры только за всё время", models.LeaderboardCollectors, models.PeriodMonth, 0,
+			"рейтинг collectors считается только за всё время (period=all)"},
+		{"Слишком большой limit", models.LeaderboardReceived, models.PeriodAll, 1000, "limit должен быть от 1 до 100"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.GetLeaderboard(tt.board, tt.period, time.Now(), "alice", tt.limit)
+			if err == nil || !IsUserError(err) || err.Error() != tt.errMsg {
+				t.Errorf("GetLeaderboard() error = %v, want %q", err, tt.errMsg)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+	"github.com/itocode21/MerchServiceAvito/internal/repositories"
+)
+
+const (
+	notificationsLimit    = 50
+	notificationsMaxLimit = 200
+	userAgentMaxLength    = 200
+)
+
+type NotificationService struct {
+	notificationRepo *repositories.NotificationRepository
+	userRepo         *repositories.UserRepository
+}
+
+func NewNotificationService(notificationRepo *repositories.NotificationRepository, userRepo *repositories.UserRepository) *NotificationService {
+	return &NotificationService{
+		notificationRepo: notificationRepo,
+		userRepo:         userRepo,
+	}
+}
+
+func (s *NotificationService) getUser(username string) (*models.User, error) {
+	user, err := s.userRepo.GetUserByUsername(username)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении пользователя: %w", err)
+	}
+	if user == nil {
+		return nil, userErrorf("пользователь %s не найден", username)
+	}
+	return user, nil
+}
+
+// GetNotifications возвращает страницу уведомлений пользователя, новые первыми
+func (s *NotificationService) GetNotifications(username string, unreadOnly bool, limit, offset int) ([]models.Notification, error) {
+	if limit == 0 {
+		limit = notificationsLimit
+	}
+	if limit < 0 || limit > notificationsMaxLimit {
+		return nil, userErrorf("limit должен быть от 1 до %d", notificationsMaxLimit)
+	}
+	if offset < 0 {
+		return nil, userErrorf("offset не может быть отрицательным")
+	}
+	user, err := s.getUser(username)
+	if err != nil {
+		return nil, err
+	}
+	return s.notificationRepo.GetUserNotifications(user.ID, unreadOnly, limit, offset)
+}
+
+func (s *NotificationService) CountUnread(username string) (int, error) {
+	user, err := s.getUser(username)
+	if err != nil {
+		return 0, err
+	}
+	return s.notificationRepo.CountUnread(user.ID)
+}
+
+func (s *NotificationService) MarkRead(username string, id int) error {
+	user, err := s.getUser(username)
+	if err != nil {
+		return err
+	}
+	marked, err := s.notificationRepo.MarkRead(user.ID, id)
+	if err != nil {
+		return err
+	}
+	if !marked {
+		return userErrorf("уведомление %d не найдено", id)
+	}
+	return nil
+}
+
+// MarkAllRead отмечает прочитанными все уведомления и возвращает, сколько их было
+func (s *NotificationService) MarkAllRead(username string) (int64, error) {
+	user, err := s.getUser(username)
+	if err != nil {
+		return 0, err
+	}
+	return s.notificationRepo.MarkAllRead(user.ID)
+}
+
+// GetPreferences возвращает для каждого вида уведомлений, получает ли их пользователь
+func (s *NotificationService) GetPreferences(username string) ([]models.NotificationPreference, error) {
+	user, err := s.getUser(username)
+	if err != nil {
+		return nil, err
+	}
+	prefs, err := s.notificationRepo.GetPreferences(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]models.NotificationPreference, 0, len(models.NotificationKinds))
+	for _, kind := range models.NotificationKinds {
+		enabled, ok := prefs[kind]
+		result = append(result, models.NotificationPreference{Kind: kind, Enabled: enabled || !ok})
+	}
+	return result, nil
+}
+
+// SetPreferences включает и отключает виды уведомлений; не упомянутые виды не меняются
+func (s *NotificationService) SetPreferences(username string, prefs map[string]bool) ([]models.NotificationPreference, error) {
+	if len(prefs) == 0 {
+		return nil, userErrorf("не указаны настройки уведомлений")
+	}
+	for kind := range prefs {
+		if !slices.Contains(models.NotificationKinds, kind) {
+			return nil, userErrorf("неизвестный вид уведомлений %s", kind)
+		}
+	}
+	user, err := s.getUser(username)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.userRepo.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback()
+	if err := s.notificationRepo.SetPreferencesTx(tx, user.ID, prefs); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+	return s.GetPreferences(username)
+}
+
+// RecordLogin запоминает устройство, с которого вошёл пользователь, и уведомляет
+// о входе с нового устройства. Первое устройство пользователя уведомления не даёт.
+func (s *NotificationService) RecordLogin(username, ip, userAgent string) error {
+	user, err := s.getUser(username)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.userRepo.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	added, hadDevices, err := s.notificationRepo.RememberDeviceTx(tx, user.ID, hashToken(ip+" "+userAgent))
+	if err != nil {
+		return err
+	}
+	if added && hadDevices {
+		if userAgent == "" {
+			userAgent = "неизвестное устройство"
+		}
+		notification := &models.Notification{
+			UserID:  user.ID,
+			Kind:    models.NotificationNewLogin,
+			Message: fmt.Sprintf("Новый вход в аккаунт: %s, IP %s", truncate(userAgent, userAgentMaxLength), ip),
+		}
+		if err := s.notificationRepo.CreateNotificationTx(tx, notification); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+	return nil
+}
+
+// NotifyPasswordChanged сообщает пользователю о смене пароля
+func (s *NotificationService) NotifyPasswordChanged(username string) error {
+	user, err := s.getUser(username)
+	if err != nil {
+		return err
+	}
+	notification := &models.Notification{
+		UserID:  user.ID,
+		Kind:    models.NotificationPasswordChanged,
+		Message: "Пароль от аккаунта изменён. Если это были не вы, обратитесь к администратору",
+	}
+	return s.notificationRepo.CreateNotification(notification)
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/itocode21/MerchServiceAvito/internal/config"
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+	"github.com/itocode21/MerchServiceAvito/internal/repositories"
+)
+
+func TestRecordLogin(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка создания мока: %v", err)
+	}
+	defer db.Close()
+
+	userRepo := repositories.NewUserRepository(&config.Config{DB: db})
+	service := NewNotificationService(repositories.NewNotificationRepository(db), userRepo)
+	userQuery := "SELECT id, username, password_hash, coins, is_active FROM users WHERE username = \\$1"
+	userColumns := []string{"id", "username", "password_hash", "coins", "is_active"}
+
+	tests := []struct {
+		name       string
+		added      bool
+		hadDevices bool
+		notify     bool
+	}{
+		{name: "Новое устройство", added: true, hadDevices: true, notify: true},
+		{name: "Первый вход", added: true, hadDevices: false},
+		{name: "Знакомое устройство", added: false, hadDevices: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock.ExpectQuery(userQuery).
+				WithArgs("alice").
+				WillReturnRows(sqlmock.NewRows(userColumns).AddRow(1, "alice", "hash", 1000, true))
+			mock.ExpectBegin()
+			mock.ExpectQuery("INSERT INTO user_devices").
+				WithArgs(1, hashToken("10.0.0.1 Firefox")).
+				WillReturnRows(sqlmock.NewRows([]string{"added", "had_devices"}).AddRow(tt.added, tt.hadDevices))
+			if tt.notify {
+				mock.ExpectQuery("INSERT INTO notifications").
+					WithArgs(1, models.NotificationNewLogin, "Новый вход в аккаунт: Firefox, IP 10.0.0.1").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+			}
+			mock.ExpectCommit()
+
+			if err := service.RecordLogin("alice", "10.0.0.1", "Firefox"); err != nil {
+				t.Errorf("RecordLogin() error = %v, want nil", err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Не все ожидания мока выполнены: %v", err)
+			}
+		})
+	}
+}
+
+func TestSetNotificationPreferences(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка создания мока: %v", err)
+	}
+	defer db.Close()
+
+	userRepo := repositories.NewUserRepository(&config.Config{DB: db})
+	service := NewNotificationService(repositories.NewNotificationRepository(db), userRepo)
+	userQuery := "SELECT id, username, password_hash, coins, is_active FROM users WHERE username = \\$1"
+	userColumns := []string{"id", "username", "password_hash", "coins", "is_active"}
+
+	if _, err := service.SetPreferences("alice", map[string]bool{"order_shipped": false}); err == nil || err.Error() != "неизвестный вид уведомлений order_shipped" {
+		t.Errorf("SetPreferences() error = %v, want неизвестный вид уведомлений", err)
+	}
+
+	mock.ExpectQuery(userQuery).
+		WithArgs("alice").
+		WillReturnRows(sqlmock.NewRows(userColumns).AddRow(1, "alice", "hash", 1000, true))
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO notification_preferences").
+		WithArgs(1, models.NotificationWishlistPriceDrop, false).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery(userQuery).
+		WithArgs("alice").
+		WillReturnRows(sqlmock.NewRows(userColumns).AddRow(1, "alice", "hash", 1000, true))
+	mock.ExpectQuery("SELECT kind, enabled FROM notification_preferences WHERE user_id = \\$1").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"kind", "enabled"}).
+			AddRow(models.NotificationWishlistPriceDrop, false).
+			AddRow(models.NotificationNewLogin, true))
+
+	prefs, err := service.SetPreferences("alice", map[string]bool{models.NotificationWishlistPriceDrop: false})
+	if err != nil {
+		t.Fatalf("SetPreferences() error = %v, want nil", err)
+	}
+	if len(prefs) != len(models.NotificationKinds) {
+		t.Fatalf("SetPreferences() вернул %d настроек, want %d", len(prefs), len(models.NotificationKinds))
+	}
+	for _, p := range prefs {
+		if p.Enabled != (p.Kind != models.NotificationWishlistPriceDrop) {
+			t.Errorf("Настройка %s = %v", p.Kind, p.Enabled)
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Не все ожидания мока выполнены: %v", err)
+	}
+}
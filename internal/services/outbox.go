@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/itocode21/MerchServiceAvito/internal/events"
+	"github.com/itocode21/MerchServiceAvito/internal/repositories"
+)
+
+const (
+	outboxRelayBatch    = 100
+	outboxRetentionDays = 7
+)
+
+// OutboxRelay публикует события outbox во внешний sink. Ретранслирует только один
+// экземпляр сервиса (advisory-блокировка), события идут в порядке записи. Если
+// событие пользователя не удалось опубликовать, его следующие события ждут
+// следующего запуска, так что порядок по каждому пользователю сохраняется.
+type OutboxRelay struct {
+	outboxRepo *repositories.OutboxRepository
+	sink       events.Sink
+	db         *sql.DB
+}
+
+func NewOutboxRelay(outboxRepo *repositories.OutboxRepository, sink events.Sink, userRepo *repositories.UserRepository) *OutboxRelay {
+	return &OutboxRelay{
+		outboxRepo: outboxRepo,
+		sink:       sink,
+		db:         userRepo.DB,
+	}
+}
+
+// RelayOnce публикует очередную пачку событий и возвращает, сколько опубликовано.
+// Событие отмечается опубликованным только после успешной записи в sink, поэтому
+// при сбое между ними оно будет опубликовано повторно.
+func (r *OutboxRelay) RelayOnce(ctx context.Context) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	locked, err := r.outboxRepo.TryLockRelayTx(tx)
+	if err != nil || !locked {
+		return 0, err
+	}
+	pending, err := r.outboxRepo.GetPendingTx(tx, outboxRelayBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	var published []int64
+	var publishErr error
+	blocked := make(map[int]bool)
+	for _, e := range pending {
+		if blocked[e.UserID] {
+			continue
+		}
+		if err := r.sink.Publish(ctx, e); err != nil {
+			blocked[e.UserID] = true
+			if publishErr == nil {
+				publishErr = fmt.Errorf("событие %d: %w", e.ID, err)
+			}
+			continue
+		}
+		published = append(published, e.ID)
+	}
+
+	if len(published) > 0 {
+		if err := r.outboxRepo.MarkPublishedTx(tx, published); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+	return len(published), publishErr
+}
+
+// Purge удаляет давно опубликованные события
+func (r *OutboxRelay) Purge() (int64, error) {
+	return r.outboxRepo.DeletePublished(outboxRetentionDays)
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/itocode21/MerchServiceAvito/internal/config"
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+	"github.com/itocode21/MerchServiceAvito/internal/repositories"
+	"github.com/lib/pq"
+)
+
+// fakeSink запоминает опубликованные события и отказывает на событиях из fail
+type fakeSink struct {
+	fail      map[int64]bool
+	published []int64
+}
+
+func (s *fakeSink) Publish(ctx context.Context, e models.OutboxEvent) error {
+	if s.fail[e.ID] {
+		return errors.New("sink недоступен")
+	}
+	s.published = append(s.published, e.ID)
+	return nil
+}
+
+func TestRelayOnce(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка создания мока: %v", err)
+	}
+	defer db.Close()
+
+	userRepo := repositories.NewUserRepository(&config.Config{DB: db})
+	outboxColumns := []string{"id", "type", "user_id", "payload", "created_at"}
+	now := time.Now()
+
+	tests := []struct {
+		name          string
+		fail          map[int64]bool
+		setupMock     func()
+		wantPublished []int64
+		wantErr       bool
+	}{
+		{
+			name: "События публикуются по порядку",
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT pg_try_advisory_xact_lock").
+					WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(true))
+				mock.ExpectQuery("FROM outbox_events").
+					WithArgs(outboxRelayBatch).
+					WillReturnRows(sqlmock.NewRows(outboxColumns).
+						AddRow(1, models.EventUserRegistered, 1, []byte(`{}`), now).
+						AddRow(2, models.EventCoinsTransferred, 1, []byte(`{}`), now).
+						AddRow(3, models.EventItemPurchased, 2, []byte(`{}`), now))
+				mock.ExpectExec("UPDATE outbox_events SET published_at = NOW\\(\\) WHERE id = ANY\\(\\$1\\)").
+					WithArgs(pq.Array([]int64{1, 2, 3})).
+					WillReturnResult(sqlmock.NewResult(0, 3))
+				mock.ExpectCommit()
+			},
+			wantPublished: []int64{1, 2, 3},
+		},
+		{
+			name: "Сбой публикации задерживает следующие события пользователя",
+			fail: map[int64]bool{1: true},
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT pg_try_advisory_xact_lock").
+					WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(true))
+				mock.ExpectQuery("FROM outbox_events").
+					WithArgs(outboxRelayBatch).
+					WillReturnRows(sqlmock.NewRows(outboxColumns).
+						AddRow(1, models.EventCoinsTransferred, 1, []byte(`{}`), now).
+						AddRow(2, models.EventItemPurchased, 2, []byte(`{}`), now).
+						AddRow(3, models.EventCoinsTransferred, 1, []byte(`{}`), now))
+				mock.ExpectExec("UPDATE outbox_events SET published_at").
+					WithArgs(pq.Array([]int64{2})).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			wantPublished: []int64{2},
+			wantErr:       true,
+		},
+		{
+			name: "Ретранслирует другой экземпляр",
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT pg_try_advisory_xact_lock").
+					WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(false))
+				mock.ExpectRollback()
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+			sink := &fakeSink{fail: tt.fail}
+			relay := NewOutboxRelay(repositories.NewOutboxRepository(db), sink, userRepo)
+			n, err := relay.RelayOnce(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RelayOnce() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if n != len(tt.wantPublished) || !reflect.DeepEqual(sink.published, tt.wantPublished) {
+				t.Errorf("RelayOnce() = %d, опубликованы %v, want %v", n, sink.published, tt.wantPublished)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Не все ожидания мока выполнены: %v", err)
+			}
+		})
+	}
+}
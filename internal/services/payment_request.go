@@ -0,0 +1,177 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+	"github.com/itocode21/MerchServiceAvito/internal/repositories"
+)
+
+const (
+	defaultPaymentRequestTTL = 72 * time.Hour
+	maxPaymentRequestMemo    = 255
+)
+
+type PaymentRequestService struct {
+	requestRepo  *repositories.PaymentRequestRepository
+	userRepo     *repositories.UserRepository
+	transService *TransactionService
+	db           *sql.DB
+}
+
+func NewPaymentRequestService(requestRepo *repositories.PaymentRequestRepository, userRepo *repositories.UserRepository, transService *TransactionService) *PaymentRequestService {
+	return &PaymentRequestService{
+		requestRepo:  requestRepo,
+		userRepo:     userRepo,
+		transService: transService,
+		db:           userRepo.DB,
+	}
+}
+
+func (s *PaymentRequestService) ttl() time.Duration {
+	if ttl := s.userRepo.Config.PaymentRequestTTL; ttl > 0 {
+		return ttl
+	}
+	return defaultPaymentRequestTTL
+}
+
+// CreateRequest создаёт запрос: requesterUsername просит у payerUsername amount монет
+func (s *PaymentRequestService) CreateRequest(requesterUsername, payerUsername string, amount int, memo string) (*models.PaymentRequest, error) {
+	if requesterUsername == payerUsername {
+		return nil, userErrorf("нельзя запросить монеты у самого себя")
+	}
+	if err := s.transService.checkAmount(amount); err != nil {
+		return nil, err
+	}
+	memo = strings.TrimSpace(memo)
+	if utf8.RuneCountInString(memo) > maxPaymentRequestMemo {
+		return nil, userErrorf("комментарий длиннее %d символов", maxPaymentRequestMemo)
+	}
+
+	requester, err := s.userRepo.GetUserByUsername(requesterUsername)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении пользователя: %w", err)
+	}
+	if requester == nil {
+		return nil, userErrorf("пользователь %s не найден", requesterUsername)
+	}
+	payer, err := s.userRepo.GetUserByUsername(payerUsername)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении пользователя: %w", err)
+	}
+	if payer == nil {
+		return nil, userErrorf("пользователь %s не найден", payerUsername)
+	}
+	if !payer.IsActive {
+		return nil, userErrorf("пользователь %s деактивирован", payerUsername)
+	}
+
+	pr := &models.PaymentRequest{
+		RequesterID: requester.ID,
+		PayerID:     payer.ID,
+		Requester:   requester.Username,
+		Payer:       payer.Username,
+		Amount:      amount,
+		Memo:        memo,
+		ExpiresAt:   time.Now().Add(s.ttl()),
+	}
+	if err := s.requestRepo.CreatePaymentRequest(pr); err != nil {
+		return nil, err
+	}
+	return pr, nil
+}
+
+func (s *PaymentRequestService) GetRequests(username string) (incoming, outgoing []models.PaymentRequest, err error) {
+	user, err := s.userRepo.GetUserByUsername(username)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ошибка при получении пользователя: %w", err)
+	}
+	if user == nil {
+		return nil, nil, userErrorf("пользователь %s не найден", username)
+	}
+	return s.requestRepo.GetUserPaymentRequests(user.ID)
+}
+
+// ApproveRequest переводит монеты плательщика автору запроса. Перевод и смена статуса
+// выполняются в одной транзакции, поэтому запрос не может быть оплачен дважды.
+func (s *PaymentRequestService) ApproveRequest(payerUsername string, id int) (*models.PaymentRequest, error) {
+	var pr *models.PaymentRequest
+	var transaction *models.Transaction
+	err := withTxRetry(func() error {
+		var err error
+		pr, err = s.resolveRequest(payerUsername, id, func(tx *sql.Tx, pr *models.PaymentRequest) error {
+			transaction = &models.Transaction{Amount: pr.Amount}
+			if err := s.transService.TransferTx(tx, pr.Payer, pr.Requester, transaction); err != nil {
+				return err
+			}
+			pr.Status = models.PaymentRequestApproved
+			pr.TransactionID = transaction.ID
+			return nil
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.transService.publishSent(pr.Payer, pr.Requester, transaction)
+	return pr, nil
+}
+
+func (s *PaymentRequestService) DeclineRequest(payerUsername string, id int) (*models.PaymentRequest, error) {
+	var pr *models.PaymentRequest
+	err := withTxRetry(func() error {
+		var err error
+		pr, err = s.resolveRequest(payerUsername, id, func(tx *sql.Tx, pr *models.PaymentRequest) error {
+			pr.Status = models.PaymentRequestDeclined
+			return nil
+		})
+		return err
+	})
+	return pr, err
+}
+
+// resolveRequest блокирует ожидающий запрос плательщика, применяет к нему resolve
+// и сохраняет новый статус. Просроченный запрос сохраняется как expired.
+func (s *PaymentRequestService) resolveRequest(payerUsername string, id int, resolve func(*sql.Tx, *models.PaymentRequest) error) (*models.PaymentRequest, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	pr, err := s.requestRepo.LockPaymentRequestTx(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if pr == nil || pr.Payer != payerUsername {
+		return nil, userErrorf("запрос монет %d не найден", id)
+	}
+
+	if pr.Status == models.PaymentRequestExpired && pr.ResolvedAt == nil {
+		if err := s.requestRepo.ResolvePaymentRequestTx(tx, pr); err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("ошибка фиксации транзакции: %w", err)
+		}
+		return nil, userErrorf("запрос монет %d истёк", id)
+	}
+	if pr.Status != models.PaymentRequestPending {
+		return nil, userErrorf("запрос монет %d уже обработан: %s", id, pr.Status)
+	}
+
+	if err := resolve(tx, pr); err != nil {
+		return nil, err
+	}
+	if err := s.requestRepo.ResolvePaymentRequestTx(tx, pr); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+	return pr, nil
+}
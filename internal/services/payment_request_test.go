@@ -0,0 +1,165 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/itocode21/MerchServiceAvito/internal/config"
+	"github.com/itocode21/MerchServiceAvito/internal/events"
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+	"github.com/itocode21/MerchServiceAvito/internal/repositories"
+)
+
+func TestApproveRequest(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка создания мока: %v", err)
+	}
+	defer db.Close()
+
+	cfg := &config.Config{DB: db, Events: events.NewBus()}
+	var sent []events.Event
+	cfg.Events.Subscribe(events.CoinsSent, "test", func(e events.Event) error {
+		sent = append(sent, e)
+		return nil
+	})
+	userRepo := repositories.NewUserRepository(cfg)
+	transRepo := repositories.NewTransactionRepository(db)
+	transService := NewTransactionService(userRepo, transRepo, repositories.NewOutboxRepository(db), repositories.NewNotificationRepository(db))
+	service := NewPaymentRequestService(repositories.NewPaymentRequestRepository(db), userRepo, transService)
+
+	requestQuery := "SELECT pr.id, .* FROM payment_requests pr .* WHERE pr.id = \\$1 FOR UPDATE OF pr"
+	requestColumns := []string{"id", "requester_id", "payer_id", "requester", "payer", "amount", "memo",
+		"status", "transaction_id", "created_at", "expires_at", "resolved_at"}
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		payer     string
+		setupMock func()
+		wantErr   bool
+		errMsg    string
+	}{
+		{
+			name:  "Успешное подтверждение",
+			payer: "payer",
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(requestQuery).
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows(requestColumns).
+						AddRow(1, 2, 1, "requester", "payer", 150, "пицца", "pending", 0, now, now.Add(time.Hour), nil))
+				mock.ExpectQuery("SELECT id, username, coins, held_coins, is_active FROM users").
+					WithArgs(sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "username", "coins", "held_coins", "is_active"}).
+						AddRow(1, "payer", 1000, 0, true).
+						AddRow(2, "requester", 500, 0, true))
+				mock.ExpectExec("UPDATE users SET coins = \\$1 WHERE id = \\$2").
+					WithArgs(850, 1).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec("UPDATE users SET coins = \\$1 WHERE id = \\$2").
+					WithArgs(650, 2).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectQuery("INSERT INTO transactions").
+					WithArgs(1, 2, 150, "transfer", nil).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(7, now))
+				mock.ExpectExec("INSERT INTO outbox_events").WithArgs(models.EventCoinsTransferred, sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectQuery("INSERT INTO notifications").WithArgs(sqlmock.AnyArg(), models.NotificationCoinsReceived, sqlmock.AnyArg()).WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+				mock.ExpectExec("UPDATE payment_requests SET status = \\$1, transaction_id = \\$2, resolved_at = \\$3").
+					WithArgs("approved", 7, sqlmock.AnyArg(), 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			wantErr: false,
+		},
+		{
+			name:  "Чужой запрос",
+			payer: "stranger",
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(requestQuery).
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows(requestColumns).
+						AddRow(1, 2, 1, "requester", "payer", 150, "", "pending", 0, now, now.Add(time.Hour), nil))
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+			errMsg:  "запрос монет 1 не найден",
+		},
+		{
+			name:  "Запрос уже отклонён",
+			payer: "payer",
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(requestQuery).
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows(requestColumns).
+						AddRow(1, 2, 1, "requester", "payer", 150, "", "declined", 0, now, now.Add(time.Hour), now))
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+			errMsg:  "запрос монет 1 уже обработан: declined",
+		},
+		{
+			name:  "Запрос истёк",
+			payer: "payer",
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(requestQuery).
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows(requestColumns).
+						AddRow(1, 2, 1, "requester", "payer", 150, "", "expired", 0, now.Add(-2*time.Hour), now.Add(-time.Hour), nil))
+				mock.ExpectExec("UPDATE payment_requests SET status = \\$1").
+					WithArgs("expired", nil, sqlmock.AnyArg(), 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			wantErr: true,
+			errMsg:  "запрос монет 1 истёк",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+			sent = nil
+			pr, err := service.ApproveRequest(tt.payer, 1)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ApproveRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && err != nil && err.Error() != tt.errMsg {
+				t.Errorf("ApproveRequest() error message = %v, want %v", err.Error(), tt.errMsg)
+			}
+			if !tt.wantErr && (pr.Status != "approved" || pr.TransactionID != 7) {
+				t.Errorf("ApproveRequest() = %+v, want approved with transaction 7", pr)
+			}
+			if wantSent := !tt.wantErr; (len(sent) == 1) != wantSent {
+				t.Errorf("ApproveRequest() опубликовал %d событий о переводе, want %v", len(sent), wantSent)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Не все ожидания мока выполнены: %v", err)
+			}
+		})
+	}
+}
+
+func TestCreateRequestValidation(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка создания мока: %v", err)
+	}
+	defer db.Close()
+
+	cfg := &config.Config{DB: db}
+	userRepo := repositories.NewUserRepository(cfg)
+	transService := NewTransactionService(userRepo, repositories.NewTransactionRepository(db), repositories.NewOutboxRepository(db), repositories.NewNotificationRepository(db))
+	service := NewPaymentRequestService(repositories.NewPaymentRequestRepository(db), userRepo, transService)
+
+	if _, err := service.CreateRequest("user1", "user1", 100, ""); err == nil || err.Error() != "нельзя запросить монеты у самого себя" {
+		t.Errorf("CreateRequest() error = %v, want self-request error", err)
+	}
+	if _, err := service.CreateRequest("user1", "user2", 0, ""); !IsUserError(err) {
+		t.Errorf("CreateRequest() error = %v, want user error", err)
+	}
+}
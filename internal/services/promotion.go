@@ -0,0 +1,253 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+	"github.com/itocode21/MerchServiceAvito/internal/repositories"
+)
+
+const maxPromoCode = 64
+
+// PromotionService рассчитывает цену предмета с учётом скидок и промокодов.
+//
+// Правила сложения: скидки без флага stackable не суммируются ни с чем — из них
+// выбирается самая выгодная. Все применимые stackable-скидки суммируются: сначала
+// процентные, затем фиксированные. Покупатель получает лучший из двух вариантов.
+type PromotionService struct {
+	promotionRepo *repositories.PromotionRepository
+	itemRepo      *repositories.ItemRepository
+	userRepo      *repositories.UserRepository
+	db            *sql.DB
+}
+
+func NewPromotionService(promotionRepo *repositories.PromotionRepository, itemRepo *repositories.ItemRepository, userRepo *repositories.UserRepository) *PromotionService {
+	return &PromotionService{
+		promotionRepo: promotionRepo,
+		itemRepo:      itemRepo,
+		userRepo:      userRepo,
+		db:            userRepo.DB,
+	}
+}
+
+// CreatePromotion заводит скидку; p.Item — название предмета, если скидка на один предмет.
+// Нулевой StartsAt означает «с текущего момента».
+func (s *PromotionService) CreatePromotion(p *models.Promotion) error {
+	p.Code = normalizePromoCode(p.Code)
+	if len(p.Code) > maxPromoCode {
+		return userErrorf("промокод длиннее %d символов", maxPromoCode)
+	}
+	switch p.Kind {
+	case models.PromotionKindPercent:
+		if p.Value <= 0 || p.Value > 100 {
+			return userErrorf("процент скидки должен быть от 1 до 100")
+		}
+	case models.PromotionKindFixed:
+		if p.Value <= 0 || p.Value > maxAmount {
+			return userErrorf("размер скидки должен быть от 1 до %d", maxAmount)
+		}
+	default:
+		return userErrorf("неизвестный вид скидки: %s", p.Kind)
+	}
+	p.Category = strings.TrimSpace(p.Category)
+	if p.Item != "" && p.Category != "" {
+		return userErrorf("скидка действует либо на предмет, либо на категорию")
+	}
+	if p.Category != "" {
+		exists, err := s.itemRepo.CategoryExists(p.Category)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return userErrorf("категория %s не найдена", p.Category)
+		}
+	}
+	if p.MaxUses < 0 || p.PerUserLimit < 0 {
+		return userErrorf("лимит использований не может быть отрицательным")
+	}
+	if p.StartsAt.IsZero() {
+		p.StartsAt = time.Now()
+	}
+	if p.EndsAt != nil && !p.EndsAt.After(p.StartsAt) {
+		return userErrorf("скидка должна закончиться позже, чем начнётся")
+	}
+	if p.Item != "" {
+		item, err := s.itemRepo.GetItemByName(p.Item)
+		if err != nil {
+			return fmt.Errorf("ошибка получения предмета: %w", err)
+		}
+		if item == nil {
+			return userErrorf("предмет %s не найден", p.Item)
+		}
+		p.ItemID = item.ID
+	}
+	return s.promotionRepo.CreatePromotion(p)
+}
+
+func (s *PromotionService) GetPromotions() ([]*models.Promotion, error) {
+	return s.promotionRepo.GetPromotions()
+}
+
+func (s *PromotionService) DeactivatePromotion(id int) error {
+	found, err := s.promotionRepo.DeactivatePromotion(id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return userErrorf("скидка %d не найдена", id)
+	}
+	return nil
+}
+
+// Quote показывает, сколько пользователь заплатит за предмет с промокодом code
+func (s *PromotionService) Quote(username, itemName, code string) (*models.PriceQuote, error) {
+	user, err := s.userRepo.GetUserByUsername(username)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении пользователя: %w", err)
+	}
+	if user == nil {
+		return nil, userErrorf("пользователь %s не найден", username)
+	}
+	item, err := s.itemRepo.GetItemByName(itemName)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения предмета: %w", err)
+	}
+	if item == nil {
+		return nil, userErrorf("предмет %s не найден", itemName)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback()
+	return s.QuoteTx(tx, user.ID, item, code)
+}
+
+// QuoteTx рассчитывает цену предмета для пользователя. Неподходящий промокод —
+// ошибка пользователя; неподходящие автоматические скидки просто не применяются.
+func (s *PromotionService) QuoteTx(tx *sql.Tx, userID int, item *models.Item, code string) (*models.PriceQuote, error) {
+	code = normalizePromoCode(code)
+	candidates, err := s.promotionRepo.GetCandidatesTx(tx, userID, item.ID, code)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var applicable []*models.Promotion
+	codeFound := false
+	for _, p := range candidates {
+		if p.Code == "" {
+			if promotionExhausted(p) == "" {
+				applicable = append(applicable, p)
+			}
+			continue
+		}
+		codeFound = true
+		if err := checkPromoCode(p, item, now); err != nil {
+			return nil, err
+		}
+		applicable = append(applicable, p)
+	}
+	if code != "" && !codeFound {
+		return nil, userErrorf("промокод %s не найден", code)
+	}
+
+	return quotePrice(item.Price, applicable), nil
+}
+
+// RedeemTx учитывает применённые к покупке скидки
+func (s *PromotionService) RedeemTx(tx *sql.Tx, userID int, purchase *models.Purchase, quote *models.PriceQuote) error {
+	for _, applied := range quote.Promotions {
+		ok, err := s.promotionRepo.UseTx(tx, applied.PromotionID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			if applied.Code != "" {
+				return userErrorf("промокод %s больше не действует", applied.Code)
+			}
+			return userErrorf("скидка закончилась, обновите цену")
+		}
+		if err := s.promotionRepo.CreateRedemptionTx(tx, applied.PromotionID, userID, purchase.ID, applied.Discount); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkPromoCode(p *models.Promotion, item *models.Item, now time.Time) error {
+	if now.Before(p.StartsAt) {
+		return userErrorf("промокод %s ещё не действует", p.Code)
+	}
+	if p.EndsAt != nil && !now.Before(*p.EndsAt) {
+		return userErrorf("срок действия промокода %s истёк", p.Code)
+	}
+	if !p.InScope {
+		return userErrorf("промокод %s не действует на %s", p.Code, item.Name)
+	}
+	if reason := promotionExhausted(p); reason != "" {
+		return userErrorf("промокод %s %s", p.Code, reason)
+	}
+	return nil
+}
+
+// promotionExhausted возвращает причину, по которой лимит скидки исчерпан, или ""
+func promotionExhausted(p *models.Promotion) string {
+	if p.MaxUses > 0 && p.Uses >= p.MaxUses {
+		return "уже использован максимальное число раз"
+	}
+	if p.PerUserLimit > 0 && p.UserUses >= p.PerUserLimit {
+		return "уже использован вами максимальное число раз"
+	}
+	return ""
+}
+
+// quotePrice выбирает лучший для покупателя вариант: одна самая выгодная скидка
+// или сумма всех stackable-скидок
+func quotePrice(listPrice int, promotions []*models.Promotion) *models.PriceQuote {
+	quote := &models.PriceQuote{ListPrice: listPrice, Price: listPrice, Promotions: []models.AppliedPromotion{}}
+
+	var best *models.Promotion
+	for _, p := range promotions {
+		if best == nil || p.Apply(listPrice) < best.Apply(listPrice) {
+			best = p
+		}
+	}
+	if best != nil && best.Apply(listPrice) < listPrice {
+		quote.Price = best.Apply(listPrice)
+		quote.Promotions = []models.AppliedPromotion{{PromotionID: best.ID, Code: best.Code, Discount: listPrice - quote.Price}}
+	}
+
+	var stacked []models.AppliedPromotion
+	price := listPrice
+	for _, kind := range []string{models.PromotionKindPercent, models.PromotionKindFixed} {
+		for _, p := range promotions {
+			if !p.Stackable || p.Kind != kind || price == 0 {
+				continue
+			}
+			next := p.Apply(price)
+			stacked = append(stacked, models.AppliedPromotion{PromotionID: p.ID, Code: p.Code, Discount: price - next})
+			price = next
+		}
+	}
+	if len(stacked) > 1 && price < quote.Price {
+		quote.Price = price
+		quote.Promotions = stacked
+	}
+
+	quote.Discount = listPrice - quote.Price
+	for _, applied := range quote.Promotions {
+		if applied.Code != "" {
+			quote.Code = applied.Code
+		}
+	}
+	return quote
+}
+
+func normalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+)
+
+var promotionColumns = []string{"id", "code", "kind", "value", "item_id", "item", "category", "starts_at", "ends_at",
+	"max_uses", "per_user_limit", "uses", "stackable", "active", "created_at", "in_scope", "user_uses"}
+
+func TestQuotePrice(t *testing.T) {
+	percent := func(id, value int, stackable bool) *models.Promotion {
+		return &models.Promotion{ID: id, Kind: models.PromotionKindPercent, Value: value, Stackable: stackable}
+	}
+	fixed := func(id, value int, stackable bool) *models.Promotion {
+		return &models.Promotion{ID: id, Kind: models.PromotionKindFixed, Value: value, Stackable: stackable}
+	}
+
+	tests := []struct {
+		name       string
+		promotions []*models.Promotion
+		wantPrice  int
+		wantIDs    []int
+	}{
+		{
+			name:      "Без скидок",
+			wantPrice: 200,
+			wantIDs:   []int{},
+		},
+		{
+			name:       "Из несуммируемых выбирается самая выгодная",
+			promotions: []*models.Promotion{percent(1, 20, false), fixed(2, 50, false)},
+			wantPrice:  150,
+			wantIDs:    []int{2},
+		},
+		{
+			name:       "Суммируемые применяются по очереди: сначала проценты",
+			promotions: []*models.Promotion{fixed(1, 30, true), percent(2, 10, true), percent(3, 25, false)},
+			wantPrice:  150,
+			wantIDs:    []int{3},
+		},
+		{
+			name:       "Сумма суммируемых выгоднее одной скидки",
+			promotions: []*models.Promotion{fixed(1, 40, true), percent(2, 20, true), percent(3, 25, false)},
+			wantPrice:  120,
+			wantIDs:    []int{2, 1},
+		},
+		{
+			name:       "Цена не опускается ниже нуля",
+			promotions: []*models.Promotion{fixed(1, 500, false)},
+			wantPrice:  0,
+			wantIDs:    []int{1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quote := quotePrice(200, tt.promotions)
+			if quote.Price != tt.wantPrice || quote.Discount != 200-tt.wantPrice {
+				t.Errorf("quotePrice() price = %d, discount = %d, want price %d", quote.Price, quote.Discount, tt.wantPrice)
+			}
+			var ids []int
+			total := 0
+			for _, applied := range quote.Promotions {
+				ids = append(ids, applied.PromotionID)
+				total += applied.Discount
+			}
+			if len(ids) != len(tt.wantIDs) {
+				t.Fatalf("quotePrice() promotions = %v, want %v", ids, tt.wantIDs)
+			}
+			for i := range ids {
+				if ids[i] != tt.wantIDs[i] {
+					t.Errorf("quotePrice() promotions = %v, want %v", ids, tt.wantIDs)
+				}
+			}
+			if total != quote.Discount {
+				t.Errorf("quotePrice() sum of discounts = %d, want %d", total, quote.Discount)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"math/rand"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	txMaxAttempts    = 5
+	txRetryBaseDelay = 10 * time.Millisecond
+)
+
+// withTxRetry повторяет транзакцию fn, если Postgres прервал её из-за взаимоблокировки
+// (40P01) или конфликта сериализации (40001). Между попытками — экспоненциальная
+// задержка со случайной добавкой, чтобы конкурирующие запросы разошлись по времени.
+func withTxRetry(fn func() error) error {
+	var err error
+	for attempt := 0; attempt < txMaxAttempts; attempt++ {
+		err = fn()
+		if err == nil || !isRetryableTxError(err) {
+			return err
+		}
+		delay := txRetryBaseDelay << attempt
+		delay += time.Duration(rand.Int63n(int64(delay)))
+		log.Printf("Transaction conflict, retrying in %v (attempt %d): %v", delay, attempt+1, err)
+		time.Sleep(delay)
+	}
+	return err
+}
+
+func isRetryableTxError(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	return pqErr.Code == "40P01" || pqErr.Code == "40001"
+}
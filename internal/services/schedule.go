@@ -0,0 +1,309 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+	"github.com/itocode21/MerchServiceAvito/internal/repositories"
+)
+
+const (
+	maxScheduleHorizon = 365 * 24 * time.Hour
+	scheduleRunBatch   = 100
+	scheduleRunsLimit  = 50
+)
+
+type ScheduleService struct {
+	scheduleRepo     *repositories.ScheduleRepository
+	notificationRepo *repositories.NotificationRepository
+	userRepo         *repositories.UserRepository
+	transService     *TransactionService
+	db               *sql.DB
+}
+
+func NewScheduleService(scheduleRepo *repositories.ScheduleRepository, notificationRepo *repositories.NotificationRepository, userRepo *repositories.UserRepository, transService *TransactionService) *ScheduleService {
+	return &ScheduleService{
+		scheduleRepo:     scheduleRepo,
+		notificationRepo: notificationRepo,
+		userRepo:         userRepo,
+		transService:     transService,
+		db:               userRepo.DB,
+	}
+}
+
+// CreateSchedule планирует перевод на startAt (нулевое значение — ближайший запуск
+// фоновой задачи) с повторением recurrence
+func (s *ScheduleService) CreateSchedule(owner, toUsername string, amount int, recurrence string, startAt time.Time) (*models.ScheduledTransfer, error) {
+	if owner == toUsername {
+		return nil, userErrorf("нельзя отправить монеты самому себе")
+	}
+	if err := s.transService.checkAmount(amount); err != nil {
+		return nil, err
+	}
+	if recurrence == "" {
+		recurrence = models.RecurrenceOnce
+	}
+	if recurrence != models.RecurrenceOnce && recurrence != models.RecurrenceWeekly && recurrence != models.RecurrenceMonthly {
+		return nil, userErrorf("неизвестная периодичность %s", recurrence)
+	}
+	now := time.Now()
+	if startAt.IsZero() {
+		startAt = now
+	}
+	if startAt.Before(now.Add(-time.Minute)) || startAt.After(now.Add(maxScheduleHorizon)) {
+		return nil, userErrorf("время первого перевода должно быть в пределах года от текущего момента")
+	}
+
+	ownerUser, err := s.userRepo.GetUserByUsername(owner)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении пользователя: %w", err)
+	}
+	if ownerUser == nil {
+		return nil, userErrorf("пользователь %s не найден", owner)
+	}
+	toUser, err := s.userRepo.GetUserByUsername(toUsername)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении пользователя: %w", err)
+	}
+	if toUser == nil {
+		return nil, userErrorf("получатель %s не найден", toUsername)
+	}
+	if !toUser.IsActive {
+		return nil, userErrorf("получатель %s деактивирован", toUsername)
+	}
+
+	st := &models.ScheduledTransfer{
+		OwnerID:    ownerUser.ID,
+		ToUserID:   toUser.ID,
+		Owner:      ownerUser.Username,
+		ToUser:     toUser.Username,
+		Amount:     amount,
+		Recurrence: recurrence,
+		StartsAt:   startAt,
+		NextRunAt:  startAt,
+	}
+	if err := s.scheduleRepo.CreateSchedule(st); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+func (s *ScheduleService) GetSchedules(owner string) ([]models.ScheduledTransfer, error) {
+	user, err := s.userRepo.GetUserByUsername(owner)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении пользователя: %w", err)
+	}
+	if user == nil {
+		return nil, userErrorf("пользователь %s не найден", owner)
+	}
+	return s.scheduleRepo.GetUserSchedules(user.ID)
+}
+
+// GetScheduleRuns возвращает последние запуски расписания, включая неудачные
+func (s *ScheduleService) GetScheduleRuns(owner string, id int) ([]models.ScheduledTransferRun, error) {
+	st, err := s.scheduleRepo.GetSchedule(id)
+	if err != nil {
+		return nil, err
+	}
+	if st == nil || st.Owner != owner {
+		return nil, userErrorf("расписание %d не найдено", id)
+	}
+	return s.scheduleRepo.GetScheduleRuns(id, scheduleRunsLimit)
+}
+
+func (s *ScheduleService) PauseSchedule(owner string, id int) (*models.ScheduledTransfer, error) {
+	return s.updateSchedule(owner, id, func(st *models.ScheduledTransfer) error {
+		if st.Status != models.ScheduleStatusActive {
+			return userErrorf("расписание %d не активно: %s", id, st.Status)
+		}
+		st.Status = models.ScheduleStatusPaused
+		return nil
+	})
+}
+
+// ResumeSchedule возобновляет расписание. Пропущенные за время паузы регулярные
+// переводы не выполняются: следующий запуск переносится в будущее.
+func (s *ScheduleService) ResumeSchedule(owner string, id int) (*models.ScheduledTransfer, error) {
+	return s.updateSchedule(owner, id, func(st *models.ScheduledTransfer) error {
+		if st.Status != models.ScheduleStatusPaused {
+			return userErrorf("расписание %d не приостановлено: %s", id, st.Status)
+		}
+		st.Status = models.ScheduleStatusActive
+		if st.Recurrence != models.RecurrenceOnce {
+			st.NextRunAt = nextRunAfter(st.StartsAt, st.Recurrence, time.Now())
+		}
+		return nil
+	})
+}
+
+func (s *ScheduleService) CancelSchedule(owner string, id int) (*models.ScheduledTransfer, error) {
+	return s.updateSchedule(owner, id, func(st *models.ScheduledTransfer) error {
+		if st.Status != models.ScheduleStatusActive && st.Status != models.ScheduleStatusPaused {
+			return userErrorf("расписание %d уже завершено: %s", id, st.Status)
+		}
+		st.Status = models.ScheduleStatusCancelled
+		return nil
+	})
+}
+
+func (s *ScheduleService) updateSchedule(owner string, id int, apply func(*models.ScheduledTransfer) error) (*models.ScheduledTransfer, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	st, err := s.scheduleRepo.LockScheduleTx(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if st == nil || st.Owner != owner {
+		return nil, userErrorf("расписание %d не найдено", id)
+	}
+	if err := apply(st); err != nil {
+		return nil, err
+	}
+	if err := s.scheduleRepo.UpdateScheduleTx(tx, st); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+	return st, nil
+}
+
+// RunDueSchedules выполняет до scheduleRunBatch наступивших переводов через
+// TransactionService.TransferTx, каждый в своей короткой транзакции. Если перевод не прошёл
+// проверки (например, не хватает монет), запуск записывается как неудачный и владелец
+// получает уведомление. Расписание, упавшее с внутренней ошибкой, пропускается до
+// следующего запуска задачи и не мешает остальным. Возвращает число запусков.
+func (s *ScheduleService) RunDueSchedules() (int, error) {
+	var runs int
+	var skipped []int
+	var errs []error
+	for runs+len(skipped) < scheduleRunBatch {
+		var st *models.ScheduledTransfer
+		var transaction *models.Transaction
+		err := withTxRetry(func() error {
+			st, transaction = nil, nil
+			tx, err := s.db.Begin()
+			if err != nil {
+				return fmt.Errorf("ошибка начала транзакции: %w", err)
+			}
+			defer tx.Rollback()
+
+			st, err = s.scheduleRepo.LockDueScheduleTx(tx, skipped)
+			if err != nil || st == nil {
+				return err
+			}
+			if transaction, err = s.runScheduleTx(tx, st); err != nil {
+				return err
+			}
+
+			if err := tx.Commit(); err != nil {
+				return fmt.Errorf("ошибка фиксации транзакции: %w", err)
+			}
+			return nil
+		})
+		if st == nil {
+			if err != nil {
+				errs = append(errs, err)
+			}
+			break
+		}
+		if err != nil {
+			skipped = append(skipped, st.ID)
+			errs = append(errs, fmt.Errorf("расписание %d: %w", st.ID, err))
+			continue
+		}
+		if transaction != nil {
+			s.transService.publishSent(st.Owner, st.ToUser, transaction)
+		}
+		runs++
+	}
+	if len(errs) > 0 {
+		return runs, fmt.Errorf("ошибок при выполнении расписаний: %d: %w", len(errs), errs[0])
+	}
+	return runs, nil
+}
+
+// runScheduleTx выполняет запуск расписания st и возвращает сделанный перевод;
+// nil — перевод не прошёл проверки
+func (s *ScheduleService) runScheduleTx(tx *sql.Tx, st *models.ScheduledTransfer) (*models.Transaction, error) {
+	if _, err := tx.Exec("SAVEPOINT scheduled_transfer"); err != nil {
+		return nil, fmt.Errorf("ошибка создания точки сохранения: %w", err)
+	}
+
+	run := &models.ScheduledTransferRun{ScheduleID: st.ID, PlannedAt: st.NextRunAt, Status: models.ScheduleRunSucceeded}
+	transaction := &models.Transaction{Amount: st.Amount}
+	if err := s.transService.TransferTx(tx, st.Owner, st.ToUser, transaction); err != nil {
+		if !IsUserError(err) {
+			return nil, err
+		}
+		if _, err := tx.Exec("ROLLBACK TO SAVEPOINT scheduled_transfer"); err != nil {
+			return nil, fmt.Errorf("ошибка отката к точке сохранения: %w", err)
+		}
+		run.Status = models.ScheduleRunFailed
+		run.Error = err.Error()
+		notification := &models.Notification{
+			UserID:  st.OwnerID,
+			Kind:    models.NotificationScheduledTransferFailed,
+			Message: fmt.Sprintf("Запланированный перевод %d монет пользователю %s не выполнен: %s", st.Amount, st.ToUser, err.Error()),
+		}
+		if err := s.notificationRepo.CreateNotificationTx(tx, notification); err != nil {
+			return nil, err
+		}
+		transaction = nil
+	} else {
+		run.TransactionID = transaction.ID
+	}
+	if _, err := tx.Exec("RELEASE SAVEPOINT scheduled_transfer"); err != nil {
+		return nil, fmt.Errorf("ошибка освобождения точки сохранения: %w", err)
+	}
+
+	if err := s.scheduleRepo.CreateRunTx(tx, run); err != nil {
+		return nil, err
+	}
+	switch {
+	case st.Recurrence != models.RecurrenceOnce:
+		st.NextRunAt = nextRunAfter(st.StartsAt, st.Recurrence, time.Now())
+	case run.Status == models.ScheduleRunFailed:
+		st.Status = models.ScheduleStatusFailed
+	default:
+		st.Status = models.ScheduleStatusCompleted
+	}
+	if err := s.scheduleRepo.UpdateScheduleTx(tx, st); err != nil {
+		return nil, err
+	}
+	return transaction, nil
+}
+
+// nextRunAfter возвращает первый после now срок расписания, начатого в start.
+// Так после простоя сервиса пропущенные регулярные переводы не выполняются пачкой.
+// Сроки отсчитываются от start, а не от предыдущего: перевод 31-го числа в коротком
+// месяце выполняется в его последний день, а в следующем — снова 31-го.
+func nextRunAfter(start time.Time, recurrence string, now time.Time) time.Time {
+	next := start
+	for i := 1; !next.After(now); i++ {
+		if recurrence == models.RecurrenceMonthly {
+			next = addMonthsClamped(start, i)
+		} else {
+			next = start.AddDate(0, 0, 7*i)
+		}
+	}
+	return next
+}
+
+// addMonthsClamped сдвигает t на months месяцев; если такого числа в месяце нет,
+// берётся последний день месяца
+func addMonthsClamped(t time.Time, months int) time.Time {
+	year, month, day := t.Date()
+	first := time.Date(year, month+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	if last := first.AddDate(0, 1, -1).Day(); day > last {
+		day = last
+	}
+	return first.AddDate(0, 0, day-1)
+}
@@ -0,0 +1,199 @@
+package services
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/itocode21/MerchServiceAvito/internal/config"
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+	"github.com/itocode21/MerchServiceAvito/internal/repositories"
+	"github.com/lib/pq"
+)
+
+func TestRunDueSchedules(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка создания мока: %v", err)
+	}
+	defer db.Close()
+
+	cfg := &config.Config{DB: db}
+	userRepo := repositories.NewUserRepository(cfg)
+	transService := NewTransactionService(userRepo, repositories.NewTransactionRepository(db), repositories.NewOutboxRepository(db), repositories.NewNotificationRepository(db))
+	service := NewScheduleService(repositories.NewScheduleRepository(db), repositories.NewNotificationRepository(db), userRepo, transService)
+
+	dueQuery := "SELECT s.id, .* FROM scheduled_transfers s .* FOR UPDATE OF s SKIP LOCKED"
+	scheduleColumns := []string{"id", "owner_id", "to_user_id", "owner", "to_user", "amount", "recurrence", "status", "starts_at", "next_run_at", "created_at"}
+	lockQuery := "SELECT id, username, coins, held_coins, is_active FROM users"
+	lockColumns := []string{"id", "username", "coins", "held_coins", "is_active"}
+	plannedAt := time.Now().Add(-time.Minute)
+	expectNoneDue := func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery(dueQuery).
+			WithArgs("active", sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows(scheduleColumns))
+		mock.ExpectRollback()
+	}
+
+	tests := []struct {
+		name      string
+		setupMock func()
+		wantRuns  int
+		wantErr   bool
+	}{
+		{
+			name: "Нехватка монет записывается и отправляется уведомление",
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(dueQuery).
+					WithArgs("active", sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows(scheduleColumns).
+						AddRow(1, 1, 2, "alice", "bob", 100, "once", "active", plannedAt, plannedAt, plannedAt))
+				mock.ExpectExec("SAVEPOINT scheduled_transfer").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(lockQuery).
+					WithArgs(sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows(lockColumns).
+						AddRow(1, "alice", 50, 0, true).
+						AddRow(2, "bob", 500, 0, true))
+				mock.ExpectExec("ROLLBACK TO SAVEPOINT scheduled_transfer").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery("INSERT INTO notifications").
+					WithArgs(1, "scheduled_transfer_failed", sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+				mock.ExpectExec("RELEASE SAVEPOINT scheduled_transfer").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery("INSERT INTO scheduled_transfer_runs").
+					WithArgs(1, plannedAt, "failed", "недостаточно монет у alice: 50 < 100", nil).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+				mock.ExpectExec("UPDATE scheduled_transfers SET status = \\$1, next_run_at = \\$2 WHERE id = \\$3").
+					WithArgs("failed", plannedAt, 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+				expectNoneDue()
+			},
+			wantRuns: 1,
+		},
+		{
+			name: "Еженедельный перевод выполняется и переносится на неделю",
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(dueQuery).
+					WithArgs("active", sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows(scheduleColumns).
+						AddRow(2, 1, 2, "alice", "bob", 100, "weekly", "active", plannedAt, plannedAt, plannedAt))
+				mock.ExpectExec("SAVEPOINT scheduled_transfer").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(lockQuery).
+					WithArgs(sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows(lockColumns).
+						AddRow(1, "alice", 1000, 0, true).
+						AddRow(2, "bob", 500, 0, true))
+				mock.ExpectExec("UPDATE users SET coins = \\$1 WHERE id = \\$2").
+					WithArgs(900, 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("UPDATE users SET coins = \\$1 WHERE id = \\$2").
+					WithArgs(600, 2).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("INSERT INTO transactions").
+					WithArgs(1, 2, 100, "transfer", nil).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(5, time.Now()))
+				mock.ExpectExec("INSERT INTO outbox_events").WithArgs(models.EventCoinsTransferred, sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectQuery("INSERT INTO notifications").WithArgs(sqlmock.AnyArg(), models.NotificationCoinsReceived, sqlmock.AnyArg()).WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+				mock.ExpectExec("RELEASE SAVEPOINT scheduled_transfer").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery("INSERT INTO scheduled_transfer_runs").
+					WithArgs(2, plannedAt, "succeeded", "", 5).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(2, time.Now()))
+				mock.ExpectExec("UPDATE scheduled_transfers SET status = \\$1, next_run_at = \\$2 WHERE id = \\$3").
+					WithArgs("active", plannedAt.AddDate(0, 0, 7), 2).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+				expectNoneDue()
+			},
+			wantRuns: 1,
+		},
+		{
+			name: "Сбой одного расписания не останавливает остальные",
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(dueQuery).
+					WithArgs("active", sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows(scheduleColumns).
+						AddRow(3, 1, 2, "alice", "bob", 100, "once", "active", plannedAt, plannedAt, plannedAt))
+				mock.ExpectExec("SAVEPOINT scheduled_transfer").WillReturnError(sql.ErrConnDone)
+				mock.ExpectRollback()
+				mock.ExpectBegin()
+				mock.ExpectQuery(dueQuery).
+					WithArgs("active", pq.Array([]int{3})).
+					WillReturnRows(sqlmock.NewRows(scheduleColumns).
+						AddRow(4, 1, 2, "alice", "bob", 100, "once", "active", plannedAt, plannedAt, plannedAt))
+				mock.ExpectExec("SAVEPOINT scheduled_transfer").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(lockQuery).
+					WithArgs(sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows(lockColumns).
+						AddRow(1, "alice", 1000, 0, true).
+						AddRow(2, "bob", 500, 0, true))
+				mock.ExpectExec("UPDATE users SET coins = \\$1 WHERE id = \\$2").
+					WithArgs(900, 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("UPDATE users SET coins = \\$1 WHERE id = \\$2").
+					WithArgs(600, 2).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("INSERT INTO transactions").
+					WithArgs(1, 2, 100, "transfer", nil).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(6, time.Now()))
+				mock.ExpectExec("INSERT INTO outbox_events").WithArgs(models.EventCoinsTransferred, sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectQuery("INSERT INTO notifications").WithArgs(sqlmock.AnyArg(), models.NotificationCoinsReceived, sqlmock.AnyArg()).WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+				mock.ExpectExec("RELEASE SAVEPOINT scheduled_transfer").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery("INSERT INTO scheduled_transfer_runs").
+					WithArgs(4, plannedAt, "succeeded", "", 6).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(3, time.Now()))
+				mock.ExpectExec("UPDATE scheduled_transfers SET status = \\$1, next_run_at = \\$2 WHERE id = \\$3").
+					WithArgs("completed", plannedAt, 4).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+				expectNoneDue()
+			},
+			wantRuns: 1,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+			n, err := service.RunDueSchedules()
+			if (err != nil) != tt.wantErr || n != tt.wantRuns {
+				t.Errorf("RunDueSchedules() = %d, %v, want %d, wantErr %v", n, err, tt.wantRuns, tt.wantErr)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Не все ожидания мока выполнены: %v", err)
+			}
+		})
+	}
+}
+
+func TestNextRunAfter(t *testing.T) {
+	from := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	endOfMonth := time.Date(2025, 1, 31, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		start      time.Time
+		recurrence string
+		now        time.Time
+		want       time.Time
+	}{
+		{"Еженедельно после простоя", from, "weekly", time.Date(2025, 1, 25, 12, 0, 0, 0, time.UTC), time.Date(2025, 1, 27, 9, 0, 0, 0, time.UTC)},
+		{"Ежемесячно", from, "monthly", time.Date(2025, 1, 25, 12, 0, 0, 0, time.UTC), time.Date(2025, 2, 6, 9, 0, 0, 0, time.UTC)},
+		{"31-е в феврале — последний день месяца", endOfMonth, "monthly", time.Date(2025, 2, 10, 0, 0, 0, 0, time.UTC), time.Date(2025, 2, 28, 9, 0, 0, 0, time.UTC)},
+		{"После февраля снова 31-е", endOfMonth, "monthly", time.Date(2025, 2, 28, 9, 0, 0, 0, time.UTC), time.Date(2025, 3, 31, 9, 0, 0, 0, time.UTC)},
+		{"31-е в апреле — 30-е", endOfMonth, "monthly", time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 4, 30, 9, 0, 0, 0, time.UTC)},
+		{"29 февраля в високосный год", endOfMonth, "monthly", time.Date(2028, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2028, 2, 29, 9, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextRunAfter(tt.start, tt.recurrence, tt.now); !got.Equal(tt.want) {
+				t.Errorf("nextRunAfter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/itocode21/MerchServiceAvito/internal/events"
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+	"github.com/itocode21/MerchServiceAvito/internal/repositories"
+)
+
+const streamChannelPrefix = "stream:"
+
+// StreamService рассылает пользователям события об их балансе и входящих
+// переводах. События публикуются в канал Redis пользователя, поэтому поток
+// получает их, к какому бы экземпляру сервиса ни был подключён.
+type StreamService struct {
+	userRepo *repositories.UserRepository
+	redis    *redis.Client
+}
+
+func NewStreamService(userRepo *repositories.UserRepository) *StreamService {
+	return &StreamService{
+		userRepo: userRepo,
+		redis:    userRepo.Config.Redis,
+	}
+}
+
+func streamChannel(username string) string {
+	return streamChannelPrefix + username
+}
+
+// Subscribe подписывает рассылку на события шины bus
+func (s *StreamService) Subscribe(bus *events.Bus) {
+	for _, kind := range []string{events.CoinsSent, events.CoinsGranted, events.ItemPurchased, events.BalanceChanged, events.InventoryChanged} {
+		bus.Subscribe(kind, "stream", s.HandleEvent)
+	}
+}
+
+// HandleEvent сообщает участникам операции новый баланс, а получателю перевода —
+// о самом переводе. Получателю подарка баланс приходит вместе с покупателем: так
+// он узнаёт о новом предмете. Кэш /api/info участников сбрасывается.
+func (s *StreamService) HandleEvent(e events.Event) error {
+	var errs []error
+	switch e.Kind {
+	case events.CoinsSent:
+		transfer := models.TransferReceived{From: e.User, Amount: e.Amount, Kind: models.TransactionKindTransfer, At: e.At}
+		errs = append(errs, s.publish(e.Target, models.StreamTransferReceived, transfer))
+		errs = append(errs, s.publishBalance(e.User), s.publishBalance(e.Target))
+	case events.CoinsGranted:
+		transfer := models.TransferReceived{Amount: e.Amount, Kind: models.TransactionKindGrant, At: e.At}
+		errs = append(errs, s.publish(e.Target, models.StreamTransferReceived, transfer))
+		errs = append(errs, s.publishBalance(e.Target))
+	case events.ItemPurchased:
+		errs = append(errs, s.publishBalance(e.User))
+		if e.Target != e.User {
+			errs = append(errs, s.publishBalance(e.Target))
+		}
+	case events.BalanceChanged, events.InventoryChanged:
+		errs = append(errs, s.publishBalance(e.User))
+	}
+	return errors.Join(errs...)
+}
+
+func (s *StreamService) publishBalance(username string) error {
+	s.redis.Del(context.Background(), "user_info:"+username)
+	balance, err := s.userRepo.GetBalance(username)
+	if err != nil || balance == nil {
+		return err
+	}
+	return s.publish(username, models.StreamBalance, balance)
+}
+
+func (s *StreamService) publish(username, kind string, data interface{}) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации события %s: %w", kind, err)
+	}
+	msg, err := json.Marshal(models.StreamEvent{Kind: kind, Data: raw})
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации события %s: %w", kind, err)
+	}
+	if err := s.redis.Publish(context.Background(), streamChannel(username), msg).Err(); err != nil {
+		return fmt.Errorf("ошибка публикации события %s для %s: %w", kind, username, err)
+	}
+	return nil
+}
+
+// Listen подписывается на события пользователя username. Первым событием в канале
+// идёт текущий баланс; канал закрывается, когда отменён ctx или оборвалась
+// подписка Redis.
+func (s *StreamService) Listen(ctx context.Context, username string) (<-chan models.StreamEvent, error) {
+	pubsub := s.redis.Subscribe(ctx, streamChannel(username))
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, fmt.Errorf("ошибка подписки на события: %w", err)
+	}
+	// Баланс читается уже после подписки, поэтому изменение между ними не потеряется
+	balance, err := s.userRepo.GetBalance(username)
+	if err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+	if balance == nil {
+		pubsub.Close()
+		return nil, userErrorf("пользователь %s не найден", username)
+	}
+	raw, err := json.Marshal(balance)
+	if err != nil {
+		pubsub.Close()
+		return nil, fmt.Errorf("ошибка сериализации баланса: %w", err)
+	}
+
+	out := make(chan models.StreamEvent, 16)
+	out <- models.StreamEvent{Kind: models.StreamBalance, Data: raw}
+	go func() {
+		defer close(out)
+		defer pubsub.Close()
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				var e models.StreamEvent
+				if err := json.Unmarshal([]byte(msg.Payload), &e); err != nil {
+					log.Printf("Пропущено повреждённое событие потока %s: %v", username, err)
+					continue
+				}
+				select {
+				case out <- e:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
@@ -0,0 +1,302 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-redis/redis/v8"
+	"github.com/itocode21/MerchServiceAvito/internal/config"
+	"github.com/itocode21/MerchServiceAvito/internal/events"
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+	"github.com/itocode21/MerchServiceAvito/internal/repositories"
+)
+
+// fakeRedis — минимальный сервер протокола Redis для тестов потока: отвечает на DEL,
+// PUBLISH, SUBSCRIBE и PING, запоминает опубликованные сообщения и рассылает их подписчикам
+type fakeRedis struct {
+	mu          sync.Mutex
+	published   []fakeMessage
+	subscribers map[string][]net.Conn
+}
+
+type fakeMessage struct {
+	channel string
+	payload string
+}
+
+func newFakeRedis(t *testing.T) (*fakeRedis, *redis.Client) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Ошибка запуска фейкового Redis: %v", err)
+	}
+	f := &fakeRedis{subscribers: make(map[string][]net.Conn)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	client := redis.NewClient(&redis.Options{Addr: ln.Addr().String()})
+	t.Cleanup(func() {
+		client.Close()
+		ln.Close()
+	})
+	return f, client
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		args, err := readRESPCommand(r)
+		if err != nil {
+			return
+		}
+		f.mu.Lock()
+		switch strings.ToUpper(args[0]) {
+		case "DEL":
+			fmt.Fprintf(conn, ":%d\r\n", len(args)-1)
+		case "PUBLISH":
+			f.published = append(f.published, fakeMessage{channel: args[1], payload: args[2]})
+			for _, sub := range f.subscribers[args[1]] {
+				writeRESPArray(sub, "message", args[1], args[2])
+			}
+			fmt.Fprintf(conn, ":%d\r\n", len(f.subscribers[args[1]]))
+		case "SUBSCRIBE":
+			for i, channel := range args[1:] {
+				f.subscribers[channel] = append(f.subscribers[channel], conn)
+				fmt.Fprintf(conn, "*3\r\n$9\r\nsubscribe\r\n$%d\r\n%s\r\n:%d\r\n", len(channel), channel, i+1)
+			}
+		case "PING":
+			fmt.Fprint(conn, "+PONG\r\n")
+		default:
+			fmt.Fprintf(conn, "-ERR unknown command '%s'\r\n", args[0])
+		}
+		f.mu.Unlock()
+	}
+}
+
+// messages возвращает опубликованные сообщения и забывает их
+func (f *fakeRedis) messages() []fakeMessage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	published := f.published
+	f.published = nil
+	return published
+}
+
+func readRESPCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("неожиданная команда: %q", line)
+	}
+	args := make([]string, n)
+	for i := range args {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "$")))
+		if err != nil {
+			return nil, fmt.Errorf("неожиданный аргумент: %q", line)
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func writeRESPArray(w io.Writer, parts ...string) {
+	fmt.Fprintf(w, "*%d\r\n", len(parts))
+	for _, part := range parts {
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(part), part)
+	}
+}
+
+func newTestStreamService(t *testing.T) (*StreamService, sqlmock.Sqlmock, *fakeRedis) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка создания мока: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	fake, client := newFakeRedis(t)
+	userRepo := repositories.NewUserRepository(&config.Config{DB: db, Redis: client})
+	return NewStreamService(userRepo), mock, fake
+}
+
+func expectBalance(mock sqlmock.Sqlmock, username string, coins, held int) {
+	mock.ExpectQuery("SELECT coins, held_coins FROM users WHERE username = \\$1").
+		WithArgs(username).
+		WillReturnRows(sqlmock.NewRows([]string{"coins", "held_coins"}).AddRow(coins, held))
+}
+
+func TestStreamHandleEvent(t *testing.T) {
+	service, mock, fake := newTestStreamService(t)
+	at := time.Now()
+
+	tests := []struct {
+		name      string
+		event     events.Event
+		setupMock func()
+		want      []string
+	}{
+		{
+			name:  "Перевод: получателю сам перевод, обоим баланс",
+			event: events.Event{Kind: events.CoinsSent, User: "alice", Target: "bob", Amount: 50, At: at},
+			setupMock: func() {
+				expectBalance(mock, "alice", 950, 0)
+				expectBalance(mock, "bob", 1050, 0)
+			},
+			want: []string{"stream:bob transfer_received", "stream:alice balance", "stream:bob balance"},
+		},
+		{
+			name:  "Начисление: получателю перевод и баланс",
+			event: events.Event{Kind: events.CoinsGranted, Target: "bob", Amount: 100, At: at},
+			setupMock: func() {
+				expectBalance(mock, "bob", 1100, 0)
+			},
+			want: []string{"stream:bob transfer_received", "stream:bob balance"},
+		},
+		{
+			name:  "Покупка себе: баланс покупателю",
+			event: events.Event{Kind: events.ItemPurchased, User: "alice", Target: "alice", Amount: 80, At: at},
+			setupMock: func() {
+				expectBalance(mock, "alice", 920, 0)
+			},
+			want: []string{"stream:alice balance"},
+		},
+		{
+			name:  "Подарок: баланс покупателю и получателю",
+			event: events.Event{Kind: events.ItemPurchased, User: "alice", Target: "bob", Amount: 80, At: at},
+			setupMock: func() {
+				expectBalance(mock, "alice", 920, 0)
+				expectBalance(mock, "bob", 1000, 0)
+			},
+			want: []string{"stream:alice balance", "stream:bob balance"},
+		},
+		{
+			name:  "Изменение резерва: баланс пользователю",
+			event: events.Event{Kind: events.BalanceChanged, User: "alice"},
+			setupMock: func() {
+				expectBalance(mock, "alice", 1000, 200)
+			},
+			want: []string{"stream:alice balance"},
+		},
+		{
+			name:  "Изменение инвентаря: баланс пользователю",
+			event: events.Event{Kind: events.InventoryChanged, User: "bob"},
+			setupMock: func() {
+				expectBalance(mock, "bob", 1000, 0)
+			},
+			want: []string{"stream:bob balance"},
+		},
+		{
+			name:      "Неизвестное событие игнорируется",
+			event:     events.Event{Kind: "user_registered", User: "alice"},
+			setupMock: func() {},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+			if err := service.HandleEvent(tt.event); err != nil {
+				t.Fatalf("HandleEvent() error = %v", err)
+			}
+			var got []string
+			for _, msg := range fake.messages() {
+				var e models.StreamEvent
+				if err := json.Unmarshal([]byte(msg.payload), &e); err != nil {
+					t.Fatalf("Повреждённое событие в %s: %v", msg.channel, err)
+				}
+				got = append(got, msg.channel+" "+e.Kind)
+			}
+			if strings.Join(got, ", ") != strings.Join(tt.want, ", ") {
+				t.Errorf("HandleEvent() опубликовал %v, want %v", got, tt.want)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Не все ожидания мока выполнены: %v", err)
+			}
+		})
+	}
+}
+
+func TestStreamListen(t *testing.T) {
+	service, mock, _ := newTestStreamService(t)
+
+	t.Run("Первым приходит текущий баланс, затем события канала", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		expectBalance(mock, "alice", 1000, 200)
+		out, err := service.Listen(ctx, "alice")
+		if err != nil {
+			t.Fatalf("Listen() error = %v", err)
+		}
+
+		first := <-out
+		var balance models.Balance
+		if err := json.Unmarshal(first.Data, &balance); err != nil || first.Kind != models.StreamBalance {
+			t.Fatalf("Listen() первое событие = %s %s, want balance", first.Kind, first.Data)
+		}
+		if balance.Coins != 1000 || balance.HeldCoins != 200 || balance.Available != 800 {
+			t.Errorf("Listen() баланс = %+v, want 1000/200/800", balance)
+		}
+
+		transfer := models.TransferReceived{From: "bob", Amount: 50, Kind: models.TransactionKindTransfer}
+		if err := service.publish("alice", models.StreamTransferReceived, transfer); err != nil {
+			t.Fatalf("publish() error = %v", err)
+		}
+		select {
+		case e := <-out:
+			if e.Kind != models.StreamTransferReceived {
+				t.Errorf("Listen() второе событие = %s, want %s", e.Kind, models.StreamTransferReceived)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Listen() не передал опубликованное событие")
+		}
+
+		cancel()
+		select {
+		case _, ok := <-out:
+			if ok {
+				t.Error("Listen() передал событие после отмены контекста")
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Listen() не закрыл канал после отмены контекста")
+		}
+	})
+
+	t.Run("Неизвестный пользователь", func(t *testing.T) {
+		mock.ExpectQuery("SELECT coins, held_coins FROM users WHERE username = \\$1").
+			WithArgs("ghost").
+			WillReturnRows(sqlmock.NewRows([]string{"coins", "held_coins"}))
+		_, err := service.Listen(context.Background(), "ghost")
+		if err == nil || !IsUserError(err) || err.Error() != "пользователь ghost не найден" {
+			t.Errorf("Listen() error = %v, want user not found", err)
+		}
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Не все ожидания мока выполнены: %v", err)
+	}
+}
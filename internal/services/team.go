@@ -0,0 +1,302 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+	"github.com/itocode21/MerchServiceAvito/internal/repositories"
+)
+
+type TeamService struct {
+	teamRepo   *repositories.TeamRepository
+	userRepo   *repositories.UserRepository
+	transRepo  *repositories.TransactionRepository
+	outboxRepo *repositories.OutboxRepository
+	db         *sql.DB
+}
+
+func NewTeamService(teamRepo *repositories.TeamRepository, userRepo *repositories.UserRepository, transRepo *repositories.TransactionRepository, outboxRepo *repositories.OutboxRepository) *TeamService {
+	return &TeamService{
+		teamRepo:   teamRepo,
+		userRepo:   userRepo,
+		transRepo:  transRepo,
+		outboxRepo: outboxRepo,
+		db:         userRepo.DB,
+	}
+}
+
+func (s *TeamService) CreateTeam(name string) (*models.Team, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("название команды не может быть пустым")
+	}
+	team := &models.Team{Name: name}
+	if err := s.teamRepo.CreateTeam(team); err != nil {
+		return nil, err
+	}
+	return team, nil
+}
+
+func (s *TeamService) SetMember(teamID int, username, role string) error {
+	if role == "" {
+		role = models.TeamRoleMember
+	}
+	if role != models.TeamRoleMember && role != models.TeamRoleManager {
+		return fmt.Errorf("неизвестная роль %s", role)
+	}
+	team, err := s.teamRepo.GetTeam(teamID)
+	if err != nil {
+		return err
+	}
+	if team == nil {
+		return fmt.Errorf("команда %d не найдена", teamID)
+	}
+	user, err := s.userRepo.GetUserByUsername(username)
+	if err != nil {
+		return fmt.Errorf("ошибка при получении пользователя: %v", err)
+	}
+	if user == nil {
+		return fmt.Errorf("пользователь %s не найден", username)
+	}
+	return s.teamRepo.SetMember(teamID, user.ID, role)
+}
+
+func (s *TeamService) RemoveMember(teamID int, username string) error {
+	user, err := s.userRepo.GetUserByUsername(username)
+	if err != nil {
+		return fmt.Errorf("ошибка при получении пользователя: %v", err)
+	}
+	if user == nil {
+		return fmt.Errorf("пользователь %s не найден", username)
+	}
+	removed, err := s.teamRepo.RemoveMember(teamID, user.ID)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return fmt.Errorf("пользователь %s не состоит в команде %d", username, teamID)
+	}
+	return nil
+}
+
+// FundTeam пополняет бюджет команды. Монеты не списываются ни с чьего счёта.
+func (s *TeamService) FundTeam(teamID, amount int) error {
+	if amount <= 0 {
+		return fmt.Errorf("сумма должна быть положительной")
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	team, err := s.teamRepo.LockTeamTx(tx, teamID)
+	if err != nil {
+		return err
+	}
+	if team == nil {
+		return fmt.Errorf("команда %d не найдена", teamID)
+	}
+
+	team.Coins += amount
+	if err := s.teamRepo.UpdateTeamBalanceTx(tx, team); err != nil {
+		return err
+	}
+
+	transaction := &models.Transaction{
+		Amount: amount,
+		Kind:   models.TransactionKindTeamFunding,
+		TeamID: team.ID,
+	}
+	if err := s.transRepo.CreateTransaction(tx, transaction); err != nil {
+		return fmt.Errorf("ошибка записи транзакции: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка фиксации транзакции: %v", err)
+	}
+	return nil
+}
+
+// RewardMember переводит монеты из бюджета команды её участнику. Доступно только менеджерам команды.
+// Сначала блокируется строка команды, затем получатель через LockUsersTx, как и во всех
+// переводах; при взаимоблокировке транзакция повторяется.
+func (s *TeamService) RewardMember(managerUsername string, teamID int, toUsername string, amount int) error {
+	if amount <= 0 {
+		return fmt.Errorf("сумма должна быть положительной")
+	}
+
+	err := withTxRetry(func() error {
+		return s.rewardMember(managerUsername, teamID, toUsername, amount)
+	})
+	if err != nil {
+		return err
+	}
+	publishBalanceChanged(s.userRepo.Config.Events, toUsername)
+	return nil
+}
+
+func (s *TeamService) rewardMember(managerUsername string, teamID int, toUsername string, amount int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	team, err := s.teamRepo.LockTeamTx(tx, teamID)
+	if err != nil {
+		return err
+	}
+	if team == nil {
+		return fmt.Errorf("команда %d не найдена", teamID)
+	}
+
+	manager, err := s.userRepo.GetUserByUsername(managerUsername)
+	if err != nil {
+		return fmt.Errorf("ошибка при получении пользователя: %w", err)
+	}
+	if manager == nil {
+		return fmt.Errorf("пользователь %s не найден", managerUsername)
+	}
+	role, err := s.teamRepo.GetMemberRoleTx(tx, teamID, manager.ID)
+	if err != nil {
+		return err
+	}
+	if role != models.TeamRoleManager {
+		return fmt.Errorf("награждать из бюджета команды может только её менеджер")
+	}
+
+	users, err := s.userRepo.LockUsersTx(tx, []string{toUsername})
+	if err != nil {
+		return err
+	}
+	toUser := users[toUsername]
+	if toUser == nil {
+		return fmt.Errorf("получатель %s не найден", toUsername)
+	}
+	if !toUser.IsActive {
+		return fmt.Errorf("получатель %s деактивирован", toUsername)
+	}
+	if toUser.ID == manager.ID {
+		return userErrorf("менеджер не может наградить сам себя")
+	}
+	toRole, err := s.teamRepo.GetMemberRoleTx(tx, teamID, toUser.ID)
+	if err != nil {
+		return err
+	}
+	if toRole == "" {
+		return fmt.Errorf("получатель %s не состоит в команде", toUsername)
+	}
+
+	if team.Coins < amount {
+		return fmt.Errorf("недостаточно монет в бюджете команды: %d < %d", team.Coins, amount)
+	}
+	if toUser.Coins > maxAmount-amount {
+		return userErrorf("баланс получателя %s превысит допустимый максимум", toUsername)
+	}
+
+	team.Coins -= amount
+	toUser.Coins += amount
+	if err := s.teamRepo.UpdateTeamBalanceTx(tx, team); err != nil {
+		return err
+	}
+	if err := s.userRepo.UpdateUserBalanceTx(tx, toUser); err != nil {
+		return fmt.Errorf("ошибка обновления баланса получателя: %w", err)
+	}
+
+	transaction := &models.Transaction{
+		FromUserID: manager.ID,
+		ToUserID:   toUser.ID,
+		Amount:     amount,
+		Kind:       models.TransactionKindTeamReward,
+		TeamID:     team.ID,
+	}
+	if err := s.transRepo.CreateTransaction(tx, transaction); err != nil {
+		return fmt.Errorf("ошибка записи транзакции: %w", err)
+	}
+	if err := s.outboxRepo.AppendTx(tx, manager.ID, transferred(managerUsername, toUsername, transaction)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+	return nil
+}
+
+func (s *TeamService) GetUserTeams(username string) ([]models.Team, error) {
+	user, err := s.userRepo.GetUserByUsername(username)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении пользователя: %v", err)
+	}
+	if user == nil {
+		return nil, fmt.Errorf("пользователь %s не найден", username)
+	}
+	return s.teamRepo.GetUserTeams(user.ID)
+}
+
+// GetTeam возвращает команду с составом. Видна участникам команды и администраторам.
+func (s *TeamService) GetTeam(username string, teamID int) (*models.Team, error) {
+	role, err := s.accessRole(username, teamID)
+	if err != nil {
+		return nil, err
+	}
+	team, err := s.teamRepo.GetTeam(teamID)
+	if err != nil {
+		return nil, err
+	}
+	if team == nil {
+		return nil, fmt.Errorf("команда %d не найдена", teamID)
+	}
+	team.Role = role
+	team.Members, err = s.teamRepo.GetMembers(teamID)
+	if err != nil {
+		return nil, err
+	}
+	return team, nil
+}
+
+// SpendingReport строит отчёт по расходам бюджета команды за [from, to). Доступен менеджерам и администраторам.
+func (s *TeamService) SpendingReport(username string, teamID int, from, to time.Time) (*models.TeamSpendingReport, error) {
+	role, err := s.accessRole(username, teamID)
+	if err != nil {
+		return nil, err
+	}
+	if role == models.TeamRoleMember {
+		return nil, fmt.Errorf("отчёт доступен только менеджерам команды")
+	}
+	if !from.Before(to) {
+		return nil, fmt.Errorf("начало периода должно быть раньше конца")
+	}
+	return s.teamRepo.GetSpendingReport(teamID, from, to)
+}
+
+// accessRole возвращает роль пользователя в команде; для администратора, не состоящего в ней, — "admin".
+func (s *TeamService) accessRole(username string, teamID int) (string, error) {
+	user, err := s.userRepo.GetUserByUsername(username)
+	if err != nil {
+		return "", fmt.Errorf("ошибка при получении пользователя: %v", err)
+	}
+	if user == nil {
+		return "", fmt.Errorf("пользователь %s не найден", username)
+	}
+	role, err := s.teamRepo.GetMemberRole(teamID, user.ID)
+	if err != nil {
+		return "", err
+	}
+	if role != "" {
+		return role, nil
+	}
+	isAdmin, err := s.userRepo.IsAdmin(username)
+	if err != nil {
+		return "", err
+	}
+	if !isAdmin {
+		return "", fmt.Errorf("вы не состоите в команде %d", teamID)
+	}
+	return "admin", nil
+}
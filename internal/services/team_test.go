@@ -0,0 +1,176 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/itocode21/MerchServiceAvito/internal/config"
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+	"github.com/itocode21/MerchServiceAvito/internal/repositories"
+)
+
+func TestRewardMember(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка создания мока: %v", err)
+	}
+	defer db.Close()
+
+	cfg := &config.Config{DB: db}
+	userRepo := repositories.NewUserRepository(cfg)
+	teamRepo := repositories.NewTeamRepository(db)
+	transRepo := repositories.NewTransactionRepository(db)
+	service := NewTeamService(teamRepo, userRepo, transRepo, repositories.NewOutboxRepository(db))
+
+	expectTeam := func(coins int) {
+		mock.ExpectQuery("SELECT id, name, coins, created_at FROM teams WHERE id = \\$1 FOR UPDATE").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "coins", "created_at"}).AddRow(1, "core", coins, time.Now()))
+	}
+	expectManager := func(role string) {
+		mock.ExpectQuery("SELECT id, username, password_hash, coins, is_active FROM users WHERE username = \\$1").
+			WithArgs("boss").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password_hash", "coins", "is_active"}).
+				AddRow(10, "boss", "hash", 1000, true))
+		rows := sqlmock.NewRows([]string{"role"})
+		if role != "" {
+			rows.AddRow(role)
+		}
+		mock.ExpectQuery("SELECT role FROM team_members WHERE team_id = \\$1 AND user_id = \\$2").
+			WithArgs(1, 10).
+			WillReturnRows(rows)
+	}
+	expectRecipient := func(role string, coins int) {
+		mock.ExpectQuery("SELECT id, username, coins, held_coins, is_active FROM users WHERE username = ANY\\(\\$1\\) ORDER BY id FOR UPDATE").
+			WithArgs(sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "username", "coins", "held_coins", "is_active"}).AddRow(20, "dev", coins, 0, true))
+		rows := sqlmock.NewRows([]string{"role"})
+		if role != "" {
+			rows.AddRow(role)
+		}
+		mock.ExpectQuery("SELECT role FROM team_members WHERE team_id = \\$1 AND user_id = \\$2").
+			WithArgs(1, 20).
+			WillReturnRows(rows)
+	}
+
+	tests := []struct {
+		name      string
+		to        string
+		amount    int
+		setupMock func()
+		wantErr   bool
+		errMsg    string
+	}{
+		{
+			name:   "Успешная награда из бюджета",
+			amount: 100,
+			setupMock: func() {
+				mock.ExpectBegin()
+				expectTeam(500)
+				expectManager("manager")
+				expectRecipient("member", 100)
+				mock.ExpectExec("UPDATE teams SET coins = \\$1 WHERE id = \\$2").
+					WithArgs(400, 1).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec("UPDATE users SET coins = \\$1 WHERE id = \\$2").
+					WithArgs(200, 20).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectQuery("INSERT INTO transactions \\(from_user_id, to_user_id, amount, kind, team_id\\)").
+					WithArgs(10, 20, 100, "team_reward", 1).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+				mock.ExpectExec("INSERT INTO outbox_events").WithArgs(models.EventCoinsTransferred, 10, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+			},
+			wantErr: false,
+		},
+		{
+			name:   "Не менеджер команды",
+			amount: 100,
+			setupMock: func() {
+				mock.ExpectBegin()
+				expectTeam(500)
+				expectManager("member")
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+			errMsg:  "награждать из бюджета команды может только её менеджер",
+		},
+		{
+			name:   "Получатель не в команде",
+			amount: 100,
+			setupMock: func() {
+				mock.ExpectBegin()
+				expectTeam(500)
+				expectManager("manager")
+				expectRecipient("", 100)
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+			errMsg:  "получатель dev не состоит в команде",
+		},
+		{
+			name:   "Недостаточно монет в бюджете",
+			amount: 600,
+			setupMock: func() {
+				mock.ExpectBegin()
+				expectTeam(500)
+				expectManager("manager")
+				expectRecipient("member", 100)
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+			errMsg:  "недостаточно монет в бюджете команды: 500 < 600",
+		},
+		{
+			name:   "Баланс получателя превысит максимум",
+			amount: 100,
+			setupMock: func() {
+				mock.ExpectBegin()
+				expectTeam(500)
+				expectManager("manager")
+				expectRecipient("member", maxAmount-50)
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+			errMsg:  "баланс получателя dev превысит допустимый максимум",
+		},
+		{
+			name:   "Менеджер награждает сам себя",
+			to:     "boss",
+			amount: 100,
+			setupMock: func() {
+				mock.ExpectBegin()
+				expectTeam(500)
+				expectManager("manager")
+				mock.ExpectQuery("SELECT id, username, coins, held_coins, is_active FROM users WHERE username = ANY\\(\\$1\\) ORDER BY id FOR UPDATE").
+					WithArgs(sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "username", "coins", "held_coins", "is_active"}).AddRow(10, "boss", 1000, 0, true))
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+			errMsg:  "менеджер не может наградить сам себя",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+			to := tt.to
+			if to == "" {
+				to = "dev"
+			}
+			err := service.RewardMember("boss", 1, to, tt.amount)
+			if tt.wantErr {
+				if err == nil || err.Error() != tt.errMsg {
+					t.Errorf("RewardMember() error = %v, want %q", err, tt.errMsg)
+				}
+			} else if err != nil {
+				t.Errorf("RewardMember() error = %v, want nil", err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Не все ожидания мока выполнены: %v", err)
+			}
+		})
+	}
+}
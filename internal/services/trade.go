@@ -0,0 +1,384 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+	"github.com/itocode21/MerchServiceAvito/internal/repositories"
+)
+
+type TradeService struct {
+	tradeRepo        *repositories.TradeRepository
+	itemRepo         *repositories.ItemRepository
+	userRepo         *repositories.UserRepository
+	transRepo        *repositories.TransactionRepository
+	notificationRepo *repositories.NotificationRepository
+	outboxRepo       *repositories.OutboxRepository
+	db               *sql.DB
+}
+
+func NewTradeService(tradeRepo *repositories.TradeRepository, itemRepo *repositories.ItemRepository, userRepo *repositories.UserRepository, transRepo *repositories.TransactionRepository, notificationRepo *repositories.NotificationRepository, outboxRepo *repositories.OutboxRepository) *TradeService {
+	return &TradeService{
+		tradeRepo:        tradeRepo,
+		itemRepo:         itemRepo,
+		userRepo:         userRepo,
+		transRepo:        transRepo,
+		notificationRepo: notificationRepo,
+		outboxRepo:       outboxRepo,
+		db:               userRepo.DB,
+	}
+}
+
+// TransferItem передаёт quantity предметов из инвентаря from в инвентарь to
+func (s *TradeService) TransferItem(from, to, itemName string, quantity int) (*models.ItemTransfer, error) {
+	if from == to {
+		return nil, userErrorf("нельзя передать предмет самому себе")
+	}
+	if quantity <= 0 {
+		return nil, userErrorf("количество должно быть положительным")
+	}
+	item, err := s.itemRepo.GetItemByName(itemName)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения предмета: %w", err)
+	}
+	if item == nil {
+		return nil, userErrorf("предмет %s не найден", itemName)
+	}
+
+	var transfer *models.ItemTransfer
+	err = withTxRetry(func() error {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return fmt.Errorf("ошибка начала транзакции: %w", err)
+		}
+		defer tx.Rollback()
+
+		fromUser, toUser, err := s.lockPartiesTx(tx, from, to)
+		if err != nil {
+			return err
+		}
+		inventory, err := s.itemRepo.LockInventoryTx(tx, []int{fromUser.ID}, []int{item.ID})
+		if err != nil {
+			return err
+		}
+		if have := inventory[fromUser.ID][item.ID]; have < quantity {
+			return userErrorf("у %s только %d шт. %s", from, have, item.Name)
+		}
+
+		transfer = &models.ItemTransfer{FromUserID: fromUser.ID, ToUserID: toUser.ID, ItemID: item.ID, Quantity: quantity}
+		if err := s.itemRepo.MoveItemsTx(tx, transfer); err != nil {
+			return err
+		}
+		notification := &models.Notification{
+			UserID:  toUser.ID,
+			Kind:    models.NotificationItemReceived,
+			Message: fmt.Sprintf("%s передал(а) вам %s: %d шт.", from, item.Name, quantity),
+		}
+		if err := s.notificationRepo.CreateNotificationTx(tx, notification); err != nil {
+			return err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("ошибка фиксации транзакции: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	publishInventoryChanged(s.userRepo.Config.Events, from, to)
+	return transfer, nil
+}
+
+// CreateTrade предлагает обмен: proposer отдаёт give и giveCoins, а взамен получает want
+// и wantCoins. Ничего не резервируется: наличие предметов и монет проверяется при принятии.
+func (s *TradeService) CreateTrade(proposer, counterparty string, give, want []models.TradeItem, giveCoins, wantCoins int) (*models.Trade, error) {
+	if proposer == counterparty {
+		return nil, userErrorf("нельзя предложить обмен самому себе")
+	}
+	for _, coins := range []int{giveCoins, wantCoins} {
+		if coins < 0 || coins > maxAmount {
+			return nil, userErrorf("недопустимая сумма монет в обмене: %d", coins)
+		}
+	}
+	if (len(give) == 0 && giveCoins == 0) || (len(want) == 0 && wantCoins == 0) {
+		return nil, userErrorf("каждая сторона обмена должна что-то отдать")
+	}
+	give, err := s.resolveTradeItems(give)
+	if err != nil {
+		return nil, err
+	}
+	want, err = s.resolveTradeItems(want)
+	if err != nil {
+		return nil, err
+	}
+
+	trade := &models.Trade{
+		Proposer:          proposer,
+		Counterparty:      counterparty,
+		ProposerCoins:     giveCoins,
+		CounterpartyCoins: wantCoins,
+		ProposerItems:     give,
+		CounterpartyItems: want,
+	}
+	err = withTxRetry(func() error {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return fmt.Errorf("ошибка начала транзакции: %w", err)
+		}
+		defer tx.Rollback()
+
+		proposerUser, counterpartyUser, err := s.lockPartiesTx(tx, proposer, counterparty)
+		if err != nil {
+			return err
+		}
+		trade.ProposerID, trade.CounterpartyID = proposerUser.ID, counterpartyUser.ID
+		if err := s.tradeRepo.CreateTradeTx(tx, trade); err != nil {
+			return err
+		}
+		notification := &models.Notification{
+			UserID:  counterpartyUser.ID,
+			Kind:    models.NotificationTradeOffered,
+			Message: fmt.Sprintf("%s предлагает вам обмен №%d", proposer, trade.ID),
+		}
+		if err := s.notificationRepo.CreateNotificationTx(tx, notification); err != nil {
+			return err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("ошибка фиксации транзакции: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return trade, nil
+}
+
+// AcceptTrade выполняет обмен целиком: блокирует обе стороны и их строки инвентаря,
+// проверяет наличие предметов и монет и перекладывает их в одной транзакции
+func (s *TradeService) AcceptTrade(counterparty string, id int) (*models.Trade, error) {
+	trade, err := s.resolveTrade(id, func(tx *sql.Tx, trade *models.Trade) error {
+		if trade.Counterparty != counterparty {
+			return userErrorf("обмен %d не найден", id)
+		}
+		proposerUser, counterpartyUser, err := s.lockPartiesTx(tx, trade.Proposer, trade.Counterparty)
+		if err != nil {
+			return err
+		}
+		if !proposerUser.IsActive {
+			return userErrorf("пользователь %s деактивирован", trade.Proposer)
+		}
+
+		var itemIDs []int
+		for _, item := range append(append([]models.TradeItem{}, trade.ProposerItems...), trade.CounterpartyItems...) {
+			itemIDs = append(itemIDs, item.ItemID)
+		}
+		inventory, err := s.itemRepo.LockInventoryTx(tx, []int{proposerUser.ID, counterpartyUser.ID}, itemIDs)
+		if err != nil {
+			return err
+		}
+		if err := checkTradeSide(proposerUser, trade.ProposerItems, trade.ProposerCoins, inventory); err != nil {
+			return err
+		}
+		if err := checkTradeSide(counterpartyUser, trade.CounterpartyItems, trade.CounterpartyCoins, inventory); err != nil {
+			return err
+		}
+
+		if err := s.moveTradeSideTx(tx, trade, proposerUser, counterpartyUser, trade.ProposerItems, trade.ProposerCoins); err != nil {
+			return err
+		}
+		if err := s.moveTradeSideTx(tx, trade, counterpartyUser, proposerUser, trade.CounterpartyItems, trade.CounterpartyCoins); err != nil {
+			return err
+		}
+		if proposerUser.Coins > maxAmount || counterpartyUser.Coins > maxAmount {
+			return userErrorf("баланс после обмена превысит допустимый максимум")
+		}
+		if trade.ProposerCoins > 0 || trade.CounterpartyCoins > 0 {
+			if err := s.userRepo.UpdateUserBalanceTx(tx, proposerUser); err != nil {
+				return fmt.Errorf("ошибка обновления баланса: %w", err)
+			}
+			if err := s.userRepo.UpdateUserBalanceTx(tx, counterpartyUser); err != nil {
+				return fmt.Errorf("ошибка обновления баланса: %w", err)
+			}
+		}
+
+		notification := &models.Notification{
+			UserID:  proposerUser.ID,
+			Kind:    models.NotificationTradeAccepted,
+			Message: fmt.Sprintf("%s принял(а) обмен №%d", counterparty, trade.ID),
+		}
+		if err := s.notificationRepo.CreateNotificationTx(tx, notification); err != nil {
+			return err
+		}
+		trade.Status = models.TradeStatusAccepted
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if trade.ProposerCoins > 0 || trade.CounterpartyCoins > 0 {
+		publishBalanceChanged(s.userRepo.Config.Events, trade.Proposer, trade.Counterparty)
+	}
+	if len(trade.ProposerItems) > 0 || len(trade.CounterpartyItems) > 0 {
+		publishInventoryChanged(s.userRepo.Config.Events, trade.Proposer, trade.Counterparty)
+	}
+	return trade, nil
+}
+
+// DeclineTrade отклоняет предложение обмена (вторая сторона)
+func (s *TradeService) DeclineTrade(counterparty string, id int) (*models.Trade, error) {
+	return s.resolveTrade(id, func(tx *sql.Tx, trade *models.Trade) error {
+		if trade.Counterparty != counterparty {
+			return userErrorf("обмен %d не найден", id)
+		}
+		trade.Status = models.TradeStatusDeclined
+		return nil
+	})
+}
+
+// CancelTrade отзывает предложение обмена (автор)
+func (s *TradeService) CancelTrade(proposer string, id int) (*models.Trade, error) {
+	return s.resolveTrade(id, func(tx *sql.Tx, trade *models.Trade) error {
+		if trade.Proposer != proposer {
+			return userErrorf("обмен %d не найден", id)
+		}
+		trade.Status = models.TradeStatusCancelled
+		return nil
+	})
+}
+
+func (s *TradeService) GetTrades(username string) ([]*models.Trade, error) {
+	user, err := s.userRepo.GetUserByUsername(username)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении пользователя: %w", err)
+	}
+	if user == nil {
+		return nil, userErrorf("пользователь %s не найден", username)
+	}
+	return s.tradeRepo.GetUserTrades(user.ID)
+}
+
+func (s *TradeService) resolveTrade(id int, apply func(*sql.Tx, *models.Trade) error) (*models.Trade, error) {
+	var trade *models.Trade
+	err := withTxRetry(func() error {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return fmt.Errorf("ошибка начала транзакции: %w", err)
+		}
+		defer tx.Rollback()
+
+		trade, err = s.tradeRepo.LockTradeTx(tx, id)
+		if err != nil {
+			return err
+		}
+		if trade == nil {
+			return userErrorf("обмен %d не найден", id)
+		}
+		if trade.Status != models.TradeStatusPending {
+			return userErrorf("обмен %d уже завершён: %s", id, trade.Status)
+		}
+		if err := apply(tx, trade); err != nil {
+			return err
+		}
+		if err := s.tradeRepo.UpdateTradeStatusTx(tx, trade); err != nil {
+			return err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("ошибка фиксации транзакции: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return trade, nil
+}
+
+// lockPartiesTx блокирует обоих участников в порядке id; получатель должен быть активен
+func (s *TradeService) lockPartiesTx(tx *sql.Tx, from, to string) (*models.User, *models.User, error) {
+	users, err := s.userRepo.LockUsersTx(tx, []string{from, to})
+	if err != nil {
+		return nil, nil, err
+	}
+	if users[from] == nil {
+		return nil, nil, userErrorf("пользователь %s не найден", from)
+	}
+	if users[to] == nil {
+		return nil, nil, userErrorf("получатель %s не найден", to)
+	}
+	if !users[to].IsActive {
+		return nil, nil, userErrorf("получатель %s деактивирован", to)
+	}
+	return users[from], users[to], nil
+}
+
+// resolveTradeItems проверяет список предметов и подставляет их идентификаторы
+func (s *TradeService) resolveTradeItems(items []models.TradeItem) ([]models.TradeItem, error) {
+	if len(items) == 0 {
+		return []models.TradeItem{}, nil
+	}
+	names := make([]string, 0, len(items))
+	seen := make(map[string]bool, len(items))
+	for _, item := range items {
+		if item.Quantity <= 0 {
+			return nil, userErrorf("количество %s должно быть положительным", item.Type)
+		}
+		if seen[item.Type] {
+			return nil, userErrorf("предмет %s указан несколько раз", item.Type)
+		}
+		seen[item.Type] = true
+		names = append(names, item.Type)
+	}
+	found, err := s.itemRepo.GetItemsByNames(names)
+	if err != nil {
+		return nil, err
+	}
+	resolved := make([]models.TradeItem, len(items))
+	for i, item := range items {
+		if found[item.Type] == nil {
+			return nil, userErrorf("предмет %s не найден", item.Type)
+		}
+		resolved[i] = models.TradeItem{ItemID: found[item.Type].ID, Type: item.Type, Quantity: item.Quantity}
+	}
+	return resolved, nil
+}
+
+func checkTradeSide(user *models.User, items []models.TradeItem, coins int, inventory map[int]map[int]int) error {
+	for _, item := range items {
+		if have := inventory[user.ID][item.ItemID]; have < item.Quantity {
+			return userErrorf("у %s только %d шт. %s", user.Username, have, item.Type)
+		}
+	}
+	if user.SpendableCoins() < coins {
+		return userErrorf("недостаточно монет у %s: %d < %d", user.Username, user.SpendableCoins(), coins)
+	}
+	return nil
+}
+
+// moveTradeSideTx перекладывает предметы и монеты одной стороны обмена; балансы
+// сохраняются вызывающим кодом после обработки обеих сторон
+func (s *TradeService) moveTradeSideTx(tx *sql.Tx, trade *models.Trade, from, to *models.User, items []models.TradeItem, coins int) error {
+	for _, item := range items {
+		transfer := &models.ItemTransfer{FromUserID: from.ID, ToUserID: to.ID, ItemID: item.ItemID, Quantity: item.Quantity, TradeID: trade.ID}
+		if err := s.itemRepo.MoveItemsTx(tx, transfer); err != nil {
+			return err
+		}
+	}
+	if coins == 0 {
+		return nil
+	}
+	from.Coins -= coins
+	to.Coins += coins
+	transaction := &models.Transaction{FromUserID: from.ID, ToUserID: to.ID, Amount: coins, Kind: models.TransactionKindTrade}
+	if err := s.transRepo.CreateTransaction(tx, transaction); err != nil {
+		return fmt.Errorf("ошибка записи транзакции: %w", err)
+	}
+	if err := s.outboxRepo.AppendTx(tx, from.ID, transferred(from.Username, to.Username, transaction)); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/itocode21/MerchServiceAvito/internal/config"
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+	"github.com/itocode21/MerchServiceAvito/internal/repositories"
+)
+
+func TestAcceptTrade(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка создания мока: %v", err)
+	}
+	defer db.Close()
+
+	cfg := &config.Config{DB: db}
+	userRepo := repositories.NewUserRepository(cfg)
+	service := NewTradeService(repositories.NewTradeRepository(db), repositories.NewItemRepository(db), userRepo,
+		repositories.NewTransactionRepository(db), repositories.NewNotificationRepository(db), repositories.NewOutboxRepository(db))
+
+	now := time.Now()
+	expectTrade := func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT t.id, .* FROM trades t .* WHERE t.id = \\$1 FOR UPDATE OF t").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "proposer_id", "counterparty_id", "proposer", "counterparty",
+				"proposer_coins", "counterparty_coins", "status", "created_at", "resolved_at"}).
+				AddRow(1, 1, 2, "alice", "bob", 0, 50, "pending", now, nil))
+		mock.ExpectQuery("SELECT ti.trade_id, ti.side, ti.item_id, i.name, ti.quantity FROM trade_items ti").
+			WithArgs(sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"trade_id", "side", "item_id", "name", "quantity"}).
+				AddRow(1, "proposer", 3, "cup", 1))
+		mock.ExpectQuery("SELECT id, username, coins, held_coins, is_active FROM users").
+			WithArgs(sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "username", "coins", "held_coins", "is_active"}).
+				AddRow(1, "alice", 100, 0, true).
+				AddRow(2, "bob", 500, 0, true))
+	}
+
+	tests := []struct {
+		name      string
+		setupMock func()
+		wantErr   bool
+		errMsg    string
+	}{
+		{
+			name: "Предмет и монеты меняют владельцев",
+			setupMock: func() {
+				expectTrade()
+				mock.ExpectQuery("SELECT user_id, item_id, quantity FROM inventory").
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"user_id", "item_id", "quantity"}).AddRow(1, 3, 2))
+				mock.ExpectExec("UPDATE inventory SET quantity = quantity - \\$1").
+					WithArgs(1, 1, 3).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("DELETE FROM inventory").
+					WithArgs(1, 3).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("INSERT INTO inventory").
+					WithArgs(2, 3, 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("INSERT INTO item_transfers").
+					WithArgs(1, 2, 3, 1, 1).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, now))
+				mock.ExpectQuery("INSERT INTO transactions").
+					WithArgs(2, 1, 50, "trade", nil).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, now))
+				mock.ExpectExec("INSERT INTO outbox_events").WithArgs(models.EventCoinsTransferred, 2, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec("UPDATE users SET coins = \\$1 WHERE id = \\$2").
+					WithArgs(150, 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("UPDATE users SET coins = \\$1 WHERE id = \\$2").
+					WithArgs(450, 2).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("INSERT INTO notifications").
+					WithArgs(1, "trade_accepted", "bob принял(а) обмен №1").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, now))
+				mock.ExpectQuery("UPDATE trades SET status = \\$1, resolved_at = NOW\\(\\) WHERE id = \\$2").
+					WithArgs("accepted", 1).
+					WillReturnRows(sqlmock.NewRows([]string{"resolved_at"}).AddRow(now))
+				mock.ExpectCommit()
+			},
+			wantErr: false,
+		},
+		{
+			name: "Предмет уже передан",
+			setupMock: func() {
+				expectTrade()
+				mock.ExpectQuery("SELECT user_id, item_id, quantity FROM inventory").
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"user_id", "item_id", "quantity"}))
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+			errMsg:  "у alice только 0 шт. cup",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+			trade, err := service.AcceptTrade("bob", 1)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("AcceptTrade() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && err != nil && err.Error() != tt.errMsg {
+				t.Errorf("AcceptTrade() error message = %v, want %v", err.Error(), tt.errMsg)
+			}
+			if !tt.wantErr && trade.Status != "accepted" {
+				t.Errorf("AcceptTrade() status = %s, want accepted", trade.Status)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Не все ожидания мока выполнены: %v", err)
+			}
+		})
+	}
+}
+
+func TestCreateTradeValidation(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка создания мока: %v", err)
+	}
+	defer db.Close()
+
+	cfg := &config.Config{DB: db}
+	service := NewTradeService(repositories.NewTradeRepository(db), repositories.NewItemRepository(db), repositories.NewUserRepository(cfg),
+		repositories.NewTransactionRepository(db), repositories.NewNotificationRepository(db), repositories.NewOutboxRepository(db))
+
+	if _, err := service.CreateTrade("alice", "bob", nil, nil, 10, 0); err == nil || err.Error() != "каждая сторона обмена должна что-то отдать" {
+		t.Errorf("CreateTrade() error = %v, want one-sided trade error", err)
+	}
+	if _, err := service.CreateTrade("alice", "alice", nil, nil, 10, 10); !IsUserError(err) {
+		t.Errorf("CreateTrade() error = %v, want user error", err)
+	}
+}
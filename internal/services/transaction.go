@@ -3,84 +3,447 @@ package services
 import (
 	"database/sql"
 	"fmt"
+	"math"
+	"slices"
+	"strings"
 
+	"github.com/itocode21/MerchServiceAvito/internal/events"
 	"github.com/itocode21/MerchServiceAvito/internal/models"
 	"github.com/itocode21/MerchServiceAvito/internal/repositories"
 )
 
 type TransactionService struct {
-	userRepo  *repositories.UserRepository
-	transRepo *repositories.TransactionRepository
-	db        *sql.DB
+	userRepo         *repositories.UserRepository
+	transRepo        *repositories.TransactionRepository
+	outboxRepo       *repositories.OutboxRepository
+	notificationRepo *repositories.NotificationRepository
+	db               *sql.DB
 }
 
-func NewTransactionService(userRepo *repositories.UserRepository, transRepo *repositories.TransactionRepository) *TransactionService {
+func NewTransactionService(userRepo *repositories.UserRepository, transRepo *repositories.TransactionRepository, outboxRepo *repositories.OutboxRepository, notificationRepo *repositories.NotificationRepository) *TransactionService {
 	return &TransactionService{
-		userRepo:  userRepo,
-		transRepo: transRepo,
-		db:        userRepo.DB,
+		userRepo:         userRepo,
+		transRepo:        transRepo,
+		outboxRepo:       outboxRepo,
+		notificationRepo: notificationRepo,
+		db:               userRepo.DB,
 	}
 }
 
+// maxAmount ограничивает суммы и балансы: столбцы coins и amount имеют тип INT
+const maxAmount = math.MaxInt32
+
 func (s *TransactionService) SendCoins(fromUsername, toUsername string, amount int) error {
+	if fromUsername == toUsername {
+		return userErrorf("нельзя отправить монеты самому себе")
+	}
+	if err := s.checkAmount(amount); err != nil {
+		return err
+	}
+
+	var transaction *models.Transaction
+	err := withTxRetry(func() error {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return fmt.Errorf("ошибка начала транзакции: %w", err)
+		}
+		defer tx.Rollback()
+
+		transaction = &models.Transaction{Amount: amount}
+		if err := s.TransferTx(tx, fromUsername, toUsername, transaction); err != nil {
+			return err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("ошибка фиксации транзакции: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.publishSent(fromUsername, toUsername, transaction)
+	return nil
+}
+
+// publishSent сообщает подписчикам о зафиксированном переводе
+func (s *TransactionService) publishSent(fromUsername, toUsername string, t *models.Transaction) {
+	s.userRepo.Config.Events.Publish(events.Event{
+		Kind:     events.CoinsSent,
+		UserID:   t.FromUserID,
+		User:     fromUsername,
+		TargetID: t.ToUserID,
+		Target:   toUsername,
+		Amount:   t.Amount,
+		At:       t.CreatedAt,
+	})
+}
+
+// publishBalanceChanged сообщает подписчикам о новом балансе пользователей после
+// операции, у которой нет собственного события. Вызывается после фиксации транзакции.
+func publishBalanceChanged(bus *events.Bus, usernames ...string) {
+	publishForUsers(bus, events.BalanceChanged, usernames)
+}
+
+// publishInventoryChanged сообщает подписчикам, что предметы пользователей
+// переложила операция без собственного события
+func publishInventoryChanged(bus *events.Bus, usernames ...string) {
+	publishForUsers(bus, events.InventoryChanged, usernames)
+}
+
+// publishForUsers публикует событие kind по разу для каждого из непустых usernames
+func publishForUsers(bus *events.Bus, kind string, usernames []string) {
+	for i, username := range usernames {
+		if username == "" || slices.Contains(usernames[:i], username) {
+			continue
+		}
+		bus.Publish(events.Event{Kind: kind, User: username})
+	}
+}
+
+// TransferTx переводит t.Amount монет внутри уже открытой транзакции и записывает перевод
+// в историю с видом t.Kind. Отправитель и получатель блокируются одним запросом в порядке id,
+// поэтому встречные переводы не взаимоблокируются.
+func (s *TransactionService) TransferTx(tx *sql.Tx, fromUsername, toUsername string, t *models.Transaction) error {
+	if fromUsername == toUsername {
+		return userErrorf("нельзя отправить монеты самому себе")
+	}
+	if err := s.checkAmount(t.Amount); err != nil {
+		return err
+	}
+
+	users, err := s.userRepo.LockUsersTx(tx, []string{fromUsername, toUsername})
+	if err != nil {
+		return err
+	}
+
+	fromUser := users[fromUsername]
+	if fromUser == nil {
+		return userErrorf("отправитель %s не найден", fromUsername)
+	}
+	if !fromUser.IsActive {
+		return userErrorf("отправитель %s деактивирован", fromUsername)
+	}
+	toUser := users[toUsername]
+	if toUser == nil {
+		return userErrorf("получатель %s не найден", toUsername)
+	}
+	if !toUser.IsActive {
+		return userErrorf("получатель %s деактивирован", toUsername)
+	}
+
+	if fromUser.SpendableCoins() < t.Amount {
+		return userErrorf("недостаточно монет у %s: %d < %d", fromUsername, fromUser.SpendableCoins(), t.Amount)
+	}
+	if toUser.Coins > maxAmount-t.Amount {
+		return userErrorf("баланс получателя %s превысит допустимый максимум", toUsername)
+	}
+	if err := s.checkDailyLimit(tx, fromUser, t.Amount); err != nil {
+		return err
+	}
+
+	fromUser.Coins -= t.Amount
+	toUser.Coins += t.Amount
+
+	if err := s.userRepo.UpdateUserBalanceTx(tx, fromUser); err != nil {
+		return fmt.Errorf("ошибка обновления баланса отправителя: %w", err)
+	}
+	if err := s.userRepo.UpdateUserBalanceTx(tx, toUser); err != nil {
+		return fmt.Errorf("ошибка обновления баланса получателя: %w", err)
+	}
+
+	t.FromUserID = fromUser.ID
+	t.ToUserID = toUser.ID
+	if err := s.transRepo.CreateTransaction(tx, t); err != nil {
+		return fmt.Errorf("ошибка записи транзакции: %w", err)
+	}
+	if err := s.outboxRepo.AppendTx(tx, fromUser.ID, transferred(fromUsername, toUsername, t)); err != nil {
+		return err
+	}
+	return s.notifyReceivedTx(tx, fromUsername, t)
+}
+
+// notifyReceivedTx уведомляет получателя о переводе t
+func (s *TransactionService) notifyReceivedTx(tx *sql.Tx, fromUsername string, t *models.Transaction) error {
+	return s.notificationRepo.CreateNotificationTx(tx, &models.Notification{
+		UserID:  t.ToUserID,
+		Kind:    models.NotificationCoinsReceived,
+		Message: fmt.Sprintf("%s отправил(а) вам %d монет", fromUsername, t.Amount),
+	})
+}
+
+func transferred(fromUsername, toUsername string, t *models.Transaction) models.CoinsTransferred {
+	return models.CoinsTransferred{
+		TransactionID: t.ID,
+		From:          fromUsername,
+		To:            toUsername,
+		Amount:        t.Amount,
+		Kind:          t.Kind,
+		At:            t.CreatedAt,
+	}
+}
+
+// checkAmount проверяет сумму одного перевода с учётом лимита TRANSFER_MAX_AMOUNT
+func (s *TransactionService) checkAmount(amount int) error {
 	if amount <= 0 {
-		return fmt.Errorf("сумма должна быть положительной")
+		return userErrorf("сумма должна быть положительной")
+	}
+	if limit := s.userRepo.Config.TransferMaxAmount; limit > 0 && amount > limit {
+		return userErrorf("сумма %d превышает лимит на один перевод: %d", amount, limit)
+	}
+	if amount > maxAmount {
+		return userErrorf("сумма %d слишком велика", amount)
+	}
+	return nil
+}
+
+// checkDailyLimit проверяет лимит TRANSFER_DAILY_LIMIT. Строка отправителя к этому моменту
+// заблокирована, поэтому параллельные переводы того же пользователя не обойдут лимит.
+func (s *TransactionService) checkDailyLimit(tx *sql.Tx, fromUser *models.User, amount int) error {
+	limit := s.userRepo.Config.TransferDailyLimit
+	if limit == 0 {
+		return nil
+	}
+	sent, err := s.transRepo.GetSentTodayTx(tx, fromUser.ID)
+	if err != nil {
+		return err
 	}
+	if sent+amount > limit {
+		return userErrorf("превышен суточный лимит переводов: отправлено %d из %d", sent, limit)
+	}
+	return nil
+}
+
+const maxBulkRecipients = 100
 
+// SplitEvenly делит totalAmount поровну между получателями. Остаток от деления
+// достаётся первым получателям списка по одной монете.
+func SplitEvenly(toUsers []string, totalAmount int) ([]models.BulkTransfer, error) {
+	if len(toUsers) == 0 {
+		return nil, userErrorf("не указаны получатели")
+	}
+	if totalAmount < len(toUsers) {
+		return nil, userErrorf("сумма %d слишком мала для %d получателей", totalAmount, len(toUsers))
+	}
+	share, rest := totalAmount/len(toUsers), totalAmount%len(toUsers)
+	transfers := make([]models.BulkTransfer, len(toUsers))
+	for i, toUser := range toUsers {
+		transfers[i] = models.BulkTransfer{ToUser: toUser, Amount: share}
+		if i < rest {
+			transfers[i].Amount++
+		}
+	}
+	return transfers, nil
+}
+
+// SendCoinsBulk переводит монеты нескольким получателям одной транзакцией: либо проходят
+// все переводы, либо ни один. Строки пользователей блокируются одним запросом в порядке id.
+func (s *TransactionService) SendCoinsBulk(fromUsername string, transfers []models.BulkTransfer) error {
+	if len(transfers) == 0 {
+		return userErrorf("не указаны получатели")
+	}
+	if len(transfers) > maxBulkRecipients {
+		return userErrorf("не более %d получателей за раз", maxBulkRecipients)
+	}
+
+	usernames := []string{fromUsername}
+	seen := make(map[string]bool, len(transfers))
+	total := 0
+	for _, t := range transfers {
+		if t.ToUser == fromUsername {
+			return userErrorf("нельзя отправить монеты самому себе")
+		}
+		if seen[t.ToUser] {
+			return userErrorf("получатель %s указан несколько раз", t.ToUser)
+		}
+		if t.Amount <= 0 {
+			return userErrorf("сумма для %s должна быть положительной", t.ToUser)
+		}
+		if err := s.checkAmount(t.Amount); err != nil {
+			return err
+		}
+		seen[t.ToUser] = true
+		usernames = append(usernames, t.ToUser)
+		total += t.Amount
+	}
+
+	var transactions []*models.Transaction
+	err := withTxRetry(func() error {
+		var err error
+		transactions, err = s.sendCoinsBulk(fromUsername, usernames, transfers, total)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	for i, t := range transactions {
+		s.publishSent(fromUsername, transfers[i].ToUser, t)
+	}
+	return nil
+}
+
+func (s *TransactionService) sendCoinsBulk(fromUsername string, usernames []string, transfers []models.BulkTransfer, total int) ([]*models.Transaction, error) {
 	tx, err := s.db.Begin()
 	if err != nil {
-		return fmt.Errorf("ошибка начала транзакции: %v", err)
+		return nil, fmt.Errorf("ошибка начала транзакции: %w", err)
 	}
 	defer tx.Rollback()
 
-	var fromUserID, fromUserCoins int
-	err = tx.QueryRow("SELECT id, coins FROM users WHERE username = $1 FOR UPDATE", fromUsername).
-		Scan(&fromUserID, &fromUserCoins)
+	users, err := s.userRepo.LockUsersTx(tx, usernames)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return fmt.Errorf("отправитель %s не найден", fromUsername)
+		return nil, err
+	}
+
+	fromUser := users[fromUsername]
+	if fromUser == nil {
+		return nil, userErrorf("отправитель %s не найден", fromUsername)
+	}
+	if !fromUser.IsActive {
+		return nil, userErrorf("отправитель %s деактивирован", fromUsername)
+	}
+	var missing, inactive, overflowing []string
+	for _, t := range transfers {
+		toUser := users[t.ToUser]
+		switch {
+		case toUser == nil:
+			missing = append(missing, t.ToUser)
+		case !toUser.IsActive:
+			inactive = append(inactive, t.ToUser)
+		case toUser.Coins > maxAmount-t.Amount:
+			overflowing = append(overflowing, t.ToUser)
 		}
-		return fmt.Errorf("ошибка блокировки отправителя: %v", err)
+	}
+	if len(missing) > 0 {
+		return nil, userErrorf("получатели не найдены: %s", strings.Join(missing, ", "))
+	}
+	if len(inactive) > 0 {
+		return nil, userErrorf("получатели деактивированы: %s", strings.Join(inactive, ", "))
+	}
+	if len(overflowing) > 0 {
+		return nil, userErrorf("баланс получателей превысит допустимый максимум: %s", strings.Join(overflowing, ", "))
+	}
+	if fromUser.SpendableCoins() < total {
+		return nil, userErrorf("недостаточно монет у %s: %d < %d", fromUsername, fromUser.SpendableCoins(), total)
+	}
+	if err := s.checkDailyLimit(tx, fromUser, total); err != nil {
+		return nil, err
 	}
 
-	var toUserID, toUserCoins int
-	err = tx.QueryRow("SELECT id, coins FROM users WHERE username = $1 FOR UPDATE", toUsername).
-		Scan(&toUserID, &toUserCoins)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return fmt.Errorf("получатель %s не найден", toUsername)
+	transactions := make([]*models.Transaction, 0, len(transfers))
+	fromUser.Coins -= total
+	if err := s.userRepo.UpdateUserBalanceTx(tx, fromUser); err != nil {
+		return nil, fmt.Errorf("ошибка обновления баланса отправителя: %w", err)
+	}
+	for _, t := range transfers {
+		toUser := users[t.ToUser]
+		toUser.Coins += t.Amount
+		if err := s.userRepo.UpdateUserBalanceTx(tx, toUser); err != nil {
+			return nil, fmt.Errorf("ошибка обновления баланса получателя: %w", err)
 		}
-		return fmt.Errorf("ошибка блокировки получателя: %v", err)
+		transaction := &models.Transaction{
+			FromUserID: fromUser.ID,
+			ToUserID:   toUser.ID,
+			Amount:     t.Amount,
+		}
+		if err := s.transRepo.CreateTransaction(tx, transaction); err != nil {
+			return nil, fmt.Errorf("ошибка записи транзакции: %w", err)
+		}
+		if err := s.outboxRepo.AppendTx(tx, fromUser.ID, transferred(fromUsername, t.ToUser, transaction)); err != nil {
+			return nil, err
+		}
+		if err := s.notifyReceivedTx(tx, fromUsername, transaction); err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, transaction)
 	}
 
-	if fromUserCoins < amount {
-		return fmt.Errorf("недостаточно монет у %s: %d < %d", fromUsername, fromUserCoins, amount)
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("ошибка фиксации транзакции: %w", err)
 	}
 
-	fromUser := &models.User{ID: fromUserID, Coins: fromUserCoins - amount}
-	toUser := &models.User{ID: toUserID, Coins: toUserCoins + amount}
+	return transactions, nil
+}
 
-	if err := s.userRepo.UpdateUserBalanceTx(tx, fromUser); err != nil {
-		return fmt.Errorf("ошибка обновления баланса отправителя: %v", err)
+// GrantCoins начисляет монеты пользователю без списания с другого счёта.
+func (s *TransactionService) GrantCoins(toUsername string, amount int) error {
+	if amount <= 0 {
+		return userErrorf("сумма должна быть положительной")
+	}
+	if amount > maxAmount {
+		return userErrorf("сумма %d слишком велика", amount)
+	}
+
+	var transaction *models.Transaction
+	err := withTxRetry(func() error {
+		var err error
+		transaction, err = s.grantCoins(toUsername, amount)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	s.userRepo.Config.Events.Publish(events.Event{
+		Kind:     events.CoinsGranted,
+		TargetID: transaction.ToUserID,
+		Target:   toUsername,
+		Amount:   amount,
+		At:       transaction.CreatedAt,
+	})
+	return nil
+}
+
+func (s *TransactionService) grantCoins(toUsername string, amount int) (*models.Transaction, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	users, err := s.userRepo.LockUsersTx(tx, []string{toUsername})
+	if err != nil {
+		return nil, err
+	}
+	toUser := users[toUsername]
+	if toUser == nil {
+		return nil, userErrorf("получатель %s не найден", toUsername)
 	}
+	if !toUser.IsActive {
+		return nil, userErrorf("получатель %s деактивирован", toUsername)
+	}
+	if toUser.Coins > maxAmount-amount {
+		return nil, userErrorf("баланс получателя %s превысит допустимый максимум", toUsername)
+	}
+
+	toUser.Coins += amount
 	if err := s.userRepo.UpdateUserBalanceTx(tx, toUser); err != nil {
-		return fmt.Errorf("ошибка обновления баланса получателя: %v", err)
+		return nil, fmt.Errorf("ошибка обновления баланса получателя: %w", err)
 	}
 
 	transaction := &models.Transaction{
-		FromUserID: fromUser.ID,
-		ToUserID:   toUser.ID,
-		Amount:     amount,
+		ToUserID: toUser.ID,
+		Amount:   amount,
+		Kind:     models.TransactionKindGrant,
 	}
 	if err := s.transRepo.CreateTransaction(tx, transaction); err != nil {
-		return fmt.Errorf("ошибка записи транзакции: %v", err)
+		return nil, fmt.Errorf("ошибка записи транзакции: %w", err)
+	}
+	if err := s.outboxRepo.AppendTx(tx, toUser.ID, transferred("", toUsername, transaction)); err != nil {
+		return nil, err
+	}
+	notification := &models.Notification{
+		UserID:  toUser.ID,
+		Kind:    models.NotificationCoinsGranted,
+		Message: fmt.Sprintf("Вам начислено %d монет", amount),
+	}
+	if err := s.notificationRepo.CreateNotificationTx(tx, notification); err != nil {
+		return nil, err
 	}
 
 	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("ошибка фиксации транзакции: %v", err)
+		return nil, fmt.Errorf("ошибка фиксации транзакции: %w", err)
 	}
-
-	return nil
+	return transaction, nil
 }
 
 func (s *TransactionService) GetUserTransactions(userID int) ([]models.Transaction, error) {
@@ -1,14 +1,16 @@
 package services
 
 import (
-	"database/sql"
+	"math"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/go-redis/redis/v8"
 	"github.com/itocode21/MerchServiceAvito/internal/config"
+	"github.com/itocode21/MerchServiceAvito/internal/models"
 	"github.com/itocode21/MerchServiceAvito/internal/repositories"
+	"github.com/lib/pq"
 )
 
 func TestSendCoins(t *testing.T) {
@@ -31,7 +33,10 @@ func TestSendCoins(t *testing.T) {
 
 	userRepo := repositories.NewUserRepository(cfg)
 	transRepo := repositories.NewTransactionRepository(db)
-	service := NewTransactionService(userRepo, transRepo)
+	service := NewTransactionService(userRepo, transRepo, repositories.NewOutboxRepository(db), repositories.NewNotificationRepository(db))
+
+	lockQuery := "SELECT id, username, coins, held_coins, is_active FROM users WHERE username = ANY\\(\\$1\\) ORDER BY id FOR UPDATE"
+	lockColumns := []string{"id", "username", "coins", "held_coins", "is_active"}
 
 	tests := []struct {
 		name         string
@@ -49,16 +54,12 @@ func TestSendCoins(t *testing.T) {
 			amount:       100,
 			setupMock: func() {
 				mock.ExpectBegin()
-				// Мокаем SELECT FOR UPDATE для отправителя
-				mock.ExpectQuery("SELECT id, coins FROM users WHERE username = \\$1 FOR UPDATE").
-					WithArgs("user1").
-					WillReturnRows(sqlmock.NewRows([]string{"id", "coins"}).
-						AddRow(1, 1000))
-				// Мокаем SELECT FOR UPDATE для получателя
-				mock.ExpectQuery("SELECT id, coins FROM users WHERE username = \\$1 FOR UPDATE").
-					WithArgs("user2").
-					WillReturnRows(sqlmock.NewRows([]string{"id", "coins"}).
-						AddRow(2, 500))
+				// Мокаем блокировку отправителя и получателя в порядке id
+				mock.ExpectQuery(lockQuery).
+					WithArgs(sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows(lockColumns).
+						AddRow(1, "user1", 1000, 0, true).
+						AddRow(2, "user2", 500, 0, true))
 				// Мокаем обновление баланса отправителя
 				mock.ExpectExec("UPDATE users SET coins = \\$1 WHERE id = \\$2").
 					WithArgs(900, 1).
@@ -69,10 +70,39 @@ func TestSendCoins(t *testing.T) {
 					WillReturnResult(sqlmock.NewResult(1, 1))
 				// Мокаем создание транзакции
 				createdAt, _ := time.Parse(time.RFC3339, "2025-02-24T12:00:00Z")
-				mock.ExpectQuery("INSERT INTO transactions \\(from_user_id, to_user_id, amount\\) VALUES \\(\\$1, \\$2, \\$3\\) RETURNING id, created_at").
-					WithArgs(1, 2, 100).
+				mock.ExpectQuery("INSERT INTO transactions \\(from_user_id, to_user_id, amount, kind, team_id\\) VALUES \\(\\$1, \\$2, \\$3, \\$4, \\$5\\) RETURNING id, created_at").
+					WithArgs(1, 2, 100, "transfer", nil).
 					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).
 						AddRow(1, createdAt))
+				mock.ExpectExec("INSERT INTO outbox_events").WithArgs(models.EventCoinsTransferred, sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectQuery("INSERT INTO notifications").WithArgs(2, models.NotificationCoinsReceived, "user1 отправил(а) вам 100 монет").WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+				mock.ExpectCommit()
+			},
+			wantErr: false,
+		},
+		{
+			name:         "Получатель с меньшим id",
+			fromUsername: "user2",
+			toUsername:   "user1",
+			amount:       100,
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockQuery).
+					WithArgs(sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows(lockColumns).
+						AddRow(1, "user1", 1000, 0, true).
+						AddRow(2, "user2", 500, 0, true))
+				mock.ExpectExec("UPDATE users SET coins = \\$1 WHERE id = \\$2").
+					WithArgs(400, 2).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec("UPDATE users SET coins = \\$1 WHERE id = \\$2").
+					WithArgs(1100, 1).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectQuery("INSERT INTO transactions").
+					WithArgs(2, 1, 100, "transfer", nil).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(2, time.Now()))
+				mock.ExpectExec("INSERT INTO outbox_events").WithArgs(models.EventCoinsTransferred, sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectQuery("INSERT INTO notifications").WithArgs(sqlmock.AnyArg(), models.NotificationCoinsReceived, sqlmock.AnyArg()).WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
 				mock.ExpectCommit()
 			},
 			wantErr: false,
@@ -84,14 +114,11 @@ func TestSendCoins(t *testing.T) {
 			amount:       2000,
 			setupMock: func() {
 				mock.ExpectBegin()
-				mock.ExpectQuery("SELECT id, coins FROM users WHERE username = \\$1 FOR UPDATE").
-					WithArgs("user1").
-					WillReturnRows(sqlmock.NewRows([]string{"id", "coins"}).
-						AddRow(1, 1000))
-				mock.ExpectQuery("SELECT id, coins FROM users WHERE username = \\$1 FOR UPDATE").
-					WithArgs("user2").
-					WillReturnRows(sqlmock.NewRows([]string{"id", "coins"}).
-						AddRow(2, 500))
+				mock.ExpectQuery(lockQuery).
+					WithArgs(sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows(lockColumns).
+						AddRow(1, "user1", 1000, 0, true).
+						AddRow(2, "user2", 500, 0, true))
 				mock.ExpectRollback()
 			},
 			wantErr: true,
@@ -104,9 +131,10 @@ func TestSendCoins(t *testing.T) {
 			amount:       100,
 			setupMock: func() {
 				mock.ExpectBegin()
-				mock.ExpectQuery("SELECT id, coins FROM users WHERE username = \\$1 FOR UPDATE").
-					WithArgs("unknown").
-					WillReturnError(sql.ErrNoRows)
+				mock.ExpectQuery(lockQuery).
+					WithArgs(sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows(lockColumns).
+						AddRow(2, "user2", 500, 0, true))
 				mock.ExpectRollback()
 			},
 			wantErr: true,
@@ -119,18 +147,47 @@ func TestSendCoins(t *testing.T) {
 			amount:       100,
 			setupMock: func() {
 				mock.ExpectBegin()
-				mock.ExpectQuery("SELECT id, coins FROM users WHERE username = \\$1 FOR UPDATE").
-					WithArgs("user1").
-					WillReturnRows(sqlmock.NewRows([]string{"id", "coins"}).
-						AddRow(1, 1000))
-				mock.ExpectQuery("SELECT id, coins FROM users WHERE username = \\$1 FOR UPDATE").
-					WithArgs("unknown").
-					WillReturnError(sql.ErrNoRows)
+				mock.ExpectQuery(lockQuery).
+					WithArgs(sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows(lockColumns).
+						AddRow(1, "user1", 1000, 0, true))
 				mock.ExpectRollback()
 			},
 			wantErr: true,
 			errMsg:  "получатель unknown не найден",
 		},
+		{
+			name:         "Повтор после взаимоблокировки",
+			fromUsername: "user1",
+			toUsername:   "user2",
+			amount:       100,
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockQuery).
+					WithArgs(sqlmock.AnyArg()).
+					WillReturnError(&pq.Error{Code: "40P01", Message: "deadlock detected"})
+				mock.ExpectRollback()
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockQuery).
+					WithArgs(sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows(lockColumns).
+						AddRow(1, "user1", 1000, 0, true).
+						AddRow(2, "user2", 500, 0, true))
+				mock.ExpectExec("UPDATE users SET coins = \\$1 WHERE id = \\$2").
+					WithArgs(900, 1).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec("UPDATE users SET coins = \\$1 WHERE id = \\$2").
+					WithArgs(600, 2).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectQuery("INSERT INTO transactions").
+					WithArgs(1, 2, 100, "transfer", nil).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(3, time.Now()))
+				mock.ExpectExec("INSERT INTO outbox_events").WithArgs(models.EventCoinsTransferred, sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectQuery("INSERT INTO notifications").WithArgs(sqlmock.AnyArg(), models.NotificationCoinsReceived, sqlmock.AnyArg()).WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+				mock.ExpectCommit()
+			},
+			wantErr: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -152,3 +209,341 @@ func TestSendCoins(t *testing.T) {
 		})
 	}
 }
+
+func TestGrantCoins(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка создания мока: %v", err)
+	}
+	defer db.Close()
+
+	cfg := &config.Config{DB: db}
+	userRepo := repositories.NewUserRepository(cfg)
+	transRepo := repositories.NewTransactionRepository(db)
+	service := NewTransactionService(userRepo, transRepo, repositories.NewOutboxRepository(db), repositories.NewNotificationRepository(db))
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, username, coins, held_coins, is_active FROM users").
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "coins", "held_coins", "is_active"}).
+			AddRow(2, "user2", 500, 0, true))
+	mock.ExpectExec("UPDATE users SET coins = \\$1 WHERE id = \\$2").
+		WithArgs(550, 2).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("INSERT INTO transactions \\(from_user_id, to_user_id, amount, kind, team_id\\)").
+		WithArgs(nil, 2, 50, "grant", nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+	mock.ExpectExec("INSERT INTO outbox_events").WithArgs(models.EventCoinsTransferred, 2, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("INSERT INTO notifications").
+		WithArgs(2, models.NotificationCoinsGranted, "Вам начислено 50 монет").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+	mock.ExpectCommit()
+
+	if err := service.GrantCoins("user2", 50); err != nil {
+		t.Errorf("GrantCoins() error = %v, want nil", err)
+	}
+	if err := service.GrantCoins("user2", 0); err == nil || !IsUserError(err) || err.Error() != "сумма должна быть положительной" {
+		t.Errorf("GrantCoins() error = %v, want %q", err, "сумма должна быть положительной")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Не все ожидания мока выполнены: %v", err)
+	}
+}
+
+func TestSendCoinsBulk(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка создания мока: %v", err)
+	}
+	defer db.Close()
+
+	cfg := &config.Config{DB: db}
+	userRepo := repositories.NewUserRepository(cfg)
+	transRepo := repositories.NewTransactionRepository(db)
+	service := NewTransactionService(userRepo, transRepo, repositories.NewOutboxRepository(db), repositories.NewNotificationRepository(db))
+
+	lockQuery := "SELECT id, username, coins, held_coins, is_active FROM users WHERE username = ANY\\(\\$1\\) ORDER BY id FOR UPDATE"
+	lockColumns := []string{"id", "username", "coins", "held_coins", "is_active"}
+
+	tests := []struct {
+		name      string
+		transfers []models.BulkTransfer
+		setupMock func()
+		wantErr   bool
+		errMsg    string
+	}{
+		{
+			name:      "Успешная рассылка",
+			transfers: []models.BulkTransfer{{ToUser: "user3", Amount: 10}, {ToUser: "user2", Amount: 20}},
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockQuery).
+					WithArgs(sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows(lockColumns).
+						AddRow(1, "user1", 100, 0, true).
+						AddRow(2, "user2", 0, 0, true).
+						AddRow(3, "user3", 5, 0, true))
+				mock.ExpectExec("UPDATE users SET coins = \\$1 WHERE id = \\$2").WithArgs(70, 1).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec("UPDATE users SET coins = \\$1 WHERE id = \\$2").WithArgs(15, 3).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectQuery("INSERT INTO transactions").
+					WithArgs(1, 3, 10, "transfer", nil).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+				mock.ExpectExec("INSERT INTO outbox_events").WithArgs(models.EventCoinsTransferred, sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectQuery("INSERT INTO notifications").WithArgs(sqlmock.AnyArg(), models.NotificationCoinsReceived, sqlmock.AnyArg()).WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+				mock.ExpectExec("UPDATE users SET coins = \\$1 WHERE id = \\$2").WithArgs(20, 2).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectQuery("INSERT INTO transactions").
+					WithArgs(1, 2, 20, "transfer", nil).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(2, time.Now()))
+				mock.ExpectExec("INSERT INTO outbox_events").WithArgs(models.EventCoinsTransferred, sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectQuery("INSERT INTO notifications").WithArgs(sqlmock.AnyArg(), models.NotificationCoinsReceived, sqlmock.AnyArg()).WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+				mock.ExpectCommit()
+			},
+			wantErr: false,
+		},
+		{
+			name:      "Часть получателей не найдена",
+			transfers: []models.BulkTransfer{{ToUser: "ghost1", Amount: 10}, {ToUser: "user2", Amount: 10}, {ToUser: "ghost2", Amount: 10}},
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockQuery).
+					WithArgs(sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows(lockColumns).
+						AddRow(1, "user1", 100, 0, true).
+						AddRow(2, "user2", 0, 0, true))
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+			errMsg:  "получатели не найдены: ghost1, ghost2",
+		},
+		{
+			name:      "Недостаточно монет на всех",
+			transfers: []models.BulkTransfer{{ToUser: "user2", Amount: 60}, {ToUser: "user3", Amount: 60}},
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockQuery).
+					WithArgs(sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows(lockColumns).
+						AddRow(1, "user1", 100, 0, true).
+						AddRow(2, "user2", 0, 0, true).
+						AddRow(3, "user3", 0, 0, true))
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+			errMsg:  "недостаточно монет у user1: 100 < 120",
+		},
+		{
+			name:      "Отправитель деактивирован",
+			transfers: []models.BulkTransfer{{ToUser: "user2", Amount: 10}},
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockQuery).
+					WithArgs(sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows(lockColumns).
+						AddRow(1, "user1", 100, 0, false).
+						AddRow(2, "user2", 0, 0, true))
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+			errMsg:  "отправитель user1 деактивирован",
+		},
+		{
+			name:      "Повторяющийся получатель",
+			transfers: []models.BulkTransfer{{ToUser: "user2", Amount: 10}, {ToUser: "user2", Amount: 10}},
+			setupMock: func() {},
+			wantErr:   true,
+			errMsg:    "получатель user2 указан несколько раз",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+			err := service.SendCoinsBulk("user1", tt.transfers)
+			if tt.wantErr {
+				if err == nil || err.Error() != tt.errMsg {
+					t.Errorf("SendCoinsBulk() error = %v, want %q", err, tt.errMsg)
+				}
+			} else if err != nil {
+				t.Errorf("SendCoinsBulk() error = %v, want nil", err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Не все ожидания мока выполнены: %v", err)
+			}
+		})
+	}
+}
+
+func TestSplitEvenly(t *testing.T) {
+	transfers, err := SplitEvenly([]string{"a", "b", "c"}, 100)
+	if err != nil {
+		t.Fatalf("SplitEvenly() error = %v", err)
+	}
+	want := []int{34, 33, 33}
+	for i, tr := range transfers {
+		if tr.Amount != want[i] {
+			t.Errorf("SplitEvenly()[%d] = %d, want %d", i, tr.Amount, want[i])
+		}
+	}
+	if _, err := SplitEvenly([]string{"a", "b", "c"}, 2); err == nil {
+		t.Errorf("SplitEvenly() error = nil, want error")
+	}
+}
+
+func TestSendCoinsHidesInternalErrors(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка создания мока: %v", err)
+	}
+	defer db.Close()
+
+	cfg := &config.Config{DB: db}
+	userRepo := repositories.NewUserRepository(cfg)
+	transRepo := repositories.NewTransactionRepository(db)
+	service := NewTransactionService(userRepo, transRepo, repositories.NewOutboxRepository(db), repositories.NewNotificationRepository(db))
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, username, coins, held_coins, is_active FROM users").
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnError(&pq.Error{Code: "57014", Message: "canceling statement due to statement timeout"})
+	mock.ExpectRollback()
+
+	err = service.SendCoins("user1", "user2", 100)
+	if err == nil || IsUserError(err) {
+		t.Errorf("SendCoins() error = %v, want internal error", err)
+	}
+	if err := service.SendCoins("user1", "user2", 0); !IsUserError(err) {
+		t.Errorf("SendCoins() error = %v, want user error", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Не все ожидания мока выполнены: %v", err)
+	}
+}
+
+func TestSendCoinsLimits(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка создания мока: %v", err)
+	}
+	defer db.Close()
+
+	cfg := &config.Config{DB: db, TransferMaxAmount: 500, TransferDailyLimit: 1000}
+	userRepo := repositories.NewUserRepository(cfg)
+	transRepo := repositories.NewTransactionRepository(db)
+	service := NewTransactionService(userRepo, transRepo, repositories.NewOutboxRepository(db), repositories.NewNotificationRepository(db))
+
+	lockQuery := "SELECT id, username, coins, held_coins, is_active FROM users WHERE username = ANY\\(\\$1\\) ORDER BY id FOR UPDATE"
+	lockColumns := []string{"id", "username", "coins", "held_coins", "is_active"}
+	sentTodayQuery := "SELECT COALESCE\\(SUM\\(amount\\), 0\\) FROM transactions WHERE from_user_id = \\$1 AND kind = \\$2"
+
+	tests := []struct {
+		name         string
+		fromUsername string
+		toUsername   string
+		amount       int
+		setupMock    func()
+		wantErr      bool
+		errMsg       string
+	}{
+		{
+			name:         "Перевод самому себе",
+			fromUsername: "user1",
+			toUsername:   "user1",
+			amount:       100,
+			setupMock:    func() {},
+			wantErr:      true,
+			errMsg:       "нельзя отправить монеты самому себе",
+		},
+		{
+			name:         "Превышен лимит на один перевод",
+			fromUsername: "user1",
+			toUsername:   "user2",
+			amount:       501,
+			setupMock:    func() {},
+			wantErr:      true,
+			errMsg:       "сумма 501 превышает лимит на один перевод: 500",
+		},
+		{
+			name:         "Превышен суточный лимит",
+			fromUsername: "user1",
+			toUsername:   "user2",
+			amount:       300,
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockQuery).
+					WithArgs(sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows(lockColumns).
+						AddRow(1, "user1", 1000, 0, true).
+						AddRow(2, "user2", 500, 0, true))
+				mock.ExpectQuery(sentTodayQuery).
+					WithArgs(1, "transfer").
+					WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(800))
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+			errMsg:  "превышен суточный лимит переводов: отправлено 800 из 1000",
+		},
+		{
+			name:         "Переполнение баланса получателя",
+			fromUsername: "user1",
+			toUsername:   "user2",
+			amount:       100,
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockQuery).
+					WithArgs(sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows(lockColumns).
+						AddRow(1, "user1", 1000, 0, true).
+						AddRow(2, "user2", math.MaxInt32-50, 0, true))
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+			errMsg:  "баланс получателя user2 превысит допустимый максимум",
+		},
+		{
+			name:         "Перевод в пределах лимитов",
+			fromUsername: "user1",
+			toUsername:   "user2",
+			amount:       200,
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockQuery).
+					WithArgs(sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows(lockColumns).
+						AddRow(1, "user1", 1000, 0, true).
+						AddRow(2, "user2", 500, 0, true))
+				mock.ExpectQuery(sentTodayQuery).
+					WithArgs(1, "transfer").
+					WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(800))
+				mock.ExpectExec("UPDATE users SET coins = \\$1 WHERE id = \\$2").
+					WithArgs(800, 1).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec("UPDATE users SET coins = \\$1 WHERE id = \\$2").
+					WithArgs(700, 2).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectQuery("INSERT INTO transactions").
+					WithArgs(1, 2, 200, "transfer", nil).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+				mock.ExpectExec("INSERT INTO outbox_events").WithArgs(models.EventCoinsTransferred, sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectQuery("INSERT INTO notifications").WithArgs(sqlmock.AnyArg(), models.NotificationCoinsReceived, sqlmock.AnyArg()).WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+				mock.ExpectCommit()
+			},
+			wantErr: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+			err := service.SendCoins(tt.fromUsername, tt.toUsername, tt.amount)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SendCoins() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && err != nil && err.Error() != tt.errMsg {
+				t.Errorf("SendCoins() error message = %v, want %v", err.Error(), tt.errMsg)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Не все ожидания мока выполнены: %v", err)
+			}
+		})
+	}
+}
@@ -4,9 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/url"
+	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
+	"github.com/itocode21/MerchServiceAvito/internal/auth"
 	"github.com/itocode21/MerchServiceAvito/internal/models"
 	"github.com/itocode21/MerchServiceAvito/internal/repositories"
 	"golang.org/x/crypto/bcrypt"
@@ -14,12 +18,16 @@ import (
 
 type UserService struct {
 	userRepo   *repositories.UserRepository
+	transRepo  *repositories.TransactionRepository
+	outboxRepo *repositories.OutboxRepository
 	workerPool chan struct{} // Пул горутин
 }
 
-func NewUserService(userRepo *repositories.UserRepository) *UserService {
+func NewUserService(userRepo *repositories.UserRepository, transRepo *repositories.TransactionRepository, outboxRepo *repositories.OutboxRepository) *UserService {
 	return &UserService{
 		userRepo:   userRepo,
+		transRepo:  transRepo,
+		outboxRepo: outboxRepo,
 		workerPool: make(chan struct{}, 100), //100 горутинами
 	}
 }
@@ -109,21 +117,34 @@ func (s *UserService) RegisterUser(username, password string) (*models.User, err
 		Coins:        1000,
 	}
 
-	// Синхронная вставка
-	if err := s.userRepo.CreateUser(user); err != nil {
+	// Синхронная вставка вместе с событием регистрации
+	if err := s.createUser(user); err != nil {
 		return nil, fmt.Errorf("ошибка при создании пользователя: %v", err)
 	}
 
-	// хеш пароля в Redis
-	hashCacheKey := "user_hash:" + username
-	s.userRepo.Config.Redis.Set(context.Background(), hashCacheKey, user.PasswordHash, 5*time.Minute)
-
 	userJSON, _ := json.Marshal(user)
 	s.userRepo.Config.Redis.Set(context.Background(), cacheKey, userJSON, 5*time.Minute)
 	s.userRepo.Config.Redis.Set(context.Background(), existsKey, "true", 5*time.Minute)
 	return user, nil
 }
 
+func (s *UserService) createUser(user *models.User) error {
+	tx, err := s.userRepo.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := s.userRepo.CreateUserTx(tx, user); err != nil {
+		return err
+	}
+	registered := models.UserRegistered{UserID: user.ID, Username: user.Username, At: user.CreatedAt}
+	if err := s.outboxRepo.AppendTx(tx, user.ID, registered); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
 func (s *UserService) UpdateUserBalance(username string, amount int) error {
 	user, err := s.userRepo.GetUserByUsername(username)
 	if err != nil {
@@ -144,3 +165,197 @@ func (s *UserService) UpdateUserBalance(username string, amount int) error {
 	}
 	return nil
 }
+
+// DeactivateUser блокирует учётную запись уволившегося сотрудника и отзывает его токены.
+// Если указан transferTo, остаток монет переводится на этот счёт с записью в историю.
+func (s *UserService) DeactivateUser(username, transferTo string) error {
+	if transferTo == username {
+		return fmt.Errorf("нельзя перевести остаток на деактивируемый счёт")
+	}
+
+	tx, err := s.userRepo.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	usernames := []string{username}
+	if transferTo != "" {
+		usernames = append(usernames, transferTo)
+	}
+	users, err := s.userRepo.LockUsersTx(tx, usernames)
+	if err != nil {
+		return err
+	}
+
+	user := users[username]
+	if user == nil {
+		return fmt.Errorf("пользователь %s не найден", username)
+	}
+	if !user.IsActive {
+		return fmt.Errorf("пользователь %s уже деактивирован", username)
+	}
+
+	if transferTo != "" {
+		target := users[transferTo]
+		if target == nil {
+			return fmt.Errorf("получатель %s не найден", transferTo)
+		}
+		if !target.IsActive {
+			return fmt.Errorf("получатель %s деактивирован", transferTo)
+		}
+
+		// Зарезервированные в эскроу монеты остаются на счёте до закрытия пари
+		if amount := user.SpendableCoins(); amount > 0 {
+			user.Coins -= amount
+			target.Coins += amount
+			if err := s.userRepo.UpdateUserBalanceTx(tx, user); err != nil {
+				return fmt.Errorf("ошибка обновления баланса: %v", err)
+			}
+			if err := s.userRepo.UpdateUserBalanceTx(tx, target); err != nil {
+				return fmt.Errorf("ошибка обновления баланса получателя: %v", err)
+			}
+			transaction := &models.Transaction{
+				FromUserID: user.ID,
+				ToUserID:   target.ID,
+				Amount:     amount,
+				Kind:       models.TransactionKindOffboarding,
+			}
+			if err := s.transRepo.CreateTransaction(tx, transaction); err != nil {
+				return fmt.Errorf("ошибка записи транзакции: %v", err)
+			}
+			if err := s.outboxRepo.AppendTx(tx, user.ID, transferred(username, transferTo, transaction)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := s.userRepo.SetUserActiveTx(tx, user.ID, false); err != nil {
+		return err
+	}
+	if err := auth.RevokeTokens(username); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка фиксации транзакции: %v", err)
+	}
+
+	s.userRepo.Config.Redis.Del(context.Background(), "user:"+username, "user_info:"+username)
+	if transferTo != "" {
+		publishBalanceChanged(s.userRepo.Config.Events, username, transferTo)
+	}
+	return nil
+}
+
+func (s *UserService) ReactivateUser(username string) error {
+	tx, err := s.userRepo.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	users, err := s.userRepo.LockUsersTx(tx, []string{username})
+	if err != nil {
+		return err
+	}
+	user := users[username]
+	if user == nil {
+		return fmt.Errorf("пользователь %s не найден", username)
+	}
+	if user.IsActive {
+		return fmt.Errorf("пользователь %s уже активен", username)
+	}
+
+	if err := s.userRepo.SetUserActiveTx(tx, user.ID, true); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка фиксации транзакции: %v", err)
+	}
+
+	s.userRepo.Config.Redis.Del(context.Background(), "user:"+username)
+	return nil
+}
+
+func (s *UserService) GetProfile(username string) (*models.Profile, error) {
+	profile, err := s.userRepo.GetProfile(username)
+	if err != nil {
+		return nil, err
+	}
+	if profile == nil {
+		return nil, fmt.Errorf("пользователь %s не найден", username)
+	}
+	return profile, nil
+}
+
+func (s *UserService) UpdateProfile(username string, upd *models.ProfileUpdate) (*models.Profile, error) {
+	fields := []struct {
+		name  string
+		value *string
+	}{
+		{"displayName", upd.DisplayName},
+		{"department", upd.Department},
+		{"office", upd.Office},
+		{"title", upd.Title},
+	}
+	for _, f := range fields {
+		if f.value == nil {
+			continue
+		}
+		*f.value = strings.TrimSpace(*f.value)
+		if utf8.RuneCountInString(*f.value) > 255 {
+			return nil, fmt.Errorf("поле %s длиннее 255 символов", f.name)
+		}
+	}
+	if upd.AvatarURL != nil {
+		*upd.AvatarURL = strings.TrimSpace(*upd.AvatarURL)
+		if err := validateAvatarURL(*upd.AvatarURL); err != nil {
+			return nil, err
+		}
+	}
+
+	profile, err := s.userRepo.UpdateProfile(username, upd)
+	if err != nil {
+		return nil, err
+	}
+	if profile == nil {
+		return nil, fmt.Errorf("пользователь %s не найден", username)
+	}
+	return profile, nil
+}
+
+const (
+	userSearchMinQuery = 2
+	userSearchMaxLimit = 20
+)
+
+// SearchUsers ищет получателей перевода. Короткие запросы и большие выборки
+// запрещены, чтобы справочник нельзя было выгрузить целиком.
+func (s *UserService) SearchUsers(q string, limit int) ([]models.Profile, error) {
+	q = strings.TrimSpace(q)
+	if utf8.RuneCountInString(q) < userSearchMinQuery {
+		return nil, fmt.Errorf("запрос должен содержать не менее %d символов", userSearchMinQuery)
+	}
+	if utf8.RuneCountInString(q) > 255 {
+		return nil, fmt.Errorf("запрос слишком длинный")
+	}
+	if limit <= 0 || limit > userSearchMaxLimit {
+		limit = userSearchMaxLimit
+	}
+	return s.userRepo.SearchProfiles(q, limit)
+}
+
+func validateAvatarURL(raw string) error {
+	if raw == "" {
+		return nil
+	}
+	if len(raw) > 1024 {
+		return fmt.Errorf("ссылка на аватар длиннее 1024 символов")
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("ссылка на аватар должна быть абсолютным http(s) URL")
+	}
+	return nil
+}
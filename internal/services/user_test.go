@@ -0,0 +1,222 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-redis/redis/v8"
+	"github.com/itocode21/MerchServiceAvito/internal/config"
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+	"github.com/itocode21/MerchServiceAvito/internal/repositories"
+)
+
+func TestDeactivateUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка создания мока: %v", err)
+	}
+	defer db.Close()
+
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+	})
+
+	cfg := &config.Config{
+		DB:        db,
+		JWTSecret: []byte("test_secret_key"),
+		Redis:     redisClient,
+	}
+
+	userRepo := repositories.NewUserRepository(cfg)
+	transRepo := repositories.NewTransactionRepository(db)
+	service := NewUserService(userRepo, transRepo, repositories.NewOutboxRepository(db))
+
+	lockQuery := "SELECT id, username, coins, held_coins, is_active FROM users WHERE username = ANY\\(\\$1\\) ORDER BY id FOR UPDATE"
+	lockColumns := []string{"id", "username", "coins", "held_coins", "is_active"}
+
+	tests := []struct {
+		name       string
+		username   string
+		transferTo string
+		setupMock  func()
+		wantErr    bool
+		errMsg     string
+	}{
+		{
+			name:       "Деактивация с переводом остатка",
+			username:   "leaver",
+			transferTo: "hr-pool",
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockQuery).
+					WithArgs(sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows(lockColumns).
+						AddRow(3, "hr-pool", 100, 0, true).
+						AddRow(5, "leaver", 250, 0, true))
+				mock.ExpectExec("UPDATE users SET coins = \\$1 WHERE id = \\$2").
+					WithArgs(0, 5).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec("UPDATE users SET coins = \\$1 WHERE id = \\$2").
+					WithArgs(350, 3).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectQuery("INSERT INTO transactions \\(from_user_id, to_user_id, amount, kind, team_id\\)").
+					WithArgs(5, 3, 250, "offboarding", nil).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+				mock.ExpectExec("INSERT INTO outbox_events").WithArgs(models.EventCoinsTransferred, 5, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec("UPDATE users SET is_active = \\$1").
+					WithArgs(false, 5).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+			},
+			wantErr: false,
+		},
+		{
+			name:     "Уже деактивирован",
+			username: "leaver",
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockQuery).
+					WithArgs(sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows(lockColumns).AddRow(5, "leaver", 0, 0, false))
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+			errMsg:  "пользователь leaver уже деактивирован",
+		},
+		{
+			name:       "Счёт для остатка не найден",
+			username:   "leaver",
+			transferTo: "ghost",
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockQuery).
+					WithArgs(sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows(lockColumns).AddRow(5, "leaver", 250, 0, true))
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+			errMsg:  "получатель ghost не найден",
+		},
+		{
+			name:       "Перевод остатка самому себе",
+			username:   "leaver",
+			transferTo: "leaver",
+			setupMock:  func() {},
+			wantErr:    true,
+			errMsg:     "нельзя перевести остаток на деактивируемый счёт",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+			err := service.DeactivateUser(tt.username, tt.transferTo)
+			if tt.wantErr {
+				if err == nil || err.Error() != tt.errMsg {
+					t.Errorf("DeactivateUser() error = %v, want %q", err, tt.errMsg)
+				}
+			} else if err != nil {
+				t.Errorf("DeactivateUser() error = %v, want nil", err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Не все ожидания мока выполнены: %v", err)
+			}
+		})
+	}
+}
+
+func TestUpdateProfile(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка создания мока: %v", err)
+	}
+	defer db.Close()
+
+	cfg := &config.Config{DB: db}
+	userRepo := repositories.NewUserRepository(cfg)
+	transRepo := repositories.NewTransactionRepository(db)
+	service := NewUserService(userRepo, transRepo, repositories.NewOutboxRepository(db))
+
+	strPtr := func(s string) *string { return &s }
+
+	tests := []struct {
+		name      string
+		update    models.ProfileUpdate
+		setupMock func()
+		wantErr   bool
+		errMsg    string
+	}{
+		{
+			name:   "Обновление отдела и аватара",
+			update: models.ProfileUpdate{Department: strPtr("  Platform "), AvatarURL: strPtr("https://cdn.example.com/a.png")},
+			setupMock: func() {
+				mock.ExpectQuery("UPDATE users SET display_name = COALESCE\\(\\$2, display_name\\)").
+					WithArgs("user1", nil, "Platform", nil, nil, "https://cdn.example.com/a.png").
+					WillReturnRows(sqlmock.NewRows([]string{"username", "display_name", "department", "office", "title", "avatar_url"}).
+						AddRow("user1", "Иван", "Platform", "", "", "https://cdn.example.com/a.png"))
+			},
+			wantErr: false,
+		},
+		{
+			name:      "Некорректная ссылка на аватар",
+			update:    models.ProfileUpdate{AvatarURL: strPtr("javascript:alert(1)")},
+			setupMock: func() {},
+			wantErr:   true,
+			errMsg:    "ссылка на аватар должна быть абсолютным http(s) URL",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+			profile, err := service.UpdateProfile("user1", &tt.update)
+			if tt.wantErr {
+				if err == nil || err.Error() != tt.errMsg {
+					t.Errorf("UpdateProfile() error = %v, want %q", err, tt.errMsg)
+				}
+			} else if err != nil {
+				t.Errorf("UpdateProfile() error = %v, want nil", err)
+			} else if profile.Department != "Platform" {
+				t.Errorf("UpdateProfile() department = %q, want %q", profile.Department, "Platform")
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Не все ожидания мока выполнены: %v", err)
+			}
+		})
+	}
+}
+
+func TestSearchUsers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка создания мока: %v", err)
+	}
+	defer db.Close()
+
+	cfg := &config.Config{DB: db}
+	userRepo := repositories.NewUserRepository(cfg)
+	transRepo := repositories.NewTransactionRepository(db)
+	service := NewUserService(userRepo, transRepo, repositories.NewOutboxRepository(db))
+
+	mock.ExpectQuery("SELECT username, display_name, department, office, title, avatar_url FROM users WHERE is_active").
+		WithArgs("iv_", "iv\\_%", 20).
+		WillReturnRows(sqlmock.NewRows([]string{"username", "display_name", "department", "office", "title", "avatar_url"}).
+			AddRow("iv_petrov", "Иван Петров", "Platform", "", "", ""))
+
+	profiles, err := service.SearchUsers(" iv_ ", 100)
+	if err != nil {
+		t.Fatalf("SearchUsers() error = %v, want nil", err)
+	}
+	if len(profiles) != 1 || profiles[0].Username != "iv_petrov" {
+		t.Errorf("SearchUsers() = %v", profiles)
+	}
+
+	if _, err := service.SearchUsers("a", 0); err == nil || err.Error() != "запрос должен содержать не менее 2 символов" {
+		t.Errorf("SearchUsers() error = %v, want %q", err, "запрос должен содержать не менее 2 символов")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Не все ожидания мока выполнены: %v", err)
+	}
+}
@@ -0,0 +1,267 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+	"github.com/itocode21/MerchServiceAvito/internal/repositories"
+)
+
+const (
+	webhookSecretPrefix   = "whsec_"
+	webhookBatch          = 20
+	webhookTimeout        = 10 * time.Second
+	webhookLease          = time.Minute
+	webhookMaxAttempts    = 10
+	webhookFirstBackoff   = 30 * time.Second
+	webhookMaxBackoff     = 6 * time.Hour
+	webhookRetentionDays  = 30
+	webhookDefaultLimit   = 50
+	webhookMaxLimit       = 200
+	webhookMaxErrorLength = 500
+)
+
+// Заголовки запроса вебхука. Подпись — HMAC-SHA256 секретом подписки от
+// строки "<timestamp>.<тело>", в виде "sha256=<hex>".
+const (
+	WebhookEventHeader     = "X-Merch-Event"
+	WebhookDeliveryHeader  = "X-Merch-Delivery"
+	WebhookTimestampHeader = "X-Merch-Timestamp"
+	WebhookSignatureHeader = "X-Merch-Signature"
+)
+
+// WebhookService рассылает события outbox подписчикам. Как events.Sink он ставит
+// событие в очередь доставок, а Deliver отправляет их с повторами по
+// экспоненциальной задержке; исчерпавшие попытки доставки попадают в список
+// недоставленных.
+type WebhookService struct {
+	webhookRepo *repositories.WebhookRepository
+	client      *http.Client
+}
+
+func NewWebhookService(webhookRepo *repositories.WebhookRepository) *WebhookService {
+	return &WebhookService{
+		webhookRepo: webhookRepo,
+		client:      &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+// CreateSubscription подписывает url на события eventTypes. Секрет для проверки
+// подписи возвращается только один раз.
+func (s *WebhookService) CreateSubscription(admin, rawURL string, eventTypes []string) (string, *models.WebhookSubscription, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", nil, userErrorf("адрес вебхука должен быть http(s) URL")
+	}
+	if len(eventTypes) == 0 {
+		return "", nil, userErrorf("не указаны типы событий")
+	}
+	for _, t := range eventTypes {
+		if !slices.Contains(models.OutboxEventTypes, t) {
+			return "", nil, userErrorf("неизвестный тип события %s", t)
+		}
+	}
+
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", nil, fmt.Errorf("ошибка генерации секрета: %w", err)
+	}
+	subscription := &models.WebhookSubscription{
+		URL:        u.String(),
+		EventTypes: slices.Compact(slices.Sorted(slices.Values(eventTypes))),
+		Secret:     webhookSecretPrefix + hex.EncodeToString(buf),
+	}
+	if err := s.webhookRepo.CreateSubscription(subscription, admin); err != nil {
+		return "", nil, err
+	}
+	return subscription.Secret, subscription, nil
+}
+
+func (s *WebhookService) GetSubscriptions() ([]models.WebhookSubscription, error) {
+	return s.webhookRepo.GetSubscriptions()
+}
+
+func (s *WebhookService) DeactivateSubscription(id int) error {
+	deactivated, err := s.webhookRepo.DeactivateSubscription(id)
+	if err != nil {
+		return err
+	}
+	if !deactivated {
+		return userErrorf("подписка %d не найдена или уже отключена", id)
+	}
+	return nil
+}
+
+// GetDeliveries возвращает журнал доставок подписки
+func (s *WebhookService) GetDeliveries(subscriptionID int, status string, limit, offset int) ([]*models.WebhookDelivery, error) {
+	exists, err := s.webhookRepo.SubscriptionExists(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, userErrorf("подписка %d не найдена", subscriptionID)
+	}
+	return s.getDeliveries(subscriptionID, status, limit, offset)
+}
+
+// GetDeadLetters возвращает доставки всех подписок, исчерпавшие попытки
+func (s *WebhookService) GetDeadLetters(limit, offset int) ([]*models.WebhookDelivery, error) {
+	return s.getDeliveries(0, models.WebhookDeliveryDead, limit, offset)
+}
+
+func (s *WebhookService) getDeliveries(subscriptionID int, status string, limit, offset int) ([]*models.WebhookDelivery, error) {
+	switch status {
+	case "", models.WebhookDeliveryPending, models.WebhookDeliveryDelivered, models.WebhookDeliveryDead:
+	default:
+		return nil, userErrorf("неизвестный статус доставки: %s", status)
+	}
+	if limit == 0 {
+		limit = webhookDefaultLimit
+	}
+	if limit < 0 || limit > webhookMaxLimit {
+		return nil, userErrorf("limit должен быть от 1 до %d", webhookMaxLimit)
+	}
+	if offset < 0 {
+		return nil, userErrorf("offset не может быть отрицательным")
+	}
+	return s.webhookRepo.GetDeliveries(subscriptionID, status, limit, offset)
+}
+
+// RetryDelivery возвращает недоставленное событие в очередь
+func (s *WebhookService) RetryDelivery(id int64) error {
+	requeued, err := s.webhookRepo.RequeueDelivery(id)
+	if err != nil {
+		return err
+	}
+	if !requeued {
+		return userErrorf("недоставленная доставка %d не найдена", id)
+	}
+	return nil
+}
+
+// Publish ставит событие outbox в очередь доставок подписчикам
+func (s *WebhookService) Publish(ctx context.Context, e models.OutboxEvent) error {
+	body, err := json.Marshal(models.WebhookPayload{ID: e.ID, Type: e.Type, CreatedAt: e.CreatedAt, Data: e.Payload})
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации события %d: %w", e.ID, err)
+	}
+	_, err = s.webhookRepo.EnqueueDeliveries(e.ID, e.Type, string(body))
+	return err
+}
+
+// Deliver отправляет очередную пачку доставок и возвращает, сколько из них удалось
+func (s *WebhookService) Deliver(ctx context.Context) (int, error) {
+	deliveries, err := s.webhookRepo.ClaimDue(webhookBatch, webhookLease)
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	for _, d := range deliveries {
+		wg.Add(1)
+		go func(d *models.WebhookDelivery) {
+			defer wg.Done()
+			s.attempt(ctx, d)
+		}(d)
+	}
+	wg.Wait()
+
+	delivered := 0
+	var errs []error
+	for _, d := range deliveries {
+		next := time.Now()
+		switch {
+		case d.Status == models.WebhookDeliveryDelivered:
+			delivered++
+		case d.Attempts >= webhookMaxAttempts:
+			d.Status = models.WebhookDeliveryDead
+		default:
+			next = next.Add(webhookBackoff(d.Attempts))
+		}
+		if err := s.webhookRepo.RecordAttempt(d, next); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return delivered, fmt.Errorf("доставок с несохранённым результатом: %d: %w", len(errs), errs[0])
+	}
+	return delivered, nil
+}
+
+// attempt отправляет доставку и записывает в неё результат попытки
+func (s *WebhookService) attempt(ctx context.Context, d *models.WebhookDelivery) {
+	d.Attempts++
+	d.LastStatusCode, d.LastError = 0, ""
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Body))
+	if err != nil {
+		d.LastError = err.Error()
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, d.EventType)
+	req.Header.Set(WebhookDeliveryHeader, strconv.FormatInt(d.EventID, 10))
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(d.Secret, timestamp, d.Body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		d.LastError = truncate(err.Error(), webhookMaxErrorLength)
+		return
+	}
+	defer resp.Body.Close()
+	d.LastStatusCode = resp.StatusCode
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		d.Status = models.WebhookDeliveryDelivered
+		return
+	}
+	text, _ := io.ReadAll(io.LimitReader(resp.Body, webhookMaxErrorLength))
+	d.LastError = truncate(fmt.Sprintf("%s: %s", resp.Status, text), webhookMaxErrorLength)
+}
+
+// Purge удаляет давно выполненные доставки
+func (s *WebhookService) Purge() (int64, error) {
+	return s.webhookRepo.DeleteDelivered(webhookRetentionDays)
+}
+
+// SignWebhook подписывает тело запроса секретом подписки
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookBackoff — задержка перед следующей попыткой после attempts неудачных:
+// 30 секунд, минута, две и так далее, но не больше шести часов
+func webhookBackoff(attempts int) time.Duration {
+	backoff := webhookFirstBackoff
+	for i := 1; i < attempts && backoff < webhookMaxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, webhookMaxBackoff)
+}
+
+// truncate обрезает s до n байт, не разрывая символы
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "")
+}
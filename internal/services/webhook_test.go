@@ -0,0 +1,201 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+	"github.com/itocode21/MerchServiceAvito/internal/repositories"
+	"github.com/lib/pq"
+)
+
+func TestDeliverWebhooks(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка создания мока: %v", err)
+	}
+	defer db.Close()
+
+	const secret = "whsec_test"
+	var mu sync.Mutex
+	received := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(r.Header.Get(WebhookTimestampHeader) + "." + string(body)))
+		if r.Header.Get(WebhookSignatureHeader) != "sha256="+hex.EncodeToString(mac.Sum(nil)) {
+			http.Error(w, "bad signature", http.StatusUnauthorized)
+			return
+		}
+		mu.Lock()
+		received[r.URL.Path] = r.Header.Get(WebhookEventHeader)
+		mu.Unlock()
+		if r.URL.Path == "/fail" {
+			http.Error(w, "slack is down", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	service := NewWebhookService(repositories.NewWebhookRepository(db))
+	body := `{"id":1,"type":"CoinsTransferred","data":{"from":"alice","to":"bob","amount":50}}`
+
+	mock.ExpectQuery("WITH due AS").
+		WithArgs(models.WebhookDeliveryPending, webhookBatch, webhookLease.Seconds()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "subscription_id", "event_id", "event_type", "body", "attempts", "url", "secret"}).
+			AddRow(1, 1, 1, models.EventCoinsTransferred, body, 0, server.URL+"/ok", secret).
+			AddRow(2, 2, 1, models.EventCoinsTransferred, body, 2, server.URL+"/fail", secret).
+			AddRow(3, 2, 7, models.EventCoinsTransferred, body, webhookMaxAttempts-1, server.URL+"/fail", secret).
+			AddRow(4, 3, 1, models.EventCoinsTransferred, body, 0, server.URL+"/ok", "whsec_other"))
+	recordAttempt := "UPDATE webhook_deliveries\\s+SET status = \\$1, attempts = \\$2"
+	mock.ExpectExec(recordAttempt).
+		WithArgs(models.WebhookDeliveryDelivered, 1, 204, "", sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(recordAttempt).
+		WithArgs(models.WebhookDeliveryPending, 3, 503, "503 Service Unavailable: slack is down\n", sqlmock.AnyArg(), 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(recordAttempt).
+		WithArgs(models.WebhookDeliveryDead, webhookMaxAttempts, 503, sqlmock.AnyArg(), sqlmock.AnyArg(), 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(recordAttempt).
+		WithArgs(models.WebhookDeliveryPending, 1, 401, sqlmock.AnyArg(), sqlmock.AnyArg(), 4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	delivered, err := service.Deliver(context.Background())
+	if err != nil {
+		t.Fatalf("Deliver() error = %v", err)
+	}
+	if delivered != 1 {
+		t.Errorf("Deliver() = %d, want 1", delivered)
+	}
+	if received["/ok"] != models.EventCoinsTransferred || received["/fail"] != models.EventCoinsTransferred {
+		t.Errorf("Получены события %v", received)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Не все ожидания мока выполнены: %v", err)
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{5, 8 * time.Minute},
+		{20, webhookMaxBackoff},
+	}
+	for _, tt := range tests {
+		if got := webhookBackoff(tt.attempts); got != tt.want {
+			t.Errorf("webhookBackoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestCreateWebhookSubscription(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка создания мока: %v", err)
+	}
+	defer db.Close()
+
+	service := NewWebhookService(repositories.NewWebhookRepository(db))
+
+	tests := []struct {
+		name       string
+		url        string
+		eventTypes []string
+		setupMock  func()
+		errMsg     string
+	}{
+		{
+			name:       "Успешная подписка",
+			url:        "https://slack-bot.internal/merch",
+			eventTypes: []string{models.EventItemPurchased, models.EventCoinsTransferred, models.EventItemPurchased},
+			setupMock: func() {
+				mock.ExpectQuery("INSERT INTO webhook_subscriptions").
+					WithArgs("https://slack-bot.internal/merch", pq.Array([]string{models.EventCoinsTransferred, models.EventItemPurchased}), sqlmock.AnyArg(), "admin").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "active", "created_at"}).AddRow(1, true, time.Now()))
+			},
+		},
+		{
+			name:       "Адрес не http",
+			url:        "ftp://slack-bot.internal",
+			eventTypes: []string{models.EventCoinsTransferred},
+			setupMock:  func() {},
+			errMsg:     "адрес вебхука должен быть http(s) URL",
+		},
+		{
+			name:       "Неизвестный тип события",
+			url:        "https://slack-bot.internal/merch",
+			eventTypes: []string{"OrderShipped"},
+			setupMock:  func() {},
+			errMsg:     "неизвестный тип события OrderShipped",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+			secret, subscription, err := service.CreateSubscription("admin", tt.url, tt.eventTypes)
+			if tt.errMsg != "" {
+				if err == nil || err.Error() != tt.errMsg {
+					t.Errorf("CreateSubscription() error = %v, want %q", err, tt.errMsg)
+				}
+			} else if err != nil {
+				t.Errorf("CreateSubscription() error = %v, want nil", err)
+			} else if subscription.Secret != secret || len(secret) != len(webhookSecretPrefix)+48 {
+				t.Errorf("CreateSubscription() secret = %q", secret)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Не все ожидания мока выполнены: %v", err)
+			}
+		})
+	}
+}
+
+func TestDeactivateWebhookSubscription(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка создания мока: %v", err)
+	}
+	defer db.Close()
+
+	service := NewWebhookService(repositories.NewWebhookRepository(db))
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE webhook_subscriptions SET active = FALSE WHERE id = \\$1 AND active").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE webhook_deliveries SET status = \\$1, last_error = \\$2 WHERE subscription_id = \\$3 AND status = \\$4").
+		WithArgs(models.WebhookDeliveryDead, "подписка отключена", 1, models.WebhookDeliveryPending).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+	if err := service.DeactivateSubscription(1); err != nil {
+		t.Errorf("DeactivateSubscription() error = %v, want nil", err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE webhook_subscriptions SET active = FALSE").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+	if err := service.DeactivateSubscription(1); err == nil || err.Error() != "подписка 1 не найдена или уже отключена" {
+		t.Errorf("DeactivateSubscription() error = %v, want подписка не найдена", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Не все ожидания мока выполнены: %v", err)
+	}
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/itocode21/MerchServiceAvito/internal/models"
+	"github.com/itocode21/MerchServiceAvito/internal/repositories"
+)
+
+const wishlistAlertBatch = 500
+
+type WishlistService struct {
+	wishlistRepo     *repositories.WishlistRepository
+	itemRepo         *repositories.ItemRepository
+	userRepo         *repositories.UserRepository
+	notificationRepo *repositories.NotificationRepository
+	db               *sql.DB
+}
+
+func NewWishlistService(wishlistRepo *repositories.WishlistRepository, itemRepo *repositories.ItemRepository, userRepo *repositories.UserRepository, notificationRepo *repositories.NotificationRepository) *WishlistService {
+	return &WishlistService{
+		wishlistRepo:     wishlistRepo,
+		itemRepo:         itemRepo,
+		userRepo:         userRepo,
+		notificationRepo: notificationRepo,
+		db:               userRepo.DB,
+	}
+}
+
+func (s *WishlistService) GetWishlist(username string) ([]models.WishlistItem, error) {
+	user, err := s.getUser(username)
+	if err != nil {
+		return nil, err
+	}
+	return s.wishlistRepo.GetUserWishlist(user.ID)
+}
+
+func (s *WishlistService) AddItem(username, itemName string) error {
+	user, item, err := s.getUserAndItem(username, itemName)
+	if err != nil {
+		return err
+	}
+	return s.wishlistRepo.AddItem(user.ID, item.ID)
+}
+
+func (s *WishlistService) RemoveItem(username, itemName string) error {
+	user, item, err := s.getUserAndItem(username, itemName)
+	if err != nil {
+		return err
+	}
+	removed, err := s.wishlistRepo.RemoveItem(user.ID, item.ID)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return userErrorf("предмета %s нет в списке желаний", itemName)
+	}
+	return nil
+}
+
+// SendAlerts уведомляет пользователей о снижении цены, появлении в наличии и о том,
+// что им хватает монет на предмет из списка желаний. Возвращает число
+// обработанных записей.
+func (s *WishlistService) SendAlerts() (int, error) {
+	var processed int
+	err := withTxRetry(func() error {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return fmt.Errorf("ошибка начала транзакции: %w", err)
+		}
+		defer tx.Rollback()
+
+		items, err := s.wishlistRepo.LockChangedItemsTx(tx, wishlistAlertBatch)
+		if err != nil {
+			return err
+		}
+		for _, w := range items {
+			for _, n := range wishlistAlerts(w) {
+				if err := s.notificationRepo.CreateNotificationTx(tx, n); err != nil {
+					return err
+				}
+			}
+			if err := s.wishlistRepo.MarkNotifiedTx(tx, w); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("ошибка фиксации транзакции: %w", err)
+		}
+		processed = len(items)
+		return nil
+	})
+	return processed, err
+}
+
+// wishlistAlerts возвращает уведомления об изменениях, о которых пользователь ещё не знает.
+// Повышение цены, окончание запаса и нехватка монет уведомлений не вызывают.
+func wishlistAlerts(w *models.WishlistItem) []*models.Notification {
+	var alerts []*models.Notification
+	if w.Price < w.NotifiedPrice {
+		alerts = append(alerts, &models.Notification{
+			UserID:  w.UserID,
+			Kind:    models.NotificationWishlistPriceDrop,
+			Message: fmt.Sprintf("%s подешевел: %d → %d монет", w.Item, w.NotifiedPrice, w.Price),
+		})
+	}
+	if w.InStock && !w.WasInStock {
+		alerts = append(alerts, &models.Notification{
+			UserID:  w.UserID,
+			Kind:    models.NotificationWishlistRestocked,
+			Message: fmt.Sprintf("%s снова в наличии", w.Item),
+		})
+	}
+	if w.Affordable() && !w.WasAffordable {
+		alerts = append(alerts, &models.Notification{
+			UserID:  w.UserID,
+			Kind:    models.NotificationWishlistAffordable,
+			Message: fmt.Sprintf("Вам хватает монет на %s: %d из %d", w.Item, w.Balance, w.Price),
+		})
+	}
+	return alerts
+}
+
+func (s *WishlistService) getUser(username string) (*models.User, error) {
+	user, err := s.userRepo.GetUserByUsername(username)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении пользователя: %w", err)
+	}
+	if user == nil {
+		return nil, userErrorf("пользователь %s не найден", username)
+	}
+	return user, nil
+}
+
+func (s *WishlistService) getUserAndItem(username, itemName string) (*models.User, *models.Item, error) {
+	user, err := s.getUser(username)
+	if err != nil {
+		return nil, nil, err
+	}
+	item, err := s.itemRepo.GetItemByName(itemName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ошибка получения предмета: %w", err)
+	}
+	if item == nil {
+		return nil, nil, userErrorf("предмет %s не найден", itemName)
+	}
+	return user, item, nil
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/itocode21/MerchServiceAvito/internal/config"
+	"github.com/itocode21/MerchServiceAvito/internal/repositories"
+)
+
+func TestSendWishlistAlerts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Ошибка создания мока: %v", err)
+	}
+	defer db.Close()
+
+	cfg := &config.Config{DB: db}
+	service := NewWishlistService(repositories.NewWishlistRepository(db), repositories.NewItemRepository(db),
+		repositories.NewUserRepository(cfg), repositories.NewNotificationRepository(db))
+
+	wishlistColumns := []string{"user_id", "item_id", "name", "price", "stock", "balance", "created_at",
+		"notified_price", "was_in_stock", "was_affordable"}
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT w.user_id, .* FROM wishlist_items w .* FOR UPDATE OF w SKIP LOCKED").
+		WithArgs(wishlistAlertBatch).
+		WillReturnRows(sqlmock.NewRows(wishlistColumns).
+			// Цена снизилась, и теперь монет хватает
+			AddRow(1, 3, "hoody", 300, nil, 350, now, 400, true, false).
+			// Предмет вернулся в наличие, но монет по-прежнему мало
+			AddRow(2, 4, "book", 500, 2, 100, now, 500, false, false).
+			// Цена выросла: уведомлять не о чем, только запомнить новую цену
+			AddRow(3, 3, "hoody", 300, nil, 100, now, 250, true, false))
+
+	mock.ExpectQuery("INSERT INTO notifications").
+		WithArgs(1, "wishlist_price_drop", "hoody подешевел: 400 → 300 монет").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, now))
+	mock.ExpectQuery("INSERT INTO notifications").
+		WithArgs(1, "wishlist_affordable", "Вам хватает монет на hoody: 350 из 300").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(2, now))
+	mock.ExpectExec("UPDATE wishlist_items SET notified_price = \\$1, was_in_stock = \\$2, was_affordable = \\$3").
+		WithArgs(300, true, true, 1, 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	mock.ExpectQuery("INSERT INTO notifications").
+		WithArgs(2, "wishlist_restocked", "book снова в наличии").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(3, now))
+	mock.ExpectExec("UPDATE wishlist_items SET notified_price = \\$1, was_in_stock = \\$2, was_affordable = \\$3").
+		WithArgs(500, true, false, 2, 4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	mock.ExpectExec("UPDATE wishlist_items SET notified_price = \\$1, was_in_stock = \\$2, was_affordable = \\$3").
+		WithArgs(300, true, false, 3, 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	processed, err := service.SendAlerts()
+	if err != nil {
+		t.Fatalf("SendAlerts() error = %v", err)
+	}
+	if processed != 3 {
+		t.Errorf("SendAlerts() = %d, want 3", processed)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Не все ожидания мока выполнены: %v", err)
+	}
+}
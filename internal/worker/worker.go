@@ -0,0 +1,24 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Every вызывает fn раз в interval, пока не отменён ctx. Ошибка запуска только
+// логируется: следующий запуск попробует снова.
+func Every(ctx context.Context, interval time.Duration, name string, fn func(ctx context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := fn(ctx); err != nil {
+				log.Printf("Фоновая задача %s завершилась с ошибкой: %v", name, err)
+			}
+		}
+	}
+}